@@ -1,28 +1,73 @@
 package bufio
 
 import (
+	"errors"
 	"io"
+	"os"
+	"time"
 
 	"github.com/angusgmorrison/gila/editor"
 )
 
+// defaultEscapeTimeout is how long ReadKey waits for the rest of an escape
+// sequence after reading a lone escape byte.
+const defaultEscapeTimeout = 50 * time.Millisecond
+
+const esc = '\x1b'
+
+// deadlineReader is an io.Reader whose reads can be interrupted by a deadline,
+// such as a net.Conn or an *os.File in non-blocking mode.
+type deadlineReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
 // KeyReader satisfies editor.KeyReader. It avoids allocations when reading keys
 // from input by maintaining a buffer, keyBuf, that is returned to the caller by
 // ReadKey and shared between ReadKey calls.
 type KeyReader struct {
-	r      io.Reader
-	keyBuf []byte
+	r             io.Reader
+	keyBuf        []byte
+	escapeTimeout time.Duration
 }
 
 var _ editor.KeyReader = (*KeyReader)(nil)
 
+// KeyReaderOption configures a KeyReader.
+type KeyReaderOption func(*KeyReader)
+
+// WithEscapeTimeout sets how long ReadKey waits for the remainder of an escape
+// sequence. See SetEscapeTimeout.
+func WithEscapeTimeout(d time.Duration) KeyReaderOption {
+	return func(kr *KeyReader) {
+		kr.escapeTimeout = d
+	}
+}
+
 // NewKeyReader returns a *KeyReader with an key buffer of len maxKeyBytes. This
 // is the maximum size of keypress it must be able to read in bytes.
-func NewKeyReader(r io.Reader, maxKeyBytes int) *KeyReader {
-	return &KeyReader{
-		r:      r,
-		keyBuf: make([]byte, maxKeyBytes),
+func NewKeyReader(r io.Reader, maxKeyBytes int, opts ...KeyReaderOption) *KeyReader {
+	kr := &KeyReader{
+		r:             r,
+		keyBuf:        make([]byte, maxKeyBytes),
+		escapeTimeout: defaultEscapeTimeout,
+	}
+	for _, opt := range opts {
+		opt(kr)
 	}
+	return kr
+}
+
+// SetEscapeTimeout sets how long ReadKey waits for the remainder of an escape
+// sequence after reading a lone escape byte or an incomplete sequence. If no
+// further bytes arrive within d, the bytes read so far are returned as the
+// keypress. A d of zero or less disables waiting.
+//
+// The timeout only applies if the underlying reader supports read deadlines,
+// like a net.Conn or a terminal opened in non-blocking mode. Otherwise, ReadKey
+// returns whatever a single read yields.
+func (kr *KeyReader) SetEscapeTimeout(d time.Duration) {
+	kr.escapeTimeout = d
 }
 
 // ReadKey attempts to read the bytes corresponding to a keypress or chord into
@@ -30,10 +75,74 @@ func NewKeyReader(r io.Reader, maxKeyBytes int) *KeyReader {
 // block until at least one byte is read. The return value is a slice containing
 // the n bytes read from r. This slice shares the same underlying memory as
 // keyBuf, making it unsafe to reuse between calls to Read.
+//
+// Under load, a terminal may deliver an escape sequence across several reads.
+// If the bytes read begin an escape sequence that is not yet complete, ReadKey
+// keeps reading until it is, or until no more bytes arrive within the escape
+// timeout.
 func (kr *KeyReader) ReadKey() ([]byte, error) {
 	n, err := kr.r.Read(kr.keyBuf)
 	if err != nil {
 		return nil, err
 	}
+	for n < len(kr.keyBuf) && !escapeComplete(kr.keyBuf[:n]) {
+		rest, err := kr.readEscapeRemainder(n)
+		if err != nil {
+			return nil, err
+		}
+		if rest == 0 {
+			break
+		}
+		n += rest
+	}
 	return kr.keyBuf[:n], nil
 }
+
+// escapeComplete reports whether key is a complete keypress. Only escape
+// sequences can be incomplete: a lone escape byte, a CSI sequence (ESC [)
+// without its final byte, or an SS3 sequence (ESC O) without the byte that
+// follows.
+func escapeComplete(key []byte) bool {
+	if len(key) == 0 || key[0] != esc {
+		return true
+	}
+	if len(key) == 1 {
+		return false
+	}
+	switch key[1] {
+	case '[':
+		for _, b := range key[2:] {
+			if b >= 0x40 && b <= 0x7e {
+				return true
+			}
+		}
+		return false
+	case 'O':
+		return len(key) > 2
+	default:
+		return true // Alt chord
+	}
+}
+
+// readEscapeRemainder waits up to kr.escapeTimeout for more bytes of an
+// escape sequence, reading them into keyBuf after the first n bytes. It
+// returns the number of bytes read, which is zero if none arrived in time or
+// the reader doesn't support read deadlines. In that case, the bytes already
+// read are a keypress by themselves, like a lone escape or Alt-[.
+func (kr *KeyReader) readEscapeRemainder(n int) (int, error) {
+	dr, ok := kr.r.(deadlineReader)
+	if !ok || kr.escapeTimeout <= 0 {
+		return 0, nil
+	}
+	if err := dr.SetReadDeadline(time.Now().Add(kr.escapeTimeout)); err != nil {
+		return 0, nil // deadlines unsupported by this reader, e.g. a blocking file
+	}
+	rest, err := dr.Read(kr.keyBuf[n:])
+	if resetErr := dr.SetReadDeadline(time.Time{}); resetErr != nil && err == nil {
+		err = resetErr
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return 0, nil
+	}
+	return rest, err
+}
@@ -3,9 +3,14 @@ package bufio
 import (
 	"errors"
 	"io"
+	"net"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/angusgmorrison/gila/editor"
 )
 
 // MockReader is a mock io.Reader.
@@ -29,6 +34,14 @@ func Test_NewKeyReader(t *testing.T) {
 	if len(kr.keyBuf) != 5 {
 		t.Errorf("NewKeyReader() = %+v, want %+v", len(kr.keyBuf), 5)
 	}
+	if kr.escapeTimeout != defaultEscapeTimeout {
+		t.Errorf("NewKeyReader() escapeTimeout = %v, want %v", kr.escapeTimeout, defaultEscapeTimeout)
+	}
+
+	kr = NewKeyReader(r, 5, WithEscapeTimeout(time.Second))
+	if kr.escapeTimeout != time.Second {
+		t.Errorf("NewKeyReader() escapeTimeout = %v, want %v", kr.escapeTimeout, time.Second)
+	}
 }
 
 func Test_KeyReader_ReadKey(t *testing.T) {
@@ -84,3 +97,194 @@ func Test_KeyReader_ReadKey(t *testing.T) {
 		})
 	}
 }
+
+func Test_KeyReader_ReadKey_escapeTimeout(t *testing.T) {
+	t.Parallel()
+
+	const timeout = 20 * time.Millisecond
+
+	testCases := []struct {
+		name   string
+		writes []string
+		delay  time.Duration
+		want   []string
+	}{
+		{
+			name: "when nothing follows an escape within the timeout " +
+				"ReadKey returns the escape by itself",
+			writes: []string{"\x1b", "a"},
+			delay:  5 * timeout,
+			want:   []string{"\x1b", "a"},
+		},
+		{
+			name: "when the rest of a sequence follows an escape within the timeout " +
+				"ReadKey returns the whole sequence",
+			writes: []string{"\x1b", "[A"},
+			want:   []string{"\x1b[A"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, w := net.Pipe()
+			t.Cleanup(func() {
+				r.Close()
+				w.Close()
+			})
+			go func() {
+				for i, s := range tc.writes {
+					if i > 0 {
+						time.Sleep(tc.delay)
+					}
+					if _, err := w.Write([]byte(s)); err != nil {
+						return
+					}
+				}
+			}()
+
+			kr := NewKeyReader(r, 8, WithEscapeTimeout(timeout))
+			for _, want := range tc.want {
+				got, err := kr.ReadKey()
+				if err != nil {
+					t.Fatalf("KeyReader.ReadKey() unexpected error: %v", err)
+				}
+				if string(got) != want {
+					t.Errorf("KeyReader.ReadKey() = %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+// scriptedReader returns one fragment per call to Read. Once the fragments are
+// exhausted, a read with a deadline set times out, and a read without one
+// returns io.EOF.
+type scriptedReader struct {
+	fragments []string
+	deadline  bool
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	if len(r.fragments) == 0 {
+		if r.deadline {
+			return 0, os.ErrDeadlineExceeded
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, r.fragments[0])
+	r.fragments = r.fragments[1:]
+	return n, nil
+}
+
+func (r *scriptedReader) SetReadDeadline(t time.Time) error {
+	r.deadline = !t.IsZero()
+	return nil
+}
+
+func Test_KeyReader_ReadKey_splitReads(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		fragments []string
+		want      string
+	}{
+		{
+			name: "when a CSI sequence is split after its introducer " +
+				"ReadKey returns the whole sequence",
+			fragments: []string{"\x1b[", "1;5A"},
+			want:      "\x1b[1;5A",
+		},
+		{
+			name: "when a CSI sequence arrives one byte at a time " +
+				"ReadKey returns the whole sequence",
+			fragments: []string{"\x1b", "[", "A"},
+			want:      "\x1b[A",
+		},
+		{
+			name: "when an SS3 sequence is split " +
+				"ReadKey returns the whole sequence",
+			fragments: []string{"\x1b", "O", "P"},
+			want:      "\x1bOP",
+		},
+		{
+			name: "when nothing follows an escape " +
+				"ReadKey returns the escape by itself",
+			fragments: []string{"\x1b"},
+			want:      "\x1b",
+		},
+		{
+			name: "when nothing follows an incomplete CSI introducer " +
+				"ReadKey returns it as an Alt chord",
+			fragments: []string{"\x1b["},
+			want:      "\x1b[",
+		},
+		{
+			name: "when an Alt chord arrives in one read " +
+				"ReadKey returns it without reading further",
+			fragments: []string{"\x1ba", "b"},
+			want:      "\x1ba",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			kr := NewKeyReader(&scriptedReader{fragments: tc.fragments}, 8)
+			got, err := kr.ReadKey()
+			if err != nil {
+				t.Fatalf("KeyReader.ReadKey() unexpected error: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("KeyReader.ReadKey() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// byteReader reads one byte of data at a time, followed by EOF.
+type byteReader struct {
+	data []byte
+}
+
+func (r *byteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, nil // an empty keypress, which the editor reads as EOF
+	}
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+	return 1, nil
+}
+
+// resizeRenderer is an editor.Renderer and editor.Resizer that draws nothing
+// and is never resized. Resizers cause the editor to read keys concurrently.
+type resizeRenderer struct {
+	editor.NullRenderer
+}
+
+func (resizeRenderer) ResizeNotifier() <-chan struct{} { return nil }
+func (resizeRenderer) Size() (int, int)                { return 80, 24 }
+
+func Test_KeyReader_ReadKey_concurrentEditor(t *testing.T) {
+	t.Parallel()
+
+	text := strings.Repeat("abcdefgh\r", 200)
+	kr := NewKeyReader(&byteReader{data: []byte(text)}, 8)
+	e := editor.New(kr, resizeRenderer{}, editor.WithConfig(editor.Config{Width: 80, Height: 24}))
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The document gains a final newline after the empty last line.
+	want := strings.ReplaceAll(text, "\r", "\n") + "\n"
+	if got := e.Buffer(); got != want {
+		t.Errorf("expected the keys to be inserted in order, got %q", got)
+	}
+}
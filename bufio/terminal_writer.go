@@ -60,7 +60,9 @@ func (tw *TerminalWriter) WriteString(s string) (int, error) {
 // buffer, the buffer will be written and flushed to output as many times as
 // required to fully consume the escape sequence.
 func (tw *TerminalWriter) WriteEscapeSequence(esc escseq.EscSeq, args ...any) (int, error) {
-	n, err := fmt.Fprintf(tw.w, string(esc), args...)
+	// Format directly into the free space at the end of the buffer. If the
+	// sequence doesn't fit, AppendFormat allocates instead.
+	n, err := tw.w.Write(escseq.AppendFormat(tw.w.AvailableBuffer(), esc, args...))
 	if err != nil {
 		return n, fmt.Errorf("write escape sequence %q: %w", esc, err)
 	}
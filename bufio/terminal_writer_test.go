@@ -2,6 +2,8 @@ package bufio
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -85,3 +87,42 @@ func Test_TerminalWriter_WriteEscapeSequence(t *testing.T) {
 		})
 	}
 }
+
+func Test_TerminalWriter_WriteEscapeSequence_cursorPosition(t *testing.T) {
+	t.Parallel()
+
+	var got bytes.Buffer
+	w := &MockWriter{writeFunc: got.Write}
+	tw := NewTerminalWriter(w)
+	var want bytes.Buffer
+	for _, pos := range [][2]int{{1, 1}, {24, 80}, {0, -1}, {1000, 65535}} {
+		if _, err := tw.WriteEscapeSequence(escseq.EscCursorPosition, pos[0], pos[1]); err != nil {
+			t.Fatalf("unexpected error writing escape sequence: %v", err)
+		}
+		fmt.Fprintf(&want, string(escseq.EscCursorPosition), pos[0], pos[1])
+	}
+	if err := tw.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing buffer: %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("expected %q, got %q", want.String(), got.String())
+	}
+}
+
+func Benchmark_TerminalWriter_WriteEscapeSequence(b *testing.B) {
+	w := &MockWriter{writeFunc: func(p []byte) (int, error) { return len(p), nil }}
+	tw := NewTerminalWriter(w)
+
+	b.Run("fmt", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fmt.Fprintf(tw.w, string(escseq.EscCursorPosition), i%50, i%80)
+		}
+	})
+	b.Run("AppendFormat", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tw.WriteEscapeSequence(escseq.EscCursorPosition, i%50, i%80)
+		}
+	})
+}
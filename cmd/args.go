@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/angusgmorrison/gila/config"
+)
+
+// options are the settings given on the command line.
+type options struct {
+	readOnly bool
+	// tabStop overrides the configured tab stop if positive.
+	tabStop int
+	// line is the 1-indexed line to place the cursor on in the first file, or
+	// 0 to start at the top.
+	line    int
+	version bool
+	paths   []string
+}
+
+// apply overrides the configured settings with those given on the command
+// line.
+func (opts options) apply(s *config.Settings) {
+	if opts.tabStop > 0 {
+		s.Editor.TabStop = opts.tabStop
+	}
+	s.Editor.ReadOnly = s.Editor.ReadOnly || opts.readOnly
+}
+
+// parseArgs parses the command-line arguments that follow the program name.
+// Flags must precede the files to open, except for +N, which may appear
+// anywhere before "--" and opens the first file at line N, like less and vi.
+// Invalid +N arguments are ignored. Usage and errors are written to output. If
+// -h or -help is given, parseArgs returns flag.ErrHelp.
+func parseArgs(args []string, output io.Writer) (options, error) {
+	var opts options
+	fs := flag.NewFlagSet("gila", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: gila [flags] [+N] [file ...]\n\n")
+		fmt.Fprintf(output, "  +N\topen the first file at line N\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&opts.readOnly, "r", false, "open files in read-only mode")
+	fs.BoolVar(&opts.readOnly, "R", false, "open files in read-only mode")
+	fs.BoolVar(&opts.readOnly, "read-only", false, "open files in read-only mode")
+	fs.IntVar(&opts.tabStop, "tabstop", 0, "display tabs `N` columns wide")
+	fs.BoolVar(&opts.version, "version", false, "print the version and exit")
+
+	args, opts.line = extractLine(args)
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+	if opts.tabStop < 0 {
+		err := errors.New("-tabstop must be positive")
+		fmt.Fprintf(output, "%s\n", err)
+		fs.Usage()
+		return options{}, err
+	}
+	opts.paths = fs.Args()
+	return opts, nil
+}
+
+// extractLine removes +N arguments from args, returning the remaining
+// arguments and the last valid N, or 0 if there is none. Arguments after "--"
+// are left untouched.
+func extractLine(args []string) (rest []string, line int) {
+	rest = make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(rest, args[i:]...), line
+		}
+		if !strings.HasPrefix(arg, "+") {
+			rest = append(rest, arg)
+			continue
+		}
+		if n, err := strconv.Atoi(arg[1:]); err == nil && n >= 1 && arg[1] != '+' {
+			line = n
+		}
+	}
+	return rest, line
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/angusgmorrison/gila/config"
+	"github.com/angusgmorrison/gila/editor"
+)
+
+func Test_parseArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		args    []string
+		want    options
+		wantErr bool
+	}{
+		{
+			name: "when there are no arguments it returns the defaults",
+			args: []string{},
+			want: options{paths: []string{}},
+		},
+		{
+			name: "when flags precede files they are parsed",
+			args: []string{"-r", "-tabstop", "8", "a.txt", "b.txt"},
+			want: options{readOnly: true, tabStop: 8, paths: []string{"a.txt", "b.txt"}},
+		},
+		{
+			name: "when -R or -read-only is given the files are read-only",
+			args: []string{"-read-only", "a.txt"},
+			want: options{readOnly: true, paths: []string{"a.txt"}},
+		},
+		{
+			name: "when --version is given it is parsed",
+			args: []string{"--version"},
+			want: options{version: true, paths: []string{}},
+		},
+		{
+			name: "when +N precedes the files it sets the line",
+			args: []string{"+12", "a.txt"},
+			want: options{line: 12, paths: []string{"a.txt"}},
+		},
+		{
+			name: "when +N precedes flags the flags are still parsed",
+			args: []string{"+3", "-r", "a.txt"},
+			want: options{readOnly: true, line: 3, paths: []string{"a.txt"}},
+		},
+		{
+			name: "when +N follows the files it sets the line",
+			args: []string{"a.txt", "+7"},
+			want: options{line: 7, paths: []string{"a.txt"}},
+		},
+		{
+			name: "when +N follows -- it is a file",
+			args: []string{"--", "+7"},
+			want: options{paths: []string{"+7"}},
+		},
+		{
+			name: "when a flag follows a file it is a file",
+			args: []string{"a.txt", "-r"},
+			want: options{paths: []string{"a.txt", "-r"}},
+		},
+		{
+			name: "when +N isn't a positive number it is ignored",
+			args: []string{"+0", "a.txt"},
+			want: options{paths: []string{"a.txt"}},
+		},
+		{
+			name: "when +N is malformed it is ignored",
+			args: []string{"a.txt", "+x"},
+			want: options{paths: []string{"a.txt"}},
+		},
+		{
+			name: "when +N is malformed an earlier valid +N applies",
+			args: []string{"+5", "a.txt", "++6"},
+			want: options{line: 5, paths: []string{"a.txt"}},
+		},
+		{
+			name:    "when -tabstop is negative it returns an error",
+			args:    []string{"-tabstop", "-2"},
+			wantErr: true,
+		},
+		{
+			name:    "when a flag is unknown it returns an error",
+			args:    []string{"-nope"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseArgs(tc.args, io.Discard)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %t, got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) && !tc.wantErr {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_parseArgs_help(t *testing.T) {
+	t.Parallel()
+
+	if _, err := parseArgs([]string{"-h"}, io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("expected flag.ErrHelp, got %v", err)
+	}
+}
+
+func Test_options_apply(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		opts     options
+		settings config.Settings
+		want     editor.Config
+	}{
+		{
+			name:     "when no flags are given the settings are unchanged",
+			settings: config.Settings{Editor: editor.Config{TabStop: 2, ReadOnly: true}},
+			want:     editor.Config{TabStop: 2, ReadOnly: true},
+		},
+		{
+			name:     "when flags are given they override the settings",
+			opts:     options{tabStop: 8, readOnly: true},
+			settings: config.Settings{Editor: editor.Config{TabStop: 2}},
+			want:     editor.Config{TabStop: 8, ReadOnly: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.opts.apply(&tc.settings)
+			if !reflect.DeepEqual(tc.settings.Editor, tc.want) {
+				t.Errorf("expected %+v, got %+v", tc.want, tc.settings.Editor)
+			}
+		})
+	}
+}
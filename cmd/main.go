@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime/debug"
+	"time"
 
 	"github.com/angusgmorrison/gila/bufio"
+	"github.com/angusgmorrison/gila/config"
 	"github.com/angusgmorrison/gila/editor"
 	"github.com/angusgmorrison/gila/escseq"
 	"github.com/angusgmorrison/gila/renderer"
@@ -16,6 +21,11 @@ import (
 const (
 	logFile = "editor.log"
 	name    = "Gila editor"
+	// How long to wait for the terminal to answer capability queries.
+	terminalQueryTimeout = 100 * time.Millisecond
+	// How long to wait for the rest of an escape sequence before treating Esc
+	// as a keypress by itself.
+	escapeTimeout = 25 * time.Millisecond
 )
 
 func main() {
@@ -25,26 +35,74 @@ func main() {
 }
 
 func run() (err error) {
-	var filepath string
-	if len(os.Args) > 1 {
-		filepath = os.Args[1]
+	opts, err := parseArgs(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
 	}
+	if err != nil {
+		os.Exit(2) // parseArgs has printed the error and usage
+	}
+	info, _ := debug.ReadBuildInfo()
+	if opts.version {
+		fmt.Printf("%s version %s\n", name, info.Main.Version)
+		return nil
+	}
+
+	// Files that can't be opened are reported once the terminal has been
+	// restored, since the editor's screen would hide the warnings.
+	var warnings []error
+	defer func() {
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+	}()
+
+	settings, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	// Environment variables override the config file, and flags override
+	// both. Invalid variables are reported once the terminal has been restored.
+	envErr := settings.ApplyEnv(os.LookupEnv)
+	opts.apply(&settings)
+
+	// If text is piped to stdin, keypresses must be read from the controlling
+	// terminal instead.
+	tty, piped, err := terminalInput(os.Stdin, term.IsTerminal, openTTY)
+	if err != nil {
+		return err
+	}
+	if piped {
+		defer tty.Close()
+	}
+	ttyFd := int(tty.Fd())
 
 	// Enable terminal raw mode to process each keypress as it happens.
-	initialTermState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	initialTermState, err := term.MakeRaw(ttyFd)
 	if err != nil {
 		return fmt.Errorf("enable terminal raw mode: %w", err)
 	}
-	defer func() { err = term.Restore(int(os.Stdin.Fd()), initialTermState) }()
+	defer cleanup(&err, func() error { return term.Restore(ttyFd, initialTermState) })
 	// In raw mode, the cursor won't return to the start of the next line after
 	// the terminal echoes the command used to run the program, so we force the
 	// line feed.
 	fmt.Print("\r")
+	exitAltScreen, err := enterAltScreen(os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer cleanup(&err, exitAltScreen)
 
-	keyReader := bufio.NewKeyReader(os.Stdin, escseq.MaxLenBytes)
+	in, restoreBlocking, err := keyInput(tty, ttyFd)
+	if err != nil {
+		return err
+	}
+	defer cleanup(&err, restoreBlocking)
+
+	maxKeyBytes := escseq.QueryMaxKeyBytes(in, os.Stdout, terminalQueryTimeout)
+	keyReader := bufio.NewKeyReader(in, maxKeyBytes, bufio.WithEscapeTimeout(escapeTimeout))
 	terminalWriter := bufio.NewTerminalWriter(os.Stdout)
-	info, _ := debug.ReadBuildInfo()
-	w, h, err := term.GetSize(int(os.Stdin.Fd()))
+	w, h, err := term.GetSize(ttyFd)
 	if err != nil {
 		return fmt.Errorf("get terminal size: %w", err)
 	}
@@ -56,23 +114,98 @@ func run() (err error) {
 			Width:  w,
 			Height: h,
 		},
+		settings.Renderer,
 	)
+	defer watchResize(ttyFd, renderer.Resize)()
 
-	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("open log file: %w", err)
+	// The log file is only written in debug mode, so that normal runs don't
+	// leave one behind.
+	var logger editor.Logger = editor.NopLogger{}
+	if settings.Editor.Debug {
+		f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("open log file: %w", err)
+		}
+		defer f.Close()
+		logger = log.New(f, "", log.LstdFlags|log.Lshortfile)
+	}
+	if envErr != nil {
+		warnings = append(warnings, fmt.Errorf("ignoring invalid environment variables: %w", envErr))
 	}
-	defer f.Close()
-	logger := log.New(f, "", log.LstdFlags|log.Lshortfile)
 
+	edConfig := settings.Editor
+	edConfig.Width = w
+	edConfig.Height = h
 	ed := editor.New(
 		keyReader,
 		renderer,
-		editor.Config{
-			Width:  w,
-			Height: h,
-		},
-		logger,
+		editor.WithConfig(edConfig),
+		editor.WithConfigLoader(func() (editor.Config, error) {
+			settings, err := config.Load()
+			if err != nil {
+				return editor.Config{}, err
+			}
+			_ = settings.ApplyEnv(os.LookupEnv) // invalid variables are already reported on exit
+			opts.apply(&settings)
+			renderer.Reconfigure(settings.Renderer)
+			return settings.Editor, nil
+		}),
+		editor.WithLogger(logger),
 	)
-	return ed.Run(filepath)
+	if piped && len(opts.paths) == 0 {
+		if err := ed.Load(os.Stdin); err != nil {
+			return fmt.Errorf("read stdin: %w", err)
+		}
+	}
+	for _, path := range opts.paths {
+		if err := ed.OpenBuffer(path); err != nil {
+			warnings = append(warnings, err)
+		}
+	}
+	if opts.line > 0 {
+		ed.JumpTo(editor.Position{Line: opts.line, Col: 1})
+	}
+	return ed.Run()
+}
+
+// cleanup calls f, joining any error it returns to *err. Deferred with a
+// pointer to a named return value, it ensures that cleaning up after an
+// error doesn't hide it.
+func cleanup(err *error, f func() error) {
+	*err = errors.Join(*err, f())
+}
+
+// enterAltScreen switches the terminal written to by w to its alternate
+// screen, so that the editor doesn't overwrite the user's scrollback. The
+// returned function switches back to the primary screen, restoring its
+// contents.
+func enterAltScreen(w io.Writer) (exit func() error, err error) {
+	if _, err := io.WriteString(w, string(escseq.EscAltScreenEnter)); err != nil {
+		return nil, fmt.Errorf("enter alternate screen: %w", err)
+	}
+	return func() error {
+		if _, err := io.WriteString(w, string(escseq.EscAltScreenExit)); err != nil {
+			return fmt.Errorf("exit alternate screen: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// terminalInput returns the file that keypresses should be read from. If stdin
+// is a terminal, it is returned as is. Otherwise, stdin is a pipe or
+// redirected file, and the controlling terminal is opened with openTTY. piped
+// reports whether stdin was replaced.
+func terminalInput(
+	stdin *os.File,
+	isTerminal func(fd int) bool,
+	openTTY func() (*os.File, error),
+) (tty *os.File, piped bool, err error) {
+	if isTerminal(int(stdin.Fd())) {
+		return stdin, false, nil
+	}
+	tty, err = openTTY()
+	if err != nil {
+		return nil, false, fmt.Errorf("open terminal for keyboard input: %w", err)
+	}
+	return tty, true, nil
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/angusgmorrison/gila/escseq"
+)
+
+func Test_terminalInput(t *testing.T) {
+	t.Parallel()
+
+	stdin, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create pipe: %v", err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		w.Close()
+	})
+	tty, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("open %s: %v", os.DevNull, err)
+	}
+	t.Cleanup(func() { tty.Close() })
+	errNoTTY := errors.New("no tty")
+
+	testCases := []struct {
+		name       string
+		isTerminal bool
+		openTTY    func() (*os.File, error)
+		want       *os.File
+		wantPiped  bool
+		wantErr    error
+	}{
+		{
+			name:       "when stdin is a terminal it is used for input",
+			isTerminal: true,
+			openTTY: func() (*os.File, error) {
+				t.Error("expected terminal not to be opened")
+				return nil, nil
+			},
+			want: stdin,
+		},
+		{
+			name:      "when stdin is piped the terminal is used for input",
+			openTTY:   func() (*os.File, error) { return tty, nil },
+			want:      tty,
+			wantPiped: true,
+		},
+		{
+			name:    "when the terminal can't be opened it returns an error",
+			openTTY: func() (*os.File, error) { return nil, errNoTTY },
+			wantErr: errNoTTY,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			isTerminal := func(fd int) bool {
+				if fd != int(stdin.Fd()) {
+					t.Errorf("expected stdin fd %d, got %d", stdin.Fd(), fd)
+				}
+				return tc.isTerminal
+			}
+			got, piped, err := terminalInput(stdin, isTerminal, tc.openTTY)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected input %v, got %v", tc.want, got)
+			}
+			if piped != tc.wantPiped {
+				t.Errorf("expected piped %t, got %t", tc.wantPiped, piped)
+			}
+		})
+	}
+}
+
+func Test_enterAltScreen(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	exit, err := enterAltScreen(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), string(escseq.EscAltScreenEnter); got != want {
+		t.Errorf("expected %q after entering, got %q", want, got)
+	}
+	buf.WriteString("frame")
+	if err := exit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := string(escseq.EscAltScreenEnter) + "frame" + string(escseq.EscAltScreenExit)
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func Test_cleanup(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("run failed")
+	errCleanup := errors.New("cleanup failed")
+
+	testCases := []struct {
+		name       string
+		runErr     error
+		cleanupErr error
+		wantErrs   []error
+	}{
+		{name: "when nothing fails it returns nil"},
+		{
+			name:     "when only the function fails it returns its error",
+			runErr:   errRun,
+			wantErrs: []error{errRun},
+		},
+		{
+			name:       "when only cleanup fails it returns its error",
+			cleanupErr: errCleanup,
+			wantErrs:   []error{errCleanup},
+		},
+		{
+			name:       "when both fail it returns both errors",
+			runErr:     errRun,
+			cleanupErr: errCleanup,
+			wantErrs:   []error{errRun, errCleanup},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			run := func() (err error) {
+				defer cleanup(&err, func() error { return tc.cleanupErr })
+				return tc.runErr
+			}
+			err := run()
+			if len(tc.wantErrs) == 0 && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			for _, want := range tc.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("expected error to include %v, got %v", want, err)
+				}
+			}
+		})
+	}
+}
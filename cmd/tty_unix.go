@@ -0,0 +1,63 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// openTTY opens the controlling terminal.
+func openTTY() (*os.File, error) {
+	return os.Open("/dev/tty")
+}
+
+// keyInput returns a file that reads from the terminal file descriptor fd in
+// non-blocking mode, so that reads from it support deadlines. This lets the
+// key reader tell a lone Esc from the start of an escape sequence.
+//
+// fd is duplicated so that the runtime poller can register the new file even
+// if tty is already registered. restore puts the terminal back into blocking
+// mode, which must happen before exit since the mode is shared with the shell,
+// and closes the duplicate.
+func keyInput(tty *os.File, fd int) (in *os.File, restore func() error, err error) {
+	dup, err := syscall.Dup(fd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("duplicate terminal file descriptor: %w", err)
+	}
+	if err := syscall.SetNonblock(dup, true); err != nil {
+		syscall.Close(dup)
+		return nil, nil, fmt.Errorf("set terminal non-blocking: %w", err)
+	}
+	in = os.NewFile(uintptr(dup), tty.Name())
+	restore = func() error {
+		if err := syscall.SetNonblock(dup, false); err != nil {
+			in.Close()
+			return fmt.Errorf("set terminal blocking: %w", err)
+		}
+		return in.Close()
+	}
+	return in, restore, nil
+}
+
+// watchResize calls resize with the new size of the terminal at fd each time
+// the terminal window is resized, until stop is called.
+func watchResize(fd int, resize func(w, h int)) (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	go func() {
+		for range sigs {
+			if w, h, err := term.GetSize(fd); err == nil {
+				resize(w, h)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// openTTY opens the console input buffer, which Windows provides in place of
+// a controlling terminal device.
+func openTTY() (*os.File, error) {
+	return os.OpenFile("CONIN$", os.O_RDWR, 0)
+}
+
+// keyInput returns tty unchanged, since console handles don't support read
+// deadlines. The key reader falls back to treating each read as a keypress.
+func keyInput(tty *os.File, _ int) (in *os.File, restore func() error, err error) {
+	return tty, func() error { return nil }, nil
+}
+
+// watchResize does nothing, since Windows has no SIGWINCH and console resize
+// events aren't read.
+func watchResize(_ int, _ func(w, h int)) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,279 @@
+// Package config reads user configuration from a config file.
+//
+// A config file consists of lines of the form
+//
+//	setting = value
+//	bind KEY = COMMAND
+//	unbind KEY
+//
+// Blank lines and lines starting with # are ignored. Settings are named in
+// snake_case after the editor.Config and renderer.Config fields they set, such
+// as tab_stop and soft_wrap, except for expand_tabs, which is the inverse of
+// editor.Config.HardTabs. The theme setting takes the name of a built-in
+// theme, default, dark or light, or the path of a JSON theme file, as read by
+// renderer.LoadTheme. Keys and commands are named as in editor.KeyMap,
+// and bindings modify editor.DefaultKeyMap.
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/angusgmorrison/gila/editor"
+	"github.com/angusgmorrison/gila/renderer"
+)
+
+// Settings holds the configuration read from a config file.
+type Settings struct {
+	Editor   editor.Config
+	Renderer renderer.Config
+}
+
+// Paths returns the locations searched for a config file, in order of
+// precedence: gila/config in the user's config directory, such as
+// $XDG_CONFIG_HOME, then ~/.gilarc.
+func Paths() []string {
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "gila", "config"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".gilarc"))
+	}
+	return paths
+}
+
+// Load reads the first config file found at Paths. If there is none, it
+// returns the zero Settings, which select the defaults.
+func Load() (Settings, error) {
+	return load(Paths())
+}
+
+func load(paths []string) (Settings, error) {
+	for _, path := range paths {
+		s, err := LoadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return s, err
+	}
+	return Settings{}, nil
+}
+
+// LoadFile reads the config file at path.
+func LoadFile(path string) (Settings, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Settings{}, err
+	}
+	defer f.Close()
+
+	s, err := Parse(f)
+	if err != nil {
+		return Settings{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse reads a config file from r. Errors give the line number they occurred
+// on.
+func Parse(r io.Reader) (Settings, error) {
+	var s Settings
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := s.parseLine(line); err != nil {
+			return Settings{}, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Settings{}, fmt.Errorf("read config: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Settings) parseLine(line string) error {
+	if key, ok := strings.CutPrefix(line, "unbind "); ok {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return errors.New("unbind: missing key")
+		}
+		return s.keyMap().Unbind(key)
+	}
+
+	// Split on the last "=", so that "=" can itself be bound.
+	i := strings.LastIndex(line, "=")
+	if i < 0 {
+		return fmt.Errorf("expected \"setting = value\", got %q", line)
+	}
+	name, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	if key, ok := strings.CutPrefix(name, "bind "); ok {
+		key = strings.TrimSpace(key)
+		if key == "" || value == "" {
+			return fmt.Errorf("expected \"bind KEY = COMMAND\", got %q", line)
+		}
+		return s.keyMap().Bind(key, editor.Command(value))
+	}
+	return s.set(name, value)
+}
+
+// envSettings maps the environment variables read by ApplyEnv to the settings
+// they override.
+var envSettings = []struct {
+	name, setting string
+}{
+	{name: "GILA_TABSTOP", setting: "tab_stop"},
+	{name: "GILA_EXPAND_TABS", setting: "expand_tabs"},
+	{name: "GILA_DEBUG", setting: "debug"},
+}
+
+// ApplyEnv overrides s with settings given by environment variables, as
+// returned by lookupEnv, such as os.LookupEnv. GILA_TABSTOP sets tab_stop,
+// GILA_EXPAND_TABS sets expand_tabs and GILA_DEBUG sets debug. Settings whose
+// variables have invalid values are left unchanged and reported in the
+// returned error.
+func (s *Settings) ApplyEnv(lookupEnv func(key string) (string, bool)) error {
+	var errs []error
+	for _, env := range envSettings {
+		value, ok := lookupEnv(env.name)
+		if !ok {
+			continue
+		}
+		prev := *s
+		if err := s.set(env.setting, strings.TrimSpace(value)); err != nil {
+			*s = prev
+			errs = append(errs, fmt.Errorf("%s: %w", env.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// keyMap returns the key map being configured, starting from the defaults.
+func (s *Settings) keyMap() editor.KeyMap {
+	if s.Editor.KeyMap == nil {
+		s.Editor.KeyMap = editor.DefaultKeyMap()
+	}
+	return s.Editor.KeyMap
+}
+
+// set assigns value to the named setting.
+func (s *Settings) set(name, value string) error {
+	var err error
+	switch name {
+	case "tab_stop":
+		s.Editor.TabStop, err = parsePositive(value)
+	case "indent_size":
+		s.Editor.IndentSize, err = parsePositive(value)
+	case "expand_tabs":
+		var expand bool
+		expand, err = strconv.ParseBool(value)
+		s.Editor.HardTabs = !expand
+	case "line_ending":
+		s.Editor.LineEnding, err = parseLineEnding(value)
+	case "preserve_bom":
+		s.Editor.PreserveBOM, err = strconv.ParseBool(value)
+	case "status_msg_duration":
+		var d time.Duration
+		d, err = time.ParseDuration(value)
+		s.Editor.StatusMsgDuration = d
+		s.Renderer.StatusMsgDuration = d
+	case "soft_wrap":
+		s.Editor.SoftWrap, err = strconv.ParseBool(value)
+	case "scroll_past_end":
+		s.Editor.ScrollPastEnd, err = strconv.ParseBool(value)
+	case "gutter":
+		s.Editor.Gutter, err = strconv.ParseBool(value)
+	case "read_only":
+		s.Editor.ReadOnly, err = strconv.ParseBool(value)
+	case "diff_gutter":
+		s.Editor.DiffGutter, err = strconv.ParseBool(value)
+	case "ensure_final_newline":
+		s.Editor.EnsureFinalNewline, err = strconv.ParseBool(value)
+	case "trim_trailing_whitespace":
+		s.Editor.TrimTrailingWhitespace, err = strconv.ParseBool(value)
+	case "lazy_load_size":
+		var n int
+		n, err = parsePositive(value)
+		s.Editor.LazyLoadSize = int64(n)
+	case "debug":
+		s.Editor.Debug, err = strconv.ParseBool(value)
+	case "backup":
+		s.Editor.Backup, err = strconv.ParseBool(value)
+	case "cursor_style":
+		s.Renderer.CursorStyle, err = parseCursorStyle(value)
+	case "theme":
+		s.Renderer.Theme, err = parseTheme(value)
+	default:
+		return fmt.Errorf("unknown setting %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("expected a positive number, got %d", n)
+	}
+	return n, nil
+}
+
+func parseLineEnding(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "lf":
+		return editor.LineEndingLF, nil
+	case "crlf":
+		return editor.LineEndingCRLF, nil
+	}
+	return "", fmt.Errorf("expected lf or crlf, got %q", value)
+}
+
+// cursorStyles maps the names of cursor styles to styles.
+var cursorStyles = map[string]renderer.CursorStyle{
+	"default":            renderer.CursorDefault,
+	"blinking-block":     renderer.CursorBlinkBlock,
+	"block":              renderer.CursorSteadyBlock,
+	"blinking-underline": renderer.CursorBlinkUnderline,
+	"underline":          renderer.CursorSteadyUnderline,
+	"blinking-bar":       renderer.CursorBlinkBar,
+	"bar":                renderer.CursorSteadyBar,
+}
+
+func parseCursorStyle(value string) (renderer.CursorStyle, error) {
+	if style, ok := cursorStyles[strings.ToLower(value)]; ok {
+		return style, nil
+	}
+	return 0, fmt.Errorf("unknown cursor style %q", value)
+}
+
+// themes maps the names of the built-in themes to themes.
+var themes = map[string]renderer.Theme{
+	"default": {},
+	"dark":    renderer.DarkTheme,
+	"light":   renderer.LightTheme,
+}
+
+// parseTheme returns the built-in theme named value, or else the theme read
+// from the JSON file at the path value.
+func parseTheme(value string) (renderer.Theme, error) {
+	if theme, ok := themes[strings.ToLower(value)]; ok {
+		return theme, nil
+	}
+	return renderer.LoadTheme(value)
+}
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/angusgmorrison/gila/editor"
+	"github.com/angusgmorrison/gila/renderer"
+)
+
+func Test_Parse(t *testing.T) {
+	t.Parallel()
+
+	input := `# Settings
+tab_stop = 4
+indent_size = 2
+line_ending = CRLF
+soft_wrap = true
+scroll_past_end = true
+trim_trailing_whitespace = true
+lazy_load_size = 1048576
+status_msg_duration = 3s
+cursor_style = blinking-bar
+theme = Dark
+
+# Bindings
+bind Ctrl-X = quit
+bind ctrl-q = save
+unbind Ctrl-S
+bind = = comment
+`
+	wantKeyMap := editor.DefaultKeyMap()
+	delete(wantKeyMap, "Ctrl-Q")
+	delete(wantKeyMap, "Ctrl-S")
+	wantKeyMap["Ctrl-X"] = editor.CmdQuit
+	wantKeyMap["ctrl-q"] = editor.CmdSave
+	wantKeyMap["="] = editor.CmdComment
+
+	want := Settings{
+		Editor: editor.Config{
+			TabStop:                4,
+			IndentSize:             2,
+			LineEnding:             editor.LineEndingCRLF,
+			SoftWrap:               true,
+			ScrollPastEnd:          true,
+			TrimTrailingWhitespace: true,
+			LazyLoadSize:           1 << 20,
+			StatusMsgDuration:      3 * time.Second,
+			KeyMap:                 wantKeyMap,
+		},
+		Renderer: renderer.Config{
+			StatusMsgDuration: 3 * time.Second,
+			CursorStyle:       renderer.CursorBlinkBar,
+			Theme:             renderer.DarkTheme,
+		},
+	}
+
+	got, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func Test_Parse_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "when a setting is unknown it returns an error",
+			input:   "\nline_numbers = true\n",
+			wantErr: `line 2: unknown setting "line_numbers"`,
+		},
+		{
+			name:    "when a boolean is malformed it returns an error",
+			input:   "soft_wrap = maybe",
+			wantErr: "line 1: soft_wrap:",
+		},
+		{
+			name:    "when a number isn't positive it returns an error",
+			input:   "tab_stop = 0",
+			wantErr: "line 1: tab_stop: expected a positive number, got 0",
+		},
+		{
+			name:    "when a key is unknown it returns an error",
+			input:   "# comment\nbind Ctrl-Foo = quit",
+			wantErr: `line 2: key "Ctrl-Foo"`,
+		},
+		{
+			name:    "when a command is unknown it returns an error",
+			input:   "bind Ctrl-X = explode",
+			wantErr: `line 1: key "Ctrl-X": unknown command "explode"`,
+		},
+		{
+			name:    "when a binding has no command it returns an error",
+			input:   "bind Ctrl-X =",
+			wantErr: "line 1: expected \"bind KEY = COMMAND\"",
+		},
+		{
+			name:    "when a line has no = it returns an error",
+			input:   "soft_wrap",
+			wantErr: "line 1: expected \"setting = value\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(strings.NewReader(tc.input))
+			if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+				t.Errorf("expected error starting with %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_load(t *testing.T) {
+	t.Parallel()
+
+	t.Run("when no config file exists it returns the zero Settings", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		got, err := load([]string{filepath.Join(dir, "config"), filepath.Join(dir, ".gilarc")})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, Settings{}) {
+			t.Errorf("expected zero Settings, got %+v", got)
+		}
+	})
+
+	t.Run("it reads the first config file that exists", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		second := filepath.Join(dir, ".gilarc")
+		third := filepath.Join(dir, "other")
+		if err := os.WriteFile(second, []byte("tab_stop = 2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(third, []byte("tab_stop = 8\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := load([]string{filepath.Join(dir, "config"), second, third})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Editor.TabStop != 2 {
+			t.Errorf("expected tab stop 2, got %d", got.Editor.TabStop)
+		}
+	})
+
+	t.Run("when the config file is malformed it returns an error naming the file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config")
+		if err := os.WriteFile(path, []byte("tab_stop = four\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := load([]string{path})
+		if err == nil || !strings.HasPrefix(err.Error(), path+": line 1: tab_stop:") {
+			t.Errorf("expected error naming %s, got %v", path, err)
+		}
+	})
+}
+
+func Test_Settings_ApplyEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		config       string
+		env          map[string]string
+		wantTabStop  int
+		wantHardTabs bool
+		wantDebug    bool
+		wantErr      bool
+	}{
+		{
+			name:        "when no variables are set the config file applies",
+			config:      "tab_stop = 8",
+			wantTabStop: 8,
+		},
+		{
+			name:         "when variables are set they override the config file",
+			config:       "tab_stop = 8\nexpand_tabs = true",
+			env:          map[string]string{"GILA_TABSTOP": "2", "GILA_EXPAND_TABS": "false"},
+			wantTabStop:  2,
+			wantHardTabs: true,
+		},
+		{
+			name:        "when a variable is invalid the config file applies",
+			config:      "tab_stop = 8",
+			env:         map[string]string{"GILA_TABSTOP": "wide"},
+			wantTabStop: 8,
+			wantErr:     true,
+		},
+		{
+			name:         "when a variable is invalid the others still apply",
+			env:          map[string]string{"GILA_TABSTOP": "-1", "GILA_EXPAND_TABS": "false"},
+			wantHardTabs: true,
+			wantErr:      true,
+		},
+		{
+			name:      "when GILA_DEBUG is set it enables debug logging",
+			env:       map[string]string{"GILA_DEBUG": "1"},
+			wantDebug: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := Parse(strings.NewReader(tc.config))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			lookupEnv := func(key string) (string, bool) {
+				value, ok := tc.env[key]
+				return value, ok
+			}
+			if err := s.ApplyEnv(lookupEnv); (err != nil) != tc.wantErr {
+				t.Errorf("expected error %t, got %v", tc.wantErr, err)
+			}
+			if s.Editor.TabStop != tc.wantTabStop {
+				t.Errorf("expected tab stop %d, got %d", tc.wantTabStop, s.Editor.TabStop)
+			}
+			if s.Editor.HardTabs != tc.wantHardTabs {
+				t.Errorf("expected hard tabs %t, got %t", tc.wantHardTabs, s.Editor.HardTabs)
+			}
+			if s.Editor.Debug != tc.wantDebug {
+				t.Errorf("expected debug %t, got %t", tc.wantDebug, s.Editor.Debug)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package editor
+
+// GutterWidth is the number of screen columns reserved on the left of each
+// line for annotation gutter marks when Config.Gutter is set.
+const GutterWidth = 2
+
+// ColorSpan applies an SGR graphic rendition, such as "1;31" for bold red, to
+// the runes of a line in the half-open range [Start, End). SGR may instead be
+// the name of a syntax class, which the renderer colors according to its
+// theme.
+type ColorSpan struct {
+	Start, End int
+	SGR        string
+}
+
+// Syntax classes that a ColorSpan's SGR may name.
+const (
+	SyntaxKeyword = "keyword"
+	SyntaxString  = "string"
+	SyntaxComment = "comment"
+	SyntaxNumber  = "number"
+)
+
+// Annotation decorates a line independently of its content, for example with
+// syntax highlighting, a version control marker or a linter message.
+type Annotation struct {
+	// Line is the 1-indexed line to decorate.
+	Line int
+	// Gutter is a mark to display to the left of the line if the gutter is
+	// enabled. Zero means no mark.
+	Gutter rune
+	// Message is displayed in the message bar while the cursor is on the
+	// line and no status message is showing.
+	Message string
+	Spans   []ColorSpan
+}
+
+// Annotate adds ann to the annotations passed to the renderer with each frame.
+func (e *Editor) Annotate(ann Annotation) {
+	e.annotations = append(e.annotations, ann)
+}
+
+// ClearAnnotations removes all annotations.
+func (e *Editor) ClearAnnotations() {
+	e.annotations = nil
+}
+
+// frameAnnotations returns the annotations to render, including those marking
+// unsaved changes if Config.DiffGutter is set, and bookmarks. Bookmarks come
+// last, so that their gutter marks take precedence.
+func (e *Editor) frameAnnotations() []Annotation {
+	if !e.config.DiffGutter && len(e.bookmarks) == 0 {
+		return e.annotations
+	}
+	var diff []Annotation
+	if e.config.DiffGutter && e.lazy == nil { // lazily loaded buffers can't change
+		diff = diffGutter(e.savedLines, lineStrings(e.lines))
+	}
+	bookmarks := e.bookmarkAnnotations()
+	anns := make([]Annotation, 0, len(diff)+len(e.annotations)+len(bookmarks))
+	anns = append(anns, diff...)
+	anns = append(anns, e.annotations...)
+	return append(anns, bookmarks...)
+}
+
+// gutter reports whether the gutter is displayed. It is shown automatically
+// while any line is bookmarked.
+func (e *Editor) gutter() bool {
+	return e.config.Gutter || e.config.DiffGutter || len(e.bookmarks) > 0
+}
+
+// textWidth returns the number of screen columns available to display text,
+// excluding the gutter.
+func (e *Editor) textWidth() int {
+	if e.gutter() {
+		return max(0, e.config.Width-GutterWidth)
+	}
+	return e.config.Width
+}
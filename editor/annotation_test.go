@@ -0,0 +1,35 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Editor_Annotate(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRenderer{}
+	e := New(nil, r, WithConfig(Config{Width: 80, Height: 24, Gutter: true}))
+	ann := Annotation{
+		Line:    1,
+		Gutter:  '+',
+		Message: "added",
+		Spans:   []ColorSpan{{Start: 0, End: 3, SGR: "32"}},
+	}
+	e.Annotate(ann)
+	e.render()
+
+	if want := []Annotation{ann}; !reflect.DeepEqual(r.last.Annotations, want) {
+		t.Errorf("expected annotations %v, got %v", want, r.last.Annotations)
+	}
+	if !r.last.Gutter {
+		t.Error("expected the frame to enable the gutter")
+	}
+
+	e.ClearAnnotations()
+	e.render()
+
+	if len(r.last.Annotations) != 0 {
+		t.Errorf("expected no annotations, got %v", r.last.Annotations)
+	}
+}
@@ -0,0 +1,125 @@
+package editor
+
+import "sort"
+
+// bookmarkGutter marks bookmarked lines in the gutter.
+const bookmarkGutter = '!'
+
+// Bookmark marks a line so that the cursor can return to it. Bookmarks last
+// for the session only.
+type Bookmark struct {
+	// Line is the 1-indexed line bookmarked.
+	Line int
+	// Note is shown in the message bar while the cursor is on the line.
+	Note string
+}
+
+// Bookmarks returns the document's bookmarks in line order.
+func (e *Editor) Bookmarks() []Bookmark {
+	bookmarks := make([]Bookmark, 0, len(e.bookmarks))
+	for _, b := range e.bookmarks {
+		bookmarks = append(bookmarks, b)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return bookmarks[i].Line < bookmarks[j].Line
+	})
+	return bookmarks
+}
+
+// toggleBookmark removes the bookmark on the current line, or adds one with an
+// optional note read from the prompt. It returns false if the prompt fails.
+func (e *Editor) toggleBookmark() bool {
+	line := e.cursor.line
+	if _, ok := e.bookmarks[line]; ok {
+		delete(e.bookmarks, line)
+		e.setStatus("Bookmark removed")
+		return true
+	}
+
+	if !e.prompt("Bookmark note (optional): %s") { // IO error
+		return false
+	}
+	note := e.promptBuf.String()
+	e.promptBuf.clear()
+	if e.bookmarks == nil {
+		e.bookmarks = make(map[int]Bookmark)
+	}
+	e.bookmarks[line] = Bookmark{Line: line, Note: note}
+	e.setStatus("Bookmark added")
+	return true
+}
+
+// nextBookmark moves the cursor to the next bookmarked line, wrapping around to
+// the first bookmark from the end of the document.
+func (e *Editor) nextBookmark() {
+	bookmarks := e.Bookmarks()
+	if len(bookmarks) == 0 {
+		e.setStatus("No bookmarks")
+		return
+	}
+	for _, b := range bookmarks {
+		if b.Line > e.cursor.line {
+			e.jumpToBookmark(b)
+			return
+		}
+	}
+	e.jumpToBookmark(bookmarks[0])
+	e.setStatus("Wrapped to first bookmark")
+}
+
+// prevBookmark moves the cursor to the previous bookmarked line, wrapping
+// around to the last bookmark from the start of the document.
+func (e *Editor) prevBookmark() {
+	bookmarks := e.Bookmarks()
+	if len(bookmarks) == 0 {
+		e.setStatus("No bookmarks")
+		return
+	}
+	for i := len(bookmarks) - 1; i >= 0; i-- {
+		if bookmarks[i].Line < e.cursor.line {
+			e.jumpToBookmark(bookmarks[i])
+			return
+		}
+	}
+	e.jumpToBookmark(bookmarks[len(bookmarks)-1])
+	e.setStatus("Wrapped to last bookmark")
+}
+
+// jumpToBookmark moves the cursor to the start of the bookmarked line, or the
+// last line if the document has since shrunk.
+func (e *Editor) jumpToBookmark(b Bookmark) {
+	e.cursor.line = min(b.Line, max(1, e.len()))
+	e.cursor.home()
+}
+
+// bookmarkAnnotations returns annotations marking each bookmarked line in the
+// gutter and displaying its note.
+func (e *Editor) bookmarkAnnotations() []Annotation {
+	bookmarks := e.Bookmarks()
+	anns := make([]Annotation, len(bookmarks))
+	for i, b := range bookmarks {
+		anns[i] = Annotation{Line: b.Line, Gutter: bookmarkGutter, Message: b.Note}
+	}
+	return anns
+}
+
+// shiftBookmarks keeps bookmarks on the lines they mark when n lines are
+// inserted before the 0-indexed line i, or -n lines are deleted from i.
+// Bookmarks on deleted lines are removed.
+func (e *Editor) shiftBookmarks(i, n int) {
+	if len(e.bookmarks) == 0 || n == 0 {
+		return
+	}
+	shifted := make(map[int]Bookmark, len(e.bookmarks))
+	for line, b := range e.bookmarks {
+		switch {
+		case line <= i:
+		case n < 0 && line <= i-n:
+			continue
+		default:
+			b.Line += n
+		}
+		shifted[b.Line] = b
+	}
+	e.bookmarks = shifted
+}
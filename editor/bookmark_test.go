@@ -0,0 +1,247 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Editor_toggleBookmark(t *testing.T) {
+	t.Parallel()
+
+	newEditor := func(ks ...string) *Editor {
+		e := New(keys(ks...), &fakeRenderer{}, WithConfig(Config{Width: 80, Height: 24}))
+		e.lines = []*Line{newLineFromString("one"), newLineFromString("two")}
+		e.cursor.line = 2
+		return e
+	}
+
+	t.Run("when the line has no bookmark it adds one with the note entered", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor("\x02", "t", "o", "d", "o", "\r")
+		for e.processKeypress() {
+		}
+
+		want := []Bookmark{{Line: 2, Note: "todo"}}
+		if got := e.Bookmarks(); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected bookmarks %v, got %v", want, got)
+		}
+		if e.statusMsg != "Bookmark added" {
+			t.Errorf("expected status %q, got %q", "Bookmark added", e.statusMsg)
+		}
+		if e.promptBuf.RuneLen() != 0 {
+			t.Errorf("expected the prompt to be cleared, got %q", e.promptBuf.String())
+		}
+	})
+
+	t.Run("when the note is skipped it adds a bookmark without a note", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor("\x02", "\r")
+		for e.processKeypress() {
+		}
+
+		want := []Bookmark{{Line: 2}}
+		if got := e.Bookmarks(); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected bookmarks %v, got %v", want, got)
+		}
+	})
+
+	t.Run("when the line has a bookmark it removes it", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor("\x02", "\r", "\x02")
+		for e.processKeypress() {
+		}
+
+		if got := e.Bookmarks(); len(got) != 0 {
+			t.Errorf("expected no bookmarks, got %v", got)
+		}
+		if e.statusMsg != "Bookmark removed" {
+			t.Errorf("expected status %q, got %q", "Bookmark removed", e.statusMsg)
+		}
+	})
+}
+
+func Test_Editor_nextBookmark_prevBookmark(t *testing.T) {
+	t.Parallel()
+
+	newEditor := func() *Editor {
+		e := New(nil, nil)
+		for _, s := range []string{"one", "two", "three", "four", "five"} {
+			e.lines = append(e.lines, newLineFromString(s))
+		}
+		e.bookmarks = map[int]Bookmark{
+			2: {Line: 2},
+			4: {Line: 4},
+		}
+		e.cursor.line, e.cursor.col = 3, 3
+		return e
+	}
+
+	t.Run("next", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor()
+		for _, wantLine := range []int{4, 2, 4} {
+			e.nextBookmark()
+			if e.cursor.line != wantLine || e.cursor.col != 1 {
+				t.Errorf("expected cursor at %d:1, got %d:%d", wantLine, e.cursor.line, e.cursor.col)
+			}
+		}
+	})
+
+	t.Run("next wraps around", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor()
+		e.cursor.line = 5
+		e.nextBookmark()
+		if e.cursor.line != 2 {
+			t.Errorf("expected cursor on line 2, got %d", e.cursor.line)
+		}
+		if e.statusMsg != "Wrapped to first bookmark" {
+			t.Errorf("expected wrap status, got %q", e.statusMsg)
+		}
+	})
+
+	t.Run("previous", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor()
+		for _, wantLine := range []int{2, 4, 2} {
+			e.prevBookmark()
+			if e.cursor.line != wantLine || e.cursor.col != 1 {
+				t.Errorf("expected cursor at %d:1, got %d:%d", wantLine, e.cursor.line, e.cursor.col)
+			}
+		}
+	})
+
+	t.Run("previous wraps around", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor()
+		e.cursor.line = 1
+		e.prevBookmark()
+		if e.cursor.line != 4 {
+			t.Errorf("expected cursor on line 4, got %d", e.cursor.line)
+		}
+		if e.statusMsg != "Wrapped to last bookmark" {
+			t.Errorf("expected wrap status, got %q", e.statusMsg)
+		}
+	})
+
+	t.Run("no bookmarks", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor()
+		e.bookmarks = nil
+		e.nextBookmark()
+		if e.cursor.line != 3 {
+			t.Errorf("expected the cursor not to move, got line %d", e.cursor.line)
+		}
+		if e.statusMsg != "No bookmarks" {
+			t.Errorf("expected status %q, got %q", "No bookmarks", e.statusMsg)
+		}
+	})
+}
+
+func Test_Editor_shiftBookmarks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		line int
+		edit func(e *Editor)
+		want []Bookmark
+	}{
+		{
+			name: "when a line is split above a bookmark it moves the bookmark down",
+			line: 1,
+			edit: func(e *Editor) { e.newLine() },
+			want: []Bookmark{{Line: 3, Note: "two"}, {Line: 5, Note: "four"}},
+		},
+		{
+			name: "when a line is split below a bookmark it leaves the bookmark in place",
+			line: 5,
+			edit: func(e *Editor) { e.newLine() },
+			want: []Bookmark{{Line: 2, Note: "two"}, {Line: 4, Note: "four"}},
+		},
+		{
+			name: "when a line is deleted above a bookmark it moves the bookmark up",
+			line: 3,
+			edit: func(e *Editor) { e.deleteCurrentLine() },
+			want: []Bookmark{{Line: 2, Note: "two"}, {Line: 3, Note: "four"}},
+		},
+		{
+			name: "when a bookmarked line is deleted it removes the bookmark",
+			line: 2,
+			edit: func(e *Editor) { e.deleteCurrentLine() },
+			want: []Bookmark{{Line: 3, Note: "four"}},
+		},
+		{
+			name: "when lines are merged above a bookmark it moves the bookmark up",
+			line: 3,
+			edit: func(e *Editor) { e.mergeCurrentLineWithPrevious() },
+			want: []Bookmark{{Line: 2, Note: "two"}, {Line: 3, Note: "four"}},
+		},
+		{
+			name: "when several lines are deleted it shifts the bookmarks that follow once",
+			line: 1,
+			edit: func(e *Editor) { e.deleteLines(0, 3) },
+			want: []Bookmark{{Line: 1, Note: "four"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(nil, nil)
+			for _, s := range []string{"one", "two", "three", "four", "five"} {
+				e.lines = append(e.lines, newLineFromString(s))
+			}
+			e.bookmarks = map[int]Bookmark{
+				2: {Line: 2, Note: "two"},
+				4: {Line: 4, Note: "four"},
+			}
+			e.cursor.line, e.cursor.col = tc.line, 1
+
+			tc.edit(e)
+
+			if got := e.Bookmarks(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected bookmarks %v, got %v", tc.want, got)
+			}
+			for _, b := range tc.want {
+				if got := e.bookmarks[b.Line]; got != b {
+					t.Errorf("expected bookmark %v to be keyed by line %d, got %v", b, b.Line, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_Editor_frameAnnotations_bookmarks(t *testing.T) {
+	t.Parallel()
+
+	e := New(nil, nil)
+	e.lines = []*Line{newLineFromString("one"), newLineFromString("two")}
+	e.Annotate(Annotation{Line: 2, Gutter: 'E'})
+	if e.gutter() {
+		t.Errorf("expected no gutter before any line is bookmarked")
+	}
+	e.bookmarks = map[int]Bookmark{2: {Line: 2, Note: "todo"}}
+
+	want := []Annotation{
+		{Line: 2, Gutter: 'E'},
+		{Line: 2, Gutter: bookmarkGutter, Message: "todo"},
+	}
+	if got := e.frameAnnotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected annotations %+v, got %+v", want, got)
+	}
+	if !e.gutter() {
+		t.Errorf("expected the gutter to be shown while a line is bookmarked")
+	}
+}
@@ -0,0 +1,120 @@
+package editor
+
+import (
+	"path/filepath"
+
+	"github.com/angusgmorrison/gila/editorconfig"
+	"github.com/angusgmorrison/gila/find"
+)
+
+// buffer holds the state of a single open document.
+type buffer struct {
+	cursor   *Cursor
+	filepath string
+	filename string
+	// The text in the buffer.
+	lines []*Line
+	// The line ending detected when the document was opened.
+	lineEnding string
+	// Whether the last line is followed by a line ending when saved.
+	finalNewline bool
+	// Whether the opened file began with a UTF-8 byte-order mark.
+	bom bool
+	// Whether a backup of the original file has been made this session.
+	backedUp bool
+	// The state of the file on disk when it was last opened or saved, or nil
+	// if the buffer has no file.
+	diskStamp *fileStamp
+	// The text of each line as of the last save, used to find unsaved changes.
+	savedLines []string
+	// Bookmarks by 1-indexed line.
+	bookmarks map[int]Bookmark
+	// The settings given by .editorconfig files for the file, which override
+	// the editor's configuration while the buffer is active.
+	editorConfig editorconfig.EC
+	// The on-disk index of a file too large to read into memory, or nil if the
+	// buffer holds every line.
+	lazy *lazyLines
+	// The hits listed one per line if this is the search results buffer, or
+	// nil otherwise.
+	hits  []find.SearchHit
+	dirty bool
+}
+
+func newBuffer() *buffer {
+	return &buffer{
+		cursor:       newCursor(),
+		filename:     defaultFilename,
+		finalNewline: true,
+	}
+}
+
+// pristine reports whether b is an empty, unnamed buffer that has never been
+// edited, such as the one the editor starts with.
+func (b *buffer) pristine() bool {
+	return b.filepath == "" && !b.dirty && len(b.lines) == 0
+}
+
+// OpenBuffer opens the file at path in a new buffer. If the only buffer is the
+// empty one the editor starts with, it is replaced, making the new buffer
+// active; otherwise the active buffer is unchanged. If the file can't be read,
+// OpenBuffer returns an error and the open buffers are left as they were.
+func (e *Editor) OpenBuffer(path string) error {
+	progress, stop := e.loadProgress("Loading " + filepath.Base(path))
+	defer stop()
+	active := e.buffer
+	e.buffer = newBuffer()
+	err := e.open(path, progress)
+	opened := e.buffer
+	e.buffer = active
+	if err != nil {
+		e.applyEditorConfig()
+		return err
+	}
+
+	if len(e.buffers) == 1 && e.buffers[0] == active && active.pristine() {
+		e.buffers[0] = opened
+		e.buffer = opened
+	} else {
+		e.buffers = append(e.buffers, opened)
+	}
+	e.applyEditorConfig()
+	return nil
+}
+
+// switchBuffer makes the buffer delta places after the active buffer active,
+// wrapping around at either end.
+func (e *Editor) switchBuffer(delta int) {
+	if len(e.buffers) < 2 {
+		e.setStatus("No other files open")
+		return
+	}
+	i := e.bufferIndex()
+	i = ((i+delta)%len(e.buffers) + len(e.buffers)) % len(e.buffers)
+	e.buffer = e.buffers[i]
+	e.applyEditorConfig()
+	e.setStatus("%s (%d/%d)", e.filename, i+1, len(e.buffers))
+}
+
+// bufferIndex returns the index of the active buffer in e.buffers.
+func (e *Editor) bufferIndex() int {
+	for i, b := range e.buffers {
+		if b == e.buffer {
+			return i
+		}
+	}
+	return 0
+}
+
+// anyDirty reports whether any open buffer has unsaved changes.
+func (e *Editor) anyDirty() bool {
+	if e.dirty {
+		return true
+	}
+	for _, b := range e.buffers {
+		if b.dirty {
+			return true
+		}
+	}
+	return false
+}
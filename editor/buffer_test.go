@@ -0,0 +1,127 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Editor_Run_multipleFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit and save the first file, switch to the second and do the same, then
+	// switch back.
+	kr := keys("a", "\x13", "\x1b.", "b", "\x13", "\x1b.")
+	r := &fakeRenderer{}
+	e := New(kr, r, WithConfig(Config{Width: 80, Height: 24}))
+	if err := e.Run(first, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for path, want := range map[string]string{first: "aone\n", second: "btwo\n"} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("expected %s to contain %q, got %q", path, want, got)
+		}
+	}
+	if r.last.Filename != "first.txt" {
+		t.Errorf("expected first.txt to be active, got %s", r.last.Filename)
+	}
+	if want := "first.txt (1/2)"; r.last.StatusMsg != want {
+		t.Errorf("expected status %q, got %q", want, r.last.StatusMsg)
+	}
+}
+
+func Test_Editor_OpenBuffer(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, []byte("text\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("when the editor has an empty buffer the file replaces it", func(t *testing.T) {
+		t.Parallel()
+
+		e := New(nil, nil)
+		if err := e.OpenBuffer(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(e.buffers) != 1 {
+			t.Errorf("expected 1 buffer, got %d", len(e.buffers))
+		}
+		if e.filename != "file.txt" {
+			t.Errorf("expected file.txt to be active, got %s", e.filename)
+		}
+	})
+
+	t.Run("when a file is already open the active buffer is unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		e := NewHeadless("existing\n")
+		if err := e.OpenBuffer(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(e.buffers) != 2 {
+			t.Errorf("expected 2 buffers, got %d", len(e.buffers))
+		}
+		if got, want := e.String(), "existing\n"; got != want {
+			t.Errorf("expected active document %q, got %q", want, got)
+		}
+	})
+
+	t.Run("when the file can't be read it returns an error and opens nothing", func(t *testing.T) {
+		t.Parallel()
+
+		e := NewHeadless("existing\n")
+		if err := e.OpenBuffer(filepath.Join(dir, "missing.txt")); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(e.buffers) != 1 {
+			t.Errorf("expected 1 buffer, got %d", len(e.buffers))
+		}
+		if got, want := e.String(), "existing\n"; got != want {
+			t.Errorf("expected active document %q, got %q", want, got)
+		}
+	})
+}
+
+func Test_Editor_quit_unsavedBuffer(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, []byte("text\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit the second buffer, then switch back to the unmodified first buffer
+	// and quit once.
+	e := NewHeadless("saved", WithKeyReader(Keys([]byte("\x1b."), []byte("x"), []byte("\x1b,"), []byte("\x11"))))
+	if err := e.OpenBuffer(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		e.processKeypress()
+	}
+	if e.bufferIndex() != 0 {
+		t.Fatalf("expected the first buffer to be active, got %d", e.bufferIndex())
+	}
+	if !e.processKeypress() {
+		t.Errorf("expected quit to be refused with unsaved changes in another buffer")
+	}
+}
@@ -1,6 +1,10 @@
 package editor
 
-import "github.com/angusgmorrison/gila/intutil"
+import (
+	"unicode/utf8"
+
+	"github.com/angusgmorrison/gila/intutil"
+)
 
 // defaultCursorMargin controls the number of characters between the left-hand
 // edge of the screen and the cursor when scrolling left, allowing the user to
@@ -11,19 +15,27 @@ const defaultCursorMargin = 3
 // It uses offsets starting from 0 to represent the Cursor's position within a
 // document too wide or long to fit terminal window.
 //
+// col counts runes, whereas renderCol and colOffset count terminal cells,
+// which differ when the line contains wide characters.
+//
 // Cursor's directional methods (e.g. left, right, home) return booleans
 // indicating whether the requested movement was possible given the bounds of
 // the editor. Requesting a movement that is not possible is not considered an
 // error.
 type Cursor struct {
 	col, line             int
+	renderCol             int
 	colOffset, lineOffset int
+	// wrappedRows is the number of extra screen rows occupied by soft-wrapped
+	// lines between the top of the screen and the cursor.
+	wrappedRows int
 }
 
 func newCursor() *Cursor {
 	return &Cursor{
-		col:  1,
-		line: 1,
+		col:       1,
+		renderCol: 1,
+		line:      1,
 	}
 }
 
@@ -37,7 +49,7 @@ func (c *Cursor) Line() int {
 	return c.line
 }
 
-// ColOffset returns the cursor's column offset.
+// ColOffset returns the cursor's column offset in terminal cells.
 func (c *Cursor) ColOffset() int {
 	return c.colOffset
 }
@@ -49,12 +61,12 @@ func (c *Cursor) LineOffset() int {
 
 // X returns the 1-indexed X-coordinate of the cursor relative to the screen.
 func (c *Cursor) X() int {
-	return c.col - c.colOffset
+	return c.renderCol - c.colOffset
 }
 
 // x returns the 1-indexed Y-coordinate of the cursor relative to the screen.
 func (c *Cursor) Y() int {
-	return c.line - c.lineOffset
+	return c.line - c.lineOffset + c.wrappedRows
 }
 
 // left moves the cursor left by one character. If the cursor is already at the
@@ -120,19 +132,175 @@ func (c *Cursor) pageUp(height int) {
 	// two to account for the line offset's zero index and to leave the top line
 	// of the previous screen visible at the bottom of the new screen.
 	targetLine := c.lineOffset - height + 2
-	c.line = intutil.Max(1, targetLine)
+	c.line = max(1, targetLine)
 }
 
-func (c *Cursor) pageDown(height, nLines int) {
+// pageUpWrapped is the soft-wrap equivalent of pageUp, moving the cursor up by
+// one page of screen rows rather than lines. It always moves at least one line
+// up, even if the line above is taller than the screen.
+func (c *Cursor) pageUpWrapped(lines []*Line, width, height int) {
+	top := c.lineOffset + 1
+	target := top
+	for rows := 0; target > 1; target-- {
+		rows += wrappedRowCount(lines, target-1, width)
+		if rows > height-1 {
+			break
+		}
+	}
+	if target == top {
+		target--
+	}
+	c.line = max(1, target)
+}
+
+// pageDownWrapped is the soft-wrap equivalent of pageDown, moving the cursor
+// down by one page of screen rows rather than lines. It always moves at least
+// one line below the top of the screen, even if the top line is taller than
+// the screen.
+func (c *Cursor) pageDownWrapped(lines []*Line, width, height int) {
+	top := c.lineOffset + 1
+	target := top
+	for rows := 0; target <= len(lines); target++ {
+		rows += wrappedRowCount(lines, target, width)
+		if rows > 2*height-1 {
+			break
+		}
+	}
+	c.line = intutil.Clamp(target-1, top+1, len(lines)+1)
+	if target > len(lines) {
+		c.line = len(lines) + 1
+	}
+}
+
+func (c *Cursor) pageDown(height, nLines int, pastEnd bool) {
 	// The target line is one full page below the bottom of the current page,
 	// less one line to allow the last line of the previous screen to be visible
 	// at the top of the new screen.
 	targetLine := (c.lineOffset + height - 1) + height
-	c.line = intutil.Min(nLines+1, targetLine)
+	c.line = min(nLines+1, targetLine)
+	if pastEnd {
+		// The cursor stops at the end of the document, but the screen keeps
+		// scrolling a full page at a time.
+		c.lineOffset = min(c.lineOffset+height-1, maxLineOffset(height, nLines, true))
+	}
+}
+
+// maxLineOffset returns the furthest a screen of the given height can scroll
+// down a document of nLines lines. Normally, the screen stops once the insert
+// point after the last line is on its bottom row. If pastEnd is true, it can
+// scroll until the last line is on its top row.
+func maxLineOffset(height, nLines int, pastEnd bool) int {
+	if pastEnd {
+		return max(0, nLines-1)
+	}
+	return max(0, nLines+1-height)
+}
+
+// centerVertically scrolls a screen of the given height so that the cursor's
+// line is in the middle, without moving the cursor. The screen can't scroll
+// above the top of the document or beyond maxLineOffset, so lines near either
+// end may not be centered.
+func (c *Cursor) centerVertically(height, nLines int, pastEnd bool) {
+	c.lineOffset = intutil.Clamp(c.line-1-height/2, 0, maxLineOffset(height, nLines, pastEnd))
+}
+
+// scrollViewUp scrolls a screen of the given height up by one line, leaving
+// the cursor on its line unless that line would leave the bottom of the screen,
+// in which case the cursor moves up with it. At the top of the document, it
+// does nothing.
+func (c *Cursor) scrollViewUp(height int) {
+	if c.lineOffset == 0 {
+		return
+	}
+	c.lineOffset--
+	c.line = min(c.line, c.lineOffset+height)
+}
+
+// scrollViewUpWrapped is the soft-wrap equivalent of scrollViewUp, moving the
+// cursor up until every screen row from the top of the screen to the bottom of
+// the cursor's line fits on the screen.
+func (c *Cursor) scrollViewUpWrapped(lines []*Line, width, height int) {
+	if c.lineOffset == 0 {
+		return
+	}
+	c.lineOffset--
+	rows := 0
+	for i := c.lineOffset + 1; i <= c.line; i++ {
+		rows += wrappedRowCount(lines, i, width)
+	}
+	for rows > height && c.line > c.lineOffset+1 {
+		rows -= wrappedRowCount(lines, c.line, width)
+		c.line--
+	}
 }
 
-func (c *Cursor) scroll(width, height int) {
-	zeroIdxLine, zeroIdxCol := c.line-1, c.col-1
+// scrollViewDown scrolls a screen of the given height down by one line, leaving
+// the cursor on its line unless that line would leave the top of the screen, in
+// which case the cursor moves down with it. The screen stops scrolling at
+// maxLineOffset.
+func (c *Cursor) scrollViewDown(height, nLines int, pastEnd bool) {
+	if c.lineOffset >= maxLineOffset(height, nLines, pastEnd) {
+		return
+	}
+	c.lineOffset++
+	c.line = max(c.line, c.lineOffset+1)
+}
+
+// scrollWrapped is the soft-wrap equivalent of scroll. Lines are never
+// scrolled horizontally. Instead, the cursor's screen position accounts for the
+// screen rows occupied by wrapped lines, and the line offset advances until the
+// cursor's row fits on the screen.
+func (c *Cursor) scrollWrapped(lines []*Line, width, height int) {
+	var line *Line
+	if c.line <= len(lines) {
+		line = lines[c.line-1]
+	}
+	starts := line.WrapPoints(width)
+	row := len(starts) - 1
+	for row > 0 && starts[row] > c.col-1 {
+		row--
+	}
+	c.renderCol = line.DisplayWidth(c.col-1) - line.DisplayWidth(starts[row]) + 1
+	if line == nil {
+		c.renderCol = c.col
+	}
+	c.colOffset = 0
+
+	if c.line-1 < c.lineOffset {
+		c.lineOffset = c.line - 1
+	}
+	// The number of screen rows from the top of the screen to the cursor.
+	rows := row + 1
+	for i := c.lineOffset + 1; i < c.line; i++ {
+		rows += wrappedRowCount(lines, i, width)
+	}
+	for rows > height && c.lineOffset < c.line-1 {
+		rows -= wrappedRowCount(lines, c.lineOffset+1, width)
+		c.lineOffset++
+	}
+	c.wrappedRows = rows - (c.line - c.lineOffset)
+}
+
+// wrappedRowCount returns the number of screen rows occupied by the 1-indexed
+// line n when wrapped to width. Lines beyond the end of the document occupy
+// one row.
+func wrappedRowCount(lines []*Line, n, width int) int {
+	if n < 1 || n > len(lines) {
+		return 1
+	}
+	return len(lines[n-1].WrapPoints(width))
+}
+
+// scroll updates the cursor's render column for the line it's on, which is nil
+// past the end of the document, then adjusts the offsets to keep the cursor on
+// a screen of the given dimensions.
+func (c *Cursor) scroll(line *Line, width, height int) {
+	c.renderCol = line.DisplayWidth(c.col-1) + 1
+	if line == nil {
+		c.renderCol = c.col
+	}
+	c.wrappedRows = 0 // left over if soft wrapping was just turned off
+	zeroIdxLine, zeroIdxCol := c.line-1, c.renderCol-1
 	// Scroll up: if the cursor is above the last-known offset, update the
 	// offset to the current cursor position.
 	if zeroIdxLine < c.lineOffset {
@@ -148,7 +316,7 @@ func (c *Cursor) scroll(width, height int) {
 	// to the the current cursor position plus a margin that allows the user to
 	// see a few characters preceding the cursor.
 	if zeroIdxCol < c.colOffset+defaultCursorMargin {
-		c.colOffset = intutil.Max(0, zeroIdxCol-defaultCursorMargin)
+		c.colOffset = max(0, zeroIdxCol-defaultCursorMargin)
 	}
 	// Scroll right: if the cursor is right of the right margin, update the
 	// offset so that it shows a full screen of text where the cursor is in the
@@ -157,3 +325,44 @@ func (c *Cursor) scroll(width, height int) {
 		c.colOffset = zeroIdxCol - width + 1
 	}
 }
+
+// AbsoluteByteOffset returns the cursor's position as a UTF-8 byte offset from
+// the start of the document formed by lines, where each line is terminated by
+// a single '\n'. A cursor positioned beyond the end of its line or the
+// document is clamped to the nearest valid insert point.
+func (c *Cursor) AbsoluteByteOffset(lines []*Line) int {
+	line := intutil.Clamp(c.line, 1, len(lines)+1)
+	var offset int
+	for _, l := range lines[:line-1] {
+		offset += len(l.String()) + 1
+	}
+	if line > len(lines) { // the insert point after the last line
+		return offset
+	}
+	runes := lines[line-1].Runes()
+	col := intutil.Clamp(c.col, 1, len(runes)+1)
+	return offset + len(string(runes[:col-1]))
+}
+
+// CursorFromByteOffset returns a cursor at the UTF-8 byte offset from the start
+// of the document formed by lines, where each line is terminated by a single
+// '\n'. It is the inverse of (*Cursor).AbsoluteByteOffset. Offsets outside the
+// document are clamped to its bounds, and offsets that fall inside a multibyte
+// rune resolve to the start of that rune.
+func CursorFromByteOffset(lines []*Line, offset int) *Cursor {
+	c := newCursor()
+	offset = max(offset, 0)
+	for _, l := range lines {
+		s := l.String()
+		if offset <= len(s) {
+			for offset < len(s) && !utf8.RuneStart(s[offset]) {
+				offset--
+			}
+			c.col = utf8.RuneCountInString(s[:offset]) + 1
+			return c
+		}
+		offset -= len(s) + 1
+		c.line++
+	}
+	return c
+}
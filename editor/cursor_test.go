@@ -2,7 +2,10 @@ package editor
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"testing/quick"
+	"unicode/utf8"
 )
 
 func Test_Cursor_Col(t *testing.T) {
@@ -596,6 +599,7 @@ func Test_Cursor_pageDown(t *testing.T) {
 		c          *Cursor
 		height     int
 		nLines     int
+		pastEnd    bool
 		wantCursor *Cursor
 	}{
 		{
@@ -630,6 +634,57 @@ func Test_Cursor_pageDown(t *testing.T) {
 				lineOffset: 0,
 			},
 		},
+		{
+			name: "when scrolling past the end is allowed and there are enough lines to fill the screen, " +
+				"it scrolls the screen as far as it would otherwise",
+			c: &Cursor{
+				col:        1,
+				line:       10,
+				lineOffset: 5,
+			},
+			height:  5,
+			nLines:  20,
+			pastEnd: true,
+			wantCursor: &Cursor{
+				col:        1,
+				line:       14,
+				lineOffset: 9,
+			},
+		},
+		{
+			name: "when scrolling past the end is allowed and there are too few lines to fill a whole screen, " +
+				"it stops the cursor at the bottom of the document and scrolls the last line to the top",
+			c: &Cursor{
+				col:        1,
+				line:       10,
+				lineOffset: 0,
+			},
+			height:  20,
+			nLines:  10,
+			pastEnd: true,
+			wantCursor: &Cursor{
+				col:        1,
+				line:       11,
+				lineOffset: 9,
+			},
+		},
+		{
+			name: "when scrolling past the end is allowed and the last line is at the top, " +
+				"it does nothing",
+			c: &Cursor{
+				col:        1,
+				line:       11,
+				lineOffset: 9,
+			},
+			height:  20,
+			nLines:  10,
+			pastEnd: true,
+			wantCursor: &Cursor{
+				col:        1,
+				line:       11,
+				lineOffset: 9,
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -638,7 +693,7 @@ func Test_Cursor_pageDown(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			tc.c.pageDown(tc.height, tc.nLines)
+			tc.c.pageDown(tc.height, tc.nLines, tc.pastEnd)
 			if !reflect.DeepEqual(tc.c, tc.wantCursor) {
 				t.Errorf("expected cursor to be %+v, got %+v", tc.wantCursor, tc.c)
 			}
@@ -707,7 +762,8 @@ func Test_Cursor_scroll(t *testing.T) {
 			t.Run(tc.name, func(t *testing.T) {
 				t.Parallel()
 
-				tc.c.scroll(20, 20)
+				tc.c.scroll(nil, 20, 20)
+				tc.wantCursor.renderCol = tc.wantCursor.col // no line, so no wide characters
 				if !reflect.DeepEqual(tc.c, tc.wantCursor) {
 					t.Errorf("expected cursor to be %+v, got %+v", tc.wantCursor, tc.c)
 				}
@@ -773,7 +829,8 @@ func Test_Cursor_scroll(t *testing.T) {
 			t.Run(tc.name, func(t *testing.T) {
 				t.Parallel()
 
-				tc.c.scroll(5, 5)
+				tc.c.scroll(nil, 5, 5)
+				tc.wantCursor.renderCol = tc.wantCursor.col // no line, so no wide characters
 				if !reflect.DeepEqual(tc.c, tc.wantCursor) {
 					t.Errorf("expected cursor to be %+v, got %+v", tc.wantCursor, tc.c)
 				}
@@ -842,7 +899,8 @@ func Test_Cursor_scroll(t *testing.T) {
 			t.Run(tc.name, func(t *testing.T) {
 				t.Parallel()
 
-				tc.c.scroll(10, 10)
+				tc.c.scroll(nil, 10, 10)
+				tc.wantCursor.renderCol = tc.wantCursor.col // no line, so no wide characters
 				if !reflect.DeepEqual(tc.c, tc.wantCursor) {
 					t.Errorf("expected cursor to be %+v, got %+v", tc.wantCursor, tc.c)
 				}
@@ -907,7 +965,8 @@ func Test_Cursor_scroll(t *testing.T) {
 			t.Run(tc.name, func(t *testing.T) {
 				t.Parallel()
 
-				tc.c.scroll(10, 10)
+				tc.c.scroll(nil, 10, 10)
+				tc.wantCursor.renderCol = tc.wantCursor.col // no line, so no wide characters
 				if !reflect.DeepEqual(tc.c, tc.wantCursor) {
 					t.Errorf("expected cursor to be %+v, got %+v", tc.wantCursor, tc.c)
 				}
@@ -915,3 +974,581 @@ func Test_Cursor_scroll(t *testing.T) {
 		}
 	})
 }
+
+func Test_Cursor_AbsoluteByteOffset(t *testing.T) {
+	t.Parallel()
+
+	lines := []*Line{
+		newLineFromString("héllo"),
+		newLineFromString(""),
+		newLineFromString("世界"),
+	}
+	testCases := []struct {
+		name   string
+		lines  []*Line
+		cursor *Cursor
+		want   int
+	}{
+		{
+			name:   "start of document",
+			lines:  lines,
+			cursor: &Cursor{line: 1, col: 1},
+			want:   0,
+		},
+		{
+			name:   "after a multibyte rune",
+			lines:  lines,
+			cursor: &Cursor{line: 1, col: 3},
+			want:   3,
+		},
+		{
+			name:   "end of line",
+			lines:  lines,
+			cursor: &Cursor{line: 1, col: 6},
+			want:   6,
+		},
+		{
+			name:   "empty line",
+			lines:  lines,
+			cursor: &Cursor{line: 2, col: 1},
+			want:   7,
+		},
+		{
+			name:   "end of last line",
+			lines:  lines,
+			cursor: &Cursor{line: 3, col: 3},
+			want:   14,
+		},
+		{
+			name:   "insert point after the last line",
+			lines:  lines,
+			cursor: &Cursor{line: 4, col: 1},
+			want:   15,
+		},
+		{
+			name:   "column beyond the end of the line is clamped",
+			lines:  lines,
+			cursor: &Cursor{line: 1, col: 10},
+			want:   6,
+		},
+		{
+			name:   "empty document",
+			lines:  nil,
+			cursor: &Cursor{line: 1, col: 1},
+			want:   0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.cursor.AbsoluteByteOffset(tc.lines); got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_CursorFromByteOffset(t *testing.T) {
+	t.Parallel()
+
+	lines := []*Line{
+		newLineFromString("héllo"),
+		newLineFromString("世界"),
+	}
+	testCases := []struct {
+		name     string
+		offset   int
+		wantLine int
+		wantCol  int
+	}{
+		{name: "negative offset", offset: -1, wantLine: 1, wantCol: 1},
+		{name: "start of document", offset: 0, wantLine: 1, wantCol: 1},
+		{name: "inside a multibyte rune", offset: 2, wantLine: 1, wantCol: 2},
+		{name: "end of line", offset: 6, wantLine: 1, wantCol: 6},
+		{name: "start of next line", offset: 7, wantLine: 2, wantCol: 1},
+		{name: "inside a wide rune", offset: 9, wantLine: 2, wantCol: 1},
+		{name: "end of document", offset: 14, wantLine: 3, wantCol: 1},
+		{name: "beyond the document", offset: 100, wantLine: 3, wantCol: 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := CursorFromByteOffset(lines, tc.offset)
+			if c.line != tc.wantLine || c.col != tc.wantCol {
+				t.Errorf("expected %d:%d, got %d:%d", tc.wantLine, tc.wantCol, c.line, c.col)
+			}
+		})
+	}
+}
+
+func Test_Cursor_byteOffsetRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	toLines := func(texts []string) []*Line {
+		lines := make([]*Line, len(texts))
+		for i, text := range texts {
+			lines[i] = newLineFromString(strings.ReplaceAll(text, "\n", ""))
+		}
+		return lines
+	}
+
+	// Every valid cursor position maps to an offset and back again.
+	cursorRoundTrip := func(texts []string) bool {
+		lines := toLines(texts)
+		for line := 1; line <= len(lines)+1; line++ {
+			lineLen := 0
+			if line <= len(lines) {
+				lineLen = lines[line-1].RuneLen()
+			}
+			for col := 1; col <= lineLen+1; col++ {
+				c := &Cursor{line: line, col: col}
+				got := CursorFromByteOffset(lines, c.AbsoluteByteOffset(lines))
+				if got.line != line || got.col != col {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(cursorRoundTrip, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Every offset at a rune boundary maps to a cursor and back again.
+	offsetRoundTrip := func(texts []string) bool {
+		lines := toLines(texts)
+		var doc strings.Builder
+		for _, l := range lines {
+			doc.WriteString(l.String())
+			doc.WriteByte('\n')
+		}
+		s := doc.String()
+		for offset := 0; offset <= len(s); offset++ {
+			if offset < len(s) && !utf8.RuneStart(s[offset]) {
+				continue
+			}
+			if got := CursorFromByteOffset(lines, offset).AbsoluteByteOffset(lines); got != offset {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(offsetRoundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Cursor_scrollWrapped(t *testing.T) {
+	t.Parallel()
+
+	// With a width of 4, the lines occupy 1, 3 and 2 rows respectively.
+	lines := []*Line{
+		newLineFromString("ab"),
+		newLineFromString("abcdefghij"),
+		newLineFromString("abcde"),
+	}
+	testCases := []struct {
+		name           string
+		c              *Cursor
+		height         int
+		wantLineOffset int
+		wantX, wantY   int
+	}{
+		{
+			name:   "first row of the first line",
+			c:      &Cursor{line: 1, col: 2},
+			height: 10,
+			wantX:  2,
+			wantY:  1,
+		},
+		{
+			name:   "first row of a wrapped line",
+			c:      &Cursor{line: 2, col: 3},
+			height: 10,
+			wantX:  3,
+			wantY:  2,
+		},
+		{
+			name:   "last row of a wrapped line",
+			c:      &Cursor{line: 2, col: 10},
+			height: 10,
+			wantX:  2,
+			wantY:  4,
+		},
+		{
+			name:   "below a wrapped line",
+			c:      &Cursor{line: 3, col: 5},
+			height: 10,
+			wantX:  1,
+			wantY:  6,
+		},
+		{
+			name:   "past the end of the document",
+			c:      &Cursor{line: 4, col: 1},
+			height: 10,
+			wantX:  1,
+			wantY:  7,
+		},
+		{
+			name:           "scrolls down by whole lines until the cursor fits",
+			c:              &Cursor{line: 3, col: 5},
+			height:         4,
+			wantLineOffset: 2,
+			wantX:          1,
+			wantY:          2,
+		},
+		{
+			name:           "scrolls up to the cursor's line",
+			c:              &Cursor{line: 1, col: 1, lineOffset: 2},
+			height:         4,
+			wantLineOffset: 0,
+			wantX:          1,
+			wantY:          1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.c.scrollWrapped(lines, 4, tc.height)
+			if tc.c.lineOffset != tc.wantLineOffset {
+				t.Errorf("expected line offset %d, got %d", tc.wantLineOffset, tc.c.lineOffset)
+			}
+			if tc.c.X() != tc.wantX || tc.c.Y() != tc.wantY {
+				t.Errorf("expected screen position (%d, %d), got (%d, %d)",
+					tc.wantX, tc.wantY, tc.c.X(), tc.c.Y())
+			}
+		})
+	}
+}
+
+func Test_Cursor_scrollWrapped_wordBoundaries(t *testing.T) {
+	t.Parallel()
+
+	// With a width of 4, the first line breaks before "cdef", occupying 3 rows,
+	// rather than 2 if it were broken at the width.
+	lines := []*Line{
+		newLineFromString("ab cdef"),
+		newLineFromString("gh"),
+	}
+	testCases := []struct {
+		name         string
+		c            *Cursor
+		wantX, wantY int
+	}{
+		{
+			name:  "on a word moved to the next row",
+			c:     &Cursor{line: 1, col: 5},
+			wantX: 2,
+			wantY: 2,
+		},
+		{
+			name:  "below a line wrapped at a word boundary",
+			c:     &Cursor{line: 2, col: 1},
+			wantX: 1,
+			wantY: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.c.scrollWrapped(lines, 4, 10)
+			if tc.c.X() != tc.wantX || tc.c.Y() != tc.wantY {
+				t.Errorf("expected screen position (%d, %d), got (%d, %d)",
+					tc.wantX, tc.wantY, tc.c.X(), tc.c.Y())
+			}
+		})
+	}
+}
+
+func Test_Cursor_pageWrapped(t *testing.T) {
+	t.Parallel()
+
+	// With a width of 4, each line occupies 2 rows.
+	lines := make([]*Line, 20)
+	for i := range lines {
+		lines[i] = newLineFromString("abcdef")
+	}
+
+	t.Run("page down", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Cursor{line: 1, col: 1}
+		c.pageDownWrapped(lines, 4, 6)
+		// 11 rows fit in two pages less one row, so the cursor moves to the
+		// last line wholly within them.
+		if c.line != 5 {
+			t.Errorf("expected line 5, got %d", c.line)
+		}
+	})
+
+	t.Run("page down near the end", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Cursor{line: 18, col: 1, lineOffset: 17}
+		c.pageDownWrapped(lines, 4, 6)
+		if c.line != 21 {
+			t.Errorf("expected line 21, got %d", c.line)
+		}
+	})
+
+	t.Run("page up", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Cursor{line: 12, col: 1, lineOffset: 10}
+		c.pageUpWrapped(lines, 4, 6)
+		// The two lines above the top of the screen fill four of the five rows
+		// available, leaving the previous top line visible.
+		if c.line != 9 {
+			t.Errorf("expected line 9, got %d", c.line)
+		}
+	})
+
+	t.Run("page up at the top", func(t *testing.T) {
+		t.Parallel()
+
+		c := &Cursor{line: 2, col: 1}
+		c.pageUpWrapped(lines, 4, 6)
+		if c.line != 1 {
+			t.Errorf("expected line 1, got %d", c.line)
+		}
+	})
+}
+
+func Test_Cursor_centerVertically(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		line           int
+		lineOffset     int
+		height         int
+		nLines         int
+		pastEnd        bool
+		wantLineOffset int
+	}{
+		{
+			name:           "when the cursor is in the middle of the document it is centered",
+			line:           50,
+			lineOffset:     45,
+			height:         10,
+			nLines:         100,
+			wantLineOffset: 44,
+		},
+		{
+			name:           "when the cursor is near the top the screen stays at the top",
+			line:           3,
+			lineOffset:     2,
+			height:         10,
+			nLines:         100,
+			wantLineOffset: 0,
+		},
+		{
+			name:           "when the cursor is near the bottom the screen stays at the bottom",
+			line:           98,
+			lineOffset:     90,
+			height:         10,
+			nLines:         100,
+			wantLineOffset: 91,
+		},
+		{
+			name:           "when the document is shorter than the screen it isn't scrolled",
+			line:           4,
+			lineOffset:     3,
+			height:         10,
+			nLines:         5,
+			wantLineOffset: 0,
+		},
+		{
+			name:           "when scrolling past the end is allowed a line near the bottom is centered",
+			line:           98,
+			lineOffset:     90,
+			height:         10,
+			nLines:         100,
+			pastEnd:        true,
+			wantLineOffset: 92,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Cursor{line: tc.line, col: 3, lineOffset: tc.lineOffset}
+			c.centerVertically(tc.height, tc.nLines, tc.pastEnd)
+			if c.lineOffset != tc.wantLineOffset {
+				t.Errorf("expected line offset %d, got %d", tc.wantLineOffset, c.lineOffset)
+			}
+			if c.line != tc.line || c.col != 3 {
+				t.Errorf("expected cursor to stay at %d:3, got %d:%d", tc.line, c.line, c.col)
+			}
+		})
+	}
+}
+
+func Test_Cursor_scrollViewUp(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		line           int
+		lineOffset     int
+		wantLine       int
+		wantLineOffset int
+	}{
+		{
+			name:           "when the cursor stays on screen it doesn't move",
+			line:           12,
+			lineOffset:     10,
+			wantLine:       12,
+			wantLineOffset: 9,
+		},
+		{
+			name:           "when the cursor is on the bottom row it moves up with the screen",
+			line:           20,
+			lineOffset:     10,
+			wantLine:       19,
+			wantLineOffset: 9,
+		},
+		{
+			name:           "when the screen is at the top of the document nothing changes",
+			line:           10,
+			lineOffset:     0,
+			wantLine:       10,
+			wantLineOffset: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Cursor{line: tc.line, col: 3, lineOffset: tc.lineOffset}
+			c.scrollViewUp(10)
+			if c.line != tc.wantLine || c.lineOffset != tc.wantLineOffset {
+				t.Errorf("expected line %d and line offset %d, got %d and %d",
+					tc.wantLine, tc.wantLineOffset, c.line, c.lineOffset)
+			}
+		})
+	}
+}
+
+func Test_Cursor_scrollViewUpWrapped(t *testing.T) {
+	t.Parallel()
+
+	// Line 2 wraps onto three rows of width 4.
+	lines := []*Line{
+		newLineFromString("a"),
+		newLineFromString("bbbbbbbbbb"),
+		newLineFromString("c"),
+		newLineFromString("d"),
+	}
+	c := &Cursor{line: 4, col: 1, lineOffset: 2}
+	c.scrollViewUpWrapped(lines, 4, 4)
+	if c.lineOffset != 1 {
+		t.Errorf("expected line offset 1, got %d", c.lineOffset)
+	}
+	// Lines 2 to 4 need five rows, so the cursor is pushed up to line 3.
+	if c.line != 3 {
+		t.Errorf("expected line 3, got %d", c.line)
+	}
+}
+
+func Test_Cursor_scrollViewDown(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		line           int
+		lineOffset     int
+		nLines         int
+		pastEnd        bool
+		wantLine       int
+		wantLineOffset int
+	}{
+		{
+			name:           "when the cursor stays on screen it doesn't move",
+			line:           15,
+			lineOffset:     10,
+			nLines:         100,
+			wantLine:       15,
+			wantLineOffset: 11,
+		},
+		{
+			name:           "when the cursor is on the top row it moves down with the screen",
+			line:           11,
+			lineOffset:     10,
+			nLines:         100,
+			wantLine:       12,
+			wantLineOffset: 11,
+		},
+		{
+			name:           "when the insert point is at the bottom of the screen nothing changes",
+			line:           95,
+			lineOffset:     91,
+			nLines:         100,
+			wantLine:       95,
+			wantLineOffset: 91,
+		},
+		{
+			name:           "when scrolling past the end is allowed it scrolls beyond the insert point",
+			line:           95,
+			lineOffset:     91,
+			nLines:         100,
+			pastEnd:        true,
+			wantLine:       95,
+			wantLineOffset: 92,
+		},
+		{
+			name:           "when scrolling past the end is allowed and the last line is at the top nothing changes",
+			line:           100,
+			lineOffset:     99,
+			nLines:         100,
+			pastEnd:        true,
+			wantLine:       100,
+			wantLineOffset: 99,
+		},
+		{
+			name:           "when the document is empty nothing changes",
+			line:           1,
+			lineOffset:     0,
+			nLines:         0,
+			wantLine:       1,
+			wantLineOffset: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Cursor{line: tc.line, col: 3, lineOffset: tc.lineOffset}
+			c.scrollViewDown(10, tc.nLines, tc.pastEnd)
+			if c.line != tc.wantLine || c.lineOffset != tc.wantLineOffset {
+				t.Errorf("expected line %d and line offset %d, got %d and %d",
+					tc.wantLine, tc.wantLineOffset, c.line, c.lineOffset)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package editor
+
+import "github.com/angusgmorrison/gila/lcs"
+
+// snapshot records the current lines as the saved state of the document,
+// against which unsaved changes are measured.
+func (e *Editor) snapshot() {
+	e.savedLines = lineStrings(e.lines)
+}
+
+// changeStarts returns the 0-indexed lines at which groups of lines added or
+// modified since the document was last saved start. Lazily loaded buffers
+// can't be changed.
+func (e *Editor) changeStarts() []int {
+	if e.lazy != nil {
+		return nil
+	}
+	return hunkStarts(e.savedLines, lineStrings(e.lines))
+}
+
+// nextHunk moves the cursor to the start of the next group of lines added or
+// modified since the document was last saved, wrapping around to the first
+// group from the end of the document.
+func (e *Editor) nextHunk() {
+	starts := e.changeStarts()
+	if len(starts) == 0 {
+		e.setStatus("No changes")
+		return
+	}
+	for _, start := range starts {
+		if start > e.cursor.line-1 {
+			e.jumpToHunk(start)
+			return
+		}
+	}
+	e.jumpToHunk(starts[0])
+	e.setStatus("Wrapped to first change")
+}
+
+// prevHunk moves the cursor to the start of the previous group of lines added
+// or modified since the document was last saved, wrapping around to the last
+// group from the start of the document.
+func (e *Editor) prevHunk() {
+	starts := e.changeStarts()
+	if len(starts) == 0 {
+		e.setStatus("No changes")
+		return
+	}
+	for i := len(starts) - 1; i >= 0; i-- {
+		if starts[i] < e.cursor.line-1 {
+			e.jumpToHunk(starts[i])
+			return
+		}
+	}
+	e.jumpToHunk(starts[len(starts)-1])
+	e.setStatus("Wrapped to last change")
+}
+
+// jumpToHunk moves the cursor to the start of the 0-indexed line.
+func (e *Editor) jumpToHunk(line int) {
+	e.cursor.line = line + 1
+	e.cursor.home()
+}
+
+// hunkStarts returns the 0-indexed lines of current at which each group of
+// lines added or modified relative to saved begins. Groups consisting only of
+// deletions are not included, since no line of current remains to jump to.
+func hunkStarts(saved, current []string) []int {
+	var (
+		starts []int
+		inHunk bool
+	)
+	for _, op := range lcs.LCS(saved, current) {
+		switch op.Kind {
+		case lcs.Equal:
+			inHunk = false
+		case lcs.Insert:
+			if !inHunk {
+				starts = append(starts, op.B)
+				inHunk = true
+			}
+		}
+	}
+	return starts
+}
+
+// Gutter marks for lines changed since the document was last saved.
+const (
+	gutterAdded    = '+'
+	gutterModified = '~'
+	gutterDeleted  = '-'
+)
+
+// diffGutter returns annotations marking the 1-indexed lines of current that
+// were added or modified relative to saved. Within each changed region,
+// deleted lines are paired with inserted lines as modifications. Where lines
+// were deleted without replacement, the line that follows them is marked
+// instead, or the last line if the deletion was at the end of the document.
+func diffGutter(saved, current []string) []Annotation {
+	var (
+		anns              []Annotation
+		deleted, inserted int // counts within the current changed region
+	)
+	for _, op := range lcs.LCS(saved, current) {
+		switch op.Kind {
+		case lcs.Delete:
+			deleted++
+		case lcs.Insert:
+			mark := rune(gutterAdded)
+			if inserted < deleted {
+				mark = gutterModified
+			}
+			anns = append(anns, Annotation{Line: op.B + 1, Gutter: mark})
+			inserted++
+		case lcs.Equal:
+			if deleted > inserted {
+				anns = append(anns, Annotation{Line: op.B + 1, Gutter: gutterDeleted})
+			}
+			deleted, inserted = 0, 0
+		}
+	}
+	if deleted > inserted && len(current) > 0 {
+		anns = append(anns, Annotation{Line: len(current), Gutter: gutterDeleted})
+	}
+	return anns
+}
+
+func lineStrings(lines []*Line) []string {
+	strs := make([]string, len(lines))
+	for i, line := range lines {
+		strs[i] = line.String()
+	}
+	return strs
+}
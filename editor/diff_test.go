@@ -0,0 +1,183 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_hunkStarts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		saved, current []string
+		want           []int
+	}{
+		{
+			name:    "no changes",
+			saved:   []string{"a", "b"},
+			current: []string{"a", "b"},
+			want:    nil,
+		},
+		{
+			name:    "new document",
+			saved:   nil,
+			current: []string{"a", "b"},
+			want:    []int{0},
+		},
+		{
+			name:    "added and modified lines",
+			saved:   []string{"a", "b", "c", "d", "e"},
+			current: []string{"a", "new", "b", "C", "D", "e"},
+			want:    []int{1, 3},
+		},
+		{
+			name:    "pure deletion is not a hunk",
+			saved:   []string{"a", "b", "c"},
+			current: []string{"a", "c"},
+			want:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := hunkStarts(tc.saved, tc.current); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_nextHunk_prevHunk(t *testing.T) {
+	t.Parallel()
+
+	newEditor := func() *Editor {
+		e := &Editor{buffer: &buffer{
+			lines: []*Line{
+				newLineFromString("one"),
+				newLineFromString("two"),
+				newLineFromString("three"),
+				newLineFromString("four"),
+				newLineFromString("five"),
+			},
+			cursor: newCursor(),
+		}}
+		e.snapshot()
+		// Modify line 2 and insert a line after line 4.
+		e.lines[1].appendRune('!')
+		e.lines = append(e.lines[:4], append([]*Line{newLineFromString("four and a half")}, e.lines[4:]...)...)
+		e.cursor.col = 3
+		return e
+	}
+
+	t.Run("next", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor()
+		for _, wantLine := range []int{2, 5, 2} {
+			e.nextHunk()
+			if e.cursor.line != wantLine || e.cursor.col != 1 {
+				t.Errorf("expected cursor at %d:1, got %d:%d", wantLine, e.cursor.line, e.cursor.col)
+			}
+		}
+		if e.statusMsg != "Wrapped to first change" {
+			t.Errorf("expected wrap status, got %q", e.statusMsg)
+		}
+	})
+
+	t.Run("previous", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor()
+		e.cursor.line = 4
+		for _, wantLine := range []int{2, 5, 2} {
+			e.prevHunk()
+			if e.cursor.line != wantLine || e.cursor.col != 1 {
+				t.Errorf("expected cursor at %d:1, got %d:%d", wantLine, e.cursor.line, e.cursor.col)
+			}
+		}
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		t.Parallel()
+
+		e := &Editor{buffer: &buffer{
+			lines:  []*Line{newLineFromString("one")},
+			cursor: newCursor(),
+		}}
+		e.snapshot()
+		e.nextHunk()
+		if e.statusMsg != "No changes" {
+			t.Errorf("expected status %q, got %q", "No changes", e.statusMsg)
+		}
+	})
+}
+
+func Test_diffGutter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		saved, current []string
+		want           []Annotation
+	}{
+		{
+			name:    "no changes",
+			saved:   []string{"a", "b"},
+			current: []string{"a", "b"},
+			want:    nil,
+		},
+		{
+			name:    "pure insertions",
+			saved:   []string{"a", "d"},
+			current: []string{"a", "b", "c", "d"},
+			want: []Annotation{
+				{Line: 2, Gutter: '+'},
+				{Line: 3, Gutter: '+'},
+			},
+		},
+		{
+			name:    "pure deletions",
+			saved:   []string{"a", "b", "c", "d"},
+			current: []string{"a", "d"},
+			want: []Annotation{
+				{Line: 2, Gutter: '-'},
+			},
+		},
+		{
+			name:    "deletions at the end of the document",
+			saved:   []string{"a", "b", "c"},
+			current: []string{"a"},
+			want: []Annotation{
+				{Line: 1, Gutter: '-'},
+			},
+		},
+		{
+			name:    "mixed modifications",
+			saved:   []string{"a", "b", "c", "d", "e"},
+			current: []string{"a", "B", "C", "x", "d"},
+			want: []Annotation{
+				{Line: 2, Gutter: '~'},
+				{Line: 3, Gutter: '~'},
+				{Line: 4, Gutter: '+'},
+				{Line: 5, Gutter: '-'},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := diffGutter(tc.saved, tc.current); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -4,25 +4,58 @@ package editor
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
-	"github.com/angusgmorrison/gila/intutil"
+	"github.com/angusgmorrison/gila/escseq"
 )
 
 const (
-	defaultFilename  = "[Untitled]"
-	defaultStatusMsg = "Help: Ctrl-S = save | Ctrl-Q = quit"
+	defaultFilename = "[Untitled]"
 	// Preallocate memory to hold pointers to at least nLinesToPreallocate lines of
 	// text.
 	nLinesToPreallocate = 1024
 	// The user must quit twice in a row to quit an unsaved file.
 	forceQuitThreshold = 2
+	// The user must reload twice in a row to discard unsaved changes.
+	forceReloadThreshold = 2
+	defaultIndentSize    = 4
+)
+
+// binarySniffLen is the number of bytes at the start of a file that are
+// checked for NUL bytes to detect binary files.
+const binarySniffLen = 8000
+
+// maxTempAttempts is the number of names tried when creating a temporary file
+// to save to.
+const maxTempAttempts = 100
+
+// ErrBinaryFile is returned when opening a file that appears to be binary.
+var ErrBinaryFile = errors.New("file appears to be binary")
+
+var (
+	errNoFile         = errors.New("buffer has no file")
+	errUnsavedChanges = errors.New("buffer has unsaved changes")
+)
+
+// utf8BOM is the UTF-8 encoding of the byte-order mark.
+const utf8BOM = "\xef\xbb\xbf"
+
+// Line endings that documents may be saved with.
+const (
+	LineEndingLF   = "\n"
+	LineEndingCRLF = "\r\n"
 )
 
 // KeyReader reads a single keystroke or chord from input and returns its raw
@@ -33,20 +66,63 @@ type KeyReader interface {
 
 // Frame contains all the data required to render a complete frame.
 type Frame struct {
-	Cursor         *Cursor
-	Lines          []*Line
-	Filename       string
+	Cursor   *Cursor
+	Lines    []*Line
+	Filename string
+	// FileType is the language or format of the document, as detected by
+	// FileType, or "" if it is unknown.
+	FileType       string
 	StatusMsg      string
 	LastStatusTime time.Time
-	Dirty          bool
+	// StatusMsgDuration is how long StatusMsg is displayed for after
+	// LastStatusTime. If zero, the renderer's default is used.
+	StatusMsgDuration time.Duration
+	Dirty             bool
+	// Recording is true while a macro is being recorded.
+	Recording bool
+	// ReadOnly is true while keypresses that modify the document are ignored.
+	ReadOnly bool
+	// SoftWrap is true when lines wider than the screen should be wrapped
+	// onto multiple rows instead of being clipped.
+	SoftWrap bool
+	// Gutter is true when GutterWidth columns to the left of the text are
+	// reserved for annotation marks.
+	Gutter      bool
+	Annotations []Annotation
+	// ShowWordCount is true when the status bar should display Words.
+	ShowWordCount bool
+	Words         int
+	// Help lists the key bindings to display in the help overlay. It is nil
+	// when the overlay is hidden.
+	Help []Binding
 }
 
 // Renderer renders a frame to some arbitrary output.
 type Renderer interface {
 	Render(frame Frame) error
+	// RenderProgress shows that done of total units of a long-running
+	// operation, described by label, are complete. It is replaced by the next
+	// frame rendered.
+	RenderProgress(done, total int, label string) error
 	Clear() error
 }
 
+// Resizer is implemented by Renderers whose screen can be resized while the
+// editor is running, such as when the terminal window changes size. The editor
+// redraws the screen at the new size as soon as ResizeNotifier receives.
+type Resizer interface {
+	ResizeNotifier() <-chan struct{}
+	// Size returns the size of the whole screen, as passed to Config.
+	Size() (width, height int)
+}
+
+// Invalidator is implemented by Renderers that redraw only what has changed
+// since the previous frame. Invalidate causes the next frame to be drawn in
+// full, such as to repair a screen corrupted by another program.
+type Invalidator interface {
+	Invalidate()
+}
+
 // Logger represents the minimal set of methods used to log the editor's
 // workings.
 type Logger interface {
@@ -54,6 +130,13 @@ type Logger interface {
 	Printf(fmt string, a ...any)
 }
 
+// NopLogger is a Logger that discards all output. It is the editor's default
+// logger.
+type NopLogger struct{}
+
+func (NopLogger) Println(...any)        {}
+func (NopLogger) Printf(string, ...any) {}
+
 // keynum is an enumerable that incorporates all Unicode symbols and
 // additionally defines representations for keys with special functions.
 type keynum rune
@@ -65,14 +148,46 @@ const (
 	keyDown
 	keyEnd
 	keyEsc
+	keyF1
+	keyF5
+	keyF12
 	keyHome
 	keyLeft
 	keyPageUp
 	keyPageDown
 	keyRight
+	keyShiftTab
 	keyUp
 )
 
+// isText reports whether k is a character that is inserted as text when typed:
+// any character that isn't a control character, including spaces like NBSP.
+func (k keynum) isText() bool {
+	return k < keyBackspace && !unicode.IsControl(rune(k))
+}
+
+// altMask is combined with a key to represent Alt-KEY. It lies beyond both the
+// Unicode range and the function key definitions.
+const altMask keynum = 1 << 21
+
+// modifier is a bitmask of the modifier keys held while pressing a special
+// key, such as the arrow keys.
+type modifier uint8
+
+const (
+	modShift modifier = 1 << iota
+	modAlt
+	modCtrl
+)
+
+// keyEvent is a transliterated keypress: the key pressed and the modifiers
+// held with it. Unmodified keys, printable characters and chords like Ctrl-S
+// and Alt-R have no modifiers; the chord is encoded in key.
+type keyEvent struct {
+	key  keynum
+	mods modifier
+}
+
 // Chords.
 const (
 	// ctrlMask can be combined with any other ASCII character code, CHAR, to
@@ -80,95 +195,505 @@ const (
 	// combinations by zeroing bits 5 and 6 of CHAR (indexed from 0).
 	ctrlMask       = 0x1f
 	chordBackspace = 'h' & ctrlMask
-	chordRefresh   = 'l' & ctrlMask
-	chordSave      = 's' & ctrlMask
-	chordQuit      = 'q' & ctrlMask
+	chordIndent    = 'i' & ctrlMask // Tab
 )
 
+// commentPrefixes maps file extensions to the prefix that begins a line
+// comment in the corresponding language.
+var commentPrefixes = map[string]string{
+	".c":    "//",
+	".cpp":  "//",
+	".go":   "//",
+	".h":    "//",
+	".java": "//",
+	".js":   "//",
+	".rs":   "//",
+	".ts":   "//",
+	".bash": "#",
+	".py":   "#",
+	".rb":   "#",
+	".sh":   "#",
+	".toml": "#",
+	".yaml": "#",
+	".yml":  "#",
+	".zsh":  "#",
+	".lua":  "--",
+	".sql":  "--",
+}
+
+// Binding describes a key or chord and the command it triggers, for display to
+// the user.
+type Binding struct {
+	Keys        string
+	Description string
+}
+
+// helpEntry describes a row of the help overlay: the keys bound to cmds,
+// followed by suffix, and what they do. If cmds are bound to their default
+// keys and label is set, label names the keys instead.
+type helpEntry struct {
+	cmds        []Command
+	label       string
+	suffix      string
+	description string
+}
+
+// helpEntries lists the rows of the help overlay in the order they are
+// displayed.
+var helpEntries = []helpEntry{
+	{cmds: []Command{CmdSave}, description: "Save"},
+	{cmds: []Command{CmdQuit}, description: "Quit"},
+	{cmds: []Command{CmdRefresh}, description: "Refresh the screen"},
+	{cmds: []Command{CmdIndent}, description: "Indent"},
+	{cmds: []Command{CmdDedent}, description: "Dedent the current line"},
+	{cmds: []Command{CmdComment}, description: "Toggle line comment"},
+	{cmds: []Command{CmdStripANSI}, description: "Strip terminal escape codes"},
+	{cmds: []Command{CmdPipe}, description: "Pipe the document through a command"},
+	{cmds: []Command{CmdFormat}, description: "Format the document"},
+	{cmds: []Command{CmdDeleteLines}, description: "Delete lines from the cursor down"},
+	{cmds: []Command{CmdMacroRecord}, suffix: " a-z", description: "Start/stop recording a macro"},
+	{cmds: []Command{CmdMacroPlay}, suffix: " a-z", description: "Play a macro"},
+	{cmds: []Command{CmdWordCount}, description: "Show/hide the word count"},
+	{cmds: []Command{CmdNextChange, CmdPrevChange}, description: "Jump to the next/previous unsaved change"},
+	{cmds: []Command{CmdReadOnly}, description: "Toggle read-only mode"},
+	{cmds: []Command{CmdBookmark}, description: "Toggle a bookmark on the current line"},
+	{cmds: []Command{CmdNextBookmark, CmdPrevBookmark}, description: "Jump to the next/previous bookmark"},
+	{cmds: []Command{CmdNextBuffer, CmdPrevBuffer}, description: "Switch to the next/previous file"},
+	{cmds: []Command{CmdSearchAll}, description: "Search all open files"},
+	{cmds: []Command{CmdUp, CmdDown, CmdLeft, CmdRight}, label: "Arrows", description: "Move the cursor"},
+	{cmds: []Command{CmdHome, CmdEnd}, description: "Jump to the start/end of the line"},
+	{cmds: []Command{CmdPageUp, CmdPageDown}, description: "Scroll by one page"},
+	{cmds: []Command{CmdCenter}, description: "Center the cursor line on screen"},
+	{cmds: []Command{CmdSoftWrap}, description: "Toggle soft wrap"},
+	{cmds: []Command{CmdScrollUp}, description: "Scroll up by one line"},
+	{cmds: []Command{CmdScrollDown}, description: "Scroll down by one line"},
+	{cmds: []Command{CmdReload}, description: "Reload the file from disk"},
+	{cmds: []Command{CmdReloadConfig}, description: "Reload the config file"},
+	{cmds: []Command{CmdDefinition}, description: "Go to the definition under the cursor"},
+	{cmds: []Command{CmdHelp}, description: "Show this help"},
+}
+
+// DefaultBindings returns the key bindings of DefaultKeyMap, for display to
+// the user.
+func DefaultBindings() []Binding {
+	return DefaultKeyMap().bindings()
+}
+
 // Config contains editor configuration data.
 type Config struct {
 	Width, Height int
+	// TabStop is the display width of tab characters in opened files. Defaults
+	// to 4.
+	TabStop int
+	// IndentSize is the number of columns that pressing Tab indents by.
+	// Defaults to 4.
+	IndentSize int
+	// HardTabs causes Tab to insert a tab character instead of indenting with
+	// spaces.
+	HardTabs bool
+	// LineEnding forces documents to be saved with the given line ending,
+	// either LineEndingLF or LineEndingCRLF. If empty, documents are saved
+	// with the dominant line ending of the file they were opened from, or LF
+	// for new documents.
+	LineEnding string
+	// PreserveBOM causes every document to be saved with a UTF-8 byte-order
+	// mark, even if the file it was opened from had none. A BOM stripped from
+	// the start of an opened file is always written back.
+	PreserveBOM bool
+	// StatusMsgDuration is how long status messages are displayed for. If
+	// zero, the renderer's default is used.
+	StatusMsgDuration time.Duration
+	// SoftWrap causes lines wider than the screen to wrap onto the following
+	// rows instead of scrolling horizontally.
+	SoftWrap bool
+	// ScrollPastEnd allows the screen to scroll down until the last line of
+	// the document is at the top, rather than stopping once the whole of the
+	// last screen is filled. The cursor still stops at the end of the
+	// document.
+	ScrollPastEnd bool
+	// Gutter reserves GutterWidth columns to the left of the text for
+	// annotation marks.
+	Gutter bool
+	// ReadOnly starts the editor in read-only mode, in which keypresses that
+	// would modify or save the document are ignored.
+	ReadOnly bool
+	// DiffGutter marks lines changed since the document was last saved in the
+	// gutter, enabling it if necessary.
+	DiffGutter bool
+	// EnsureFinalNewline causes documents to be saved ending in exactly one
+	// line ending: trailing blank lines are removed and a missing final line
+	// ending is added. It takes precedence over preserving the final line
+	// ending of the opened file.
+	EnsureFinalNewline bool
+	// TrimTrailingWhitespace causes whitespace at the end of each line to be
+	// removed when the document is saved.
+	TrimTrailingWhitespace bool
+	// Backup causes the original file to be copied to a file of the same name
+	// suffixed with "~" the first time the document is saved.
+	Backup bool
+	// Debug enables the editor's debug log. If false, nothing is written to
+	// the logger set by WithLogger or SetLogger.
+	Debug bool
+	// LazyLoadSize is the size in bytes above which files are opened
+	// read-only, reading only the lines near the cursor from disk as they are
+	// needed instead of the whole file. Defaults to 64 MiB.
+	LazyLoadSize int64
+	// KeyMap binds keys to commands. If nil, DefaultKeyMap is used. Entries
+	// with invalid key names are logged and ignored; use KeyMap.Validate to
+	// check them in advance.
+	KeyMap KeyMap
 }
 
 // Editor holds the state for a text editor. Its methods run the main loop for
 // reading and writing input to and from a terminal.
 type Editor struct {
-	config         Config
-	cursor         *Cursor
-	filepath       string
-	filename       string
+	// The active buffer, whose fields are promoted for convenience.
+	*buffer
+	// The open buffers, in the order they were opened.
+	buffers []*buffer
+	config  Config
+	// The configuration the editor was created with, or last reloaded by
+	// ReloadConfig, which .editorconfig settings are applied on top of.
+	baseConfig Config
+	// The function that reads the configuration applied by ReloadConfig, or
+	// nil if there is none.
+	loadConfig     func() (Config, error)
 	promptBuf      *Line
+	bindings       []Binding
+	keyMap         map[keyEvent]Command // compiled from config.KeyMap
+	showHelp       bool
+	showWordCount  bool
+	readOnly       bool
 	statusMsg      string
 	lastStatusTime time.Time
+	// clock returns the current time. If nil, time.Now is used.
+	clock func() time.Time
 	// The number of consecutive quit commands, used for force-quitting unsaved documents.
 	quitCount int
-	// The text in the buffer.
-	lines    []*Line
-	dirty    bool
-	r        KeyReader
-	renderer Renderer
-	readErr  error
-	writeErr error
-	logger   Logger // TODO: make logging debug-only
-}
-
-// New returns a new *Editor that reads from kr and writes to tw.
-func New(kr KeyReader, r Renderer, config Config, logger Logger) *Editor {
-	config.Height -= 2 // reserve the last two lines of the screen for the status bar and status message
-	return &Editor{
-		config:         config,
-		filename:       defaultFilename,
-		r:              kr,
-		renderer:       r,
-		promptBuf:      newLine(),
-		statusMsg:      defaultStatusMsg,
-		lastStatusTime: time.Now(),
-		cursor:         newCursor(),
-		logger:         logger,
-	}
-}
-
-// Run starts the editor loop. The editor will update the screen and process
-// user input until commanded to quit or an error occurs.
-func (e *Editor) Run(filepath string) (err error) {
-	defer e.renderer.Clear() // TODO: Use a multierror to capture all possible errors.
-
-	if filepath != "" {
-		if err = e.open(filepath); err != nil {
+	// The number of consecutive reload commands, used for discarding unsaved
+	// changes.
+	reloadCount int
+	// Decorations passed to the renderer with each frame.
+	annotations []Annotation
+	macros      *MacroRegistry
+	// The macro being recorded, or nil if no recording is in progress.
+	recording     *Macro
+	recordingName rune
+	// Keystrokes of a macro waiting to be replayed.
+	replayQueue [][]byte
+	replaying   bool
+	r           KeyReader
+	// Keys read from r in the background while waiting for the screen to be
+	// resized. It is nil until the first key is read, or if the renderer isn't
+	// a Resizer.
+	keyReads chan keyRead
+	// The language server used to find definitions, or nil if there is none.
+	lspClient LSPClient
+	renderer  Renderer
+	readErr   error
+	writeErr  error
+	logger    Logger
+	// Counters and timings reported by Metrics. The document statistics are
+	// computed when the snapshot is taken.
+	metrics EditorMetrics
+}
+
+// New returns a new *Editor that reads from kr and draws with r, configured by
+// opts. Without options, the editor has a zero-sized screen, default tab and
+// indent sizes, and discards its logs.
+//
+// r may be nil, in which case the editor processes keypresses without drawing
+// anything. This is intended for tests that exercise editing logic without a
+// display: prompts still block on the KeyReader, but the user can't see them.
+// kr may also be nil, or set to nil by WithKeyReader, in which case the editor
+// reads no keys, and Run returns as soon as any files are open.
+func New(kr KeyReader, r Renderer, opts ...EditorOption) *Editor {
+	b := newBuffer()
+	e := &Editor{
+		buffer:    b,
+		buffers:   []*buffer{b},
+		r:         kr,
+		renderer:  r,
+		promptBuf: newLine(),
+		macros:    newMacroRegistry(),
+		clock:     time.Now,
+		logger:    NopLogger{},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.r == nil {
+		e.r = Keys()
+	}
+	e.lastStatusTime = e.now()
+
+	e.config.Height -= 2 // reserve the last two lines of the screen for the status bar and status message
+	e.config = e.config.withDefaults()
+	e.readOnly = e.config.ReadOnly
+	e.setKeyMap(e.config.KeyMap)
+	e.statusMsg = e.config.KeyMap.helpHint()
+	e.baseConfig = e.config
+	return e
+}
+
+// withDefaults returns c with its unset tab stop, indent size, key map and lazy
+// load size replaced by the defaults.
+func (c Config) withDefaults() Config {
+	if c.TabStop <= 0 {
+		c.TabStop = defaultTabStop
+	}
+	if c.IndentSize <= 0 {
+		c.IndentSize = defaultIndentSize
+	}
+	if c.KeyMap == nil {
+		c.KeyMap = DefaultKeyMap()
+	}
+	if c.LazyLoadSize <= 0 {
+		c.LazyLoadSize = defaultLazyLoadSize
+	}
+	return c
+}
+
+// setKeyMap compiles km into the key events the editor responds to. Invalid
+// entries are logged and ignored.
+func (e *Editor) setKeyMap(km KeyMap) {
+	var err error
+	if e.keyMap, err = km.compile(); err != nil {
+		e.debugf("ignoring invalid key bindings: %v\n", err)
+	}
+	e.bindings = km.bindings()
+}
+
+// Run opens the files at paths, if any, and starts the editor loop with the
+// first buffer active. The editor will update the screen and process user input
+// until commanded to quit or an error occurs. The screen is cleared on return,
+// and Run returns every error that stopped the loop or prevented clearing.
+func (e *Editor) Run(paths ...string) (err error) {
+	if e.renderer != nil {
+		defer func() { err = errors.Join(err, e.renderer.Clear()) }()
+	}
+
+	for _, path := range paths {
+		if err = e.OpenBuffer(path); err != nil {
 			return err
 		}
 	}
 
 	for e.render() && e.processKeypress() {
 	}
-	if e.readErr != nil {
-		return e.readErr
-	}
-	if e.writeErr != nil {
-		return e.writeErr
-	}
-	return nil
+	return errors.Join(e.readErr, e.writeErr)
 }
 
-// open opens the file at path and reads its lines into memory.
-func (e *Editor) open(path string) (err error) {
+// open opens the file at path and reads its lines into memory, or indexes them
+// to be read on demand if the file is larger than Config.LazyLoadSize. If
+// progress is non-nil, it is called as the file is read with the number of
+// bytes read so far and the size of the file.
+func (e *Editor) open(path string, progress func(bytesRead, totalBytes int64)) (err error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	defer func() { err = f.Close() }()
+	// A lazily loaded buffer reads from the file for as long as it is open.
+	lazy := false
+	defer func() {
+		if lazy && err == nil {
+			return
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
 
+	info, statErr := f.Stat()
+	var r io.Reader = f
+	if progress != nil && statErr == nil {
+		r = &progressReader{r: f, total: info.Size(), progress: progress}
+	}
+	// The buffer is only changed once the file has been read, so that a file
+	// that can't be opened, such as a binary file, leaves it as it was. The
+	// file's tab width is needed to read it, since it's stored with each line.
+	ec := e.loadEditorConfig(path)
+	tabStop := e.configWith(ec).TabStop
+	lazy = statErr == nil && info.Size() > e.config.LazyLoadSize
+	if lazy {
+		if err := e.openLazy(f, path, info.Size(), tabStop); err != nil {
+			return err
+		}
+	} else {
+		doc, err := readDocument(r, tabStop)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+		e.setDocument(doc)
+	}
 	e.filepath = path
 	e.filename = filepath.Base(path)
-	e.lines = make([]*Line, 0, nLinesToPreallocate)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		e.lines = append(e.lines, newLineFromString(scanner.Text()))
+	e.editorConfig = ec
+	e.applyEditorConfig()
+	if lazy {
+		e.savedLines = nil
+		e.setStatus("%s is too large to edit and was opened read-only", e.filename)
+	} else {
+		e.snapshot()
 	}
-	if err = scanner.Err(); err != nil {
-		return fmt.Errorf("scan line from %s: %w", path, err)
+	if statErr == nil {
+		e.diskStamp = stampOf(info)
 	}
-	return nil // EOF
+	return nil
+}
+
+// fileStamp identifies a version of a file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(info fs.FileInfo) *fileStamp {
+	return &fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// changedOnDisk reports whether the file has been modified by another program
+// since it was last opened or saved. A file that can no longer be found is not
+// considered changed, since saving recreates it without loss.
+func (e *Editor) changedOnDisk() bool {
+	if e.diskStamp == nil {
+		return false
+	}
+	info, err := os.Stat(e.filepath)
+	if err != nil {
+		return false
+	}
+	return *stampOf(info) != *e.diskStamp
+}
+
+// Reload discards the buffer and re-reads the file from disk, returning the
+// cursor to the top of the document. If the buffer has unsaved changes, Reload
+// warns the user and returns errUnsavedChanges unless force is true. If the
+// file can't be read, the buffer is left unchanged.
+func (e *Editor) Reload(force bool) error {
+	if e.filepath == "" {
+		return errNoFile
+	}
+	if e.dirty && !force {
+		e.setStatus("WARNING: Unsaved changes. F5 to discard them and reload.")
+		return errUnsavedChanges
+	}
+
+	progress, stop := e.loadProgress("Reloading " + e.filename)
+	defer stop()
+	// open only changes the buffer once the file has been read.
+	if err := e.open(e.filepath, progress); err != nil {
+		return err
+	}
+	e.dirty = false
+	e.cursor = newCursor()
+	e.setStatus("Reloaded %s", e.filename)
+	return nil
+}
+
+// Load reads a document from r, such as a pipe, into an unnamed buffer. The
+// document has no file to compare against, so it is marked as modified and
+// the user is prompted for a filename when they save.
+func (e *Editor) Load(r io.Reader) error {
+	if err := e.read(r); err != nil {
+		return err
+	}
+	e.filepath = ""
+	e.filename = defaultFilename
+	e.diskStamp = nil
+	e.savedLines = nil
+	e.dirty = true
+	return nil
+}
+
+// document is the text of a file and the format it was saved in.
+type document struct {
+	lines []*Line
+	// The line ending used by most lines.
+	lineEnding   string
+	finalNewline bool
+	bom          bool
+}
+
+// read replaces the buffer with the lines read from r, recording the
+// document's line ending, byte-order mark and final newline so that it can be
+// saved in the same format. If r can't be read, or holds binary data, the
+// buffer is unchanged.
+func (e *Editor) read(r io.Reader) error {
+	doc, err := readDocument(r, e.config.TabStop)
+	if err != nil {
+		return err
+	}
+	e.setDocument(doc)
+	return nil
+}
+
+// setDocument replaces the buffer's text and format with doc's, releasing the
+// lines it replaces and the file of a lazily loaded buffer.
+func (e *Editor) setDocument(doc *document) {
+	for _, line := range e.lines {
+		if line != nil { // not loaded by a lazily loaded buffer
+			line.release()
+		}
+	}
+	e.closeLazy()
+	e.lines = doc.lines
+	e.lineEnding = doc.lineEnding
+	e.finalNewline = doc.finalNewline
+	e.bom = doc.bom
+}
+
+// readDocument reads the lines of a document from r, whose tab stops are
+// tabStop columns apart. Documents containing NUL bytes are binary, and
+// readDocument returns ErrBinaryFile without reading them.
+func readDocument(r io.Reader, tabStop int) (*document, error) {
+	doc := &document{
+		lines:        make([]*Line, 0, nLinesToPreallocate),
+		finalNewline: true,
+	}
+	var nLF, nCRLF int
+	// The buffer must hold the bytes sniffed for NULs.
+	reader := bufio.NewReaderSize(r, binarySniffLen)
+	if prefix, _ := reader.Peek(len(utf8BOM)); string(prefix) == utf8BOM {
+		doc.bom = true
+		if _, err := reader.Discard(len(utf8BOM)); err != nil {
+			return nil, fmt.Errorf("discard BOM: %w", err)
+		}
+	}
+	// Text files don't contain NUL bytes, and rendering binary content can
+	// leave the terminal in an unusable state.
+	if prefix, _ := reader.Peek(binarySniffLen); bytes.IndexByte(prefix, 0) >= 0 {
+		return nil, ErrBinaryFile
+	}
+	for {
+		text, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("read line: %w", err)
+		}
+		if text == "" { // EOF
+			break
+		}
+		switch {
+		case strings.HasSuffix(text, LineEndingCRLF):
+			nCRLF++
+			text = strings.TrimSuffix(text, LineEndingCRLF)
+		case strings.HasSuffix(text, LineEndingLF):
+			nLF++
+			text = strings.TrimSuffix(text, LineEndingLF)
+		default: // final line without a terminator
+			doc.finalNewline = false
+			text = strings.TrimSuffix(text, "\r")
+		}
+		doc.lines = append(doc.lines, newPendingLine(text, tabStop))
+		if err == io.EOF {
+			break
+		}
+	}
+	doc.lineEnding = LineEndingLF
+	if nCRLF > nLF {
+		doc.lineEnding = LineEndingCRLF
+	}
+	return doc, nil
 }
 
 // processKeypress is designed to be called in a tight loop. By returning a
@@ -176,64 +701,265 @@ func (e *Editor) open(path string) (err error) {
 // during the refresh, it is saved to (*editor).readErr, and processKeypress
 // returns false.
 func (e *Editor) processKeypress() bool {
-	rawKey, err := e.r.ReadKey()
+	rawKey, err := e.readKey()
 	if err != nil {
 		e.readErr = err
 		return false
 	}
-	e.logger.Printf("read raw key %q\n", string(rawKey))
 
-	key := transliterateKeypress(rawKey)
-	if key == 0 { // EOF, return without error
+	ev := transliterateKeypress(rawKey)
+	if ev.key == 0 { // EOF, return without error
 		return false
 	}
-	e.logger.Printf("transliterated %q to %q\n", string(rawKey), key)
+	defer e.recordKeypress(e.now())
 
-	switch key {
-	case chordSave:
+	// Any keypress dismisses the help overlay without further effect.
+	if e.showHelp {
+		e.showHelp = false
+		return true
+	}
+
+	cmd, bound := e.keyMap[ev]
+	if !bound && ev.mods != 0 {
+		// Unbound modified keys act as the unmodified key.
+		cmd, bound = e.keyMap[keyEvent{key: ev.key}]
+	}
+	insert := !bound && ev.mods == 0 && ev.key.isText()
+
+	if e.lazy != nil && (mutates(cmd) || insert) {
+		e.setStatus("Read-only: %s is too large to edit", e.filename)
+		e.quitCount = 0
+		e.reloadCount = 0
+		return true
+	}
+	// The search results can't be edited, and Enter jumps to the hit on the
+	// cursor's line.
+	if e.hits != nil && (mutates(cmd) || insert) {
+		if cmd == CmdNewLine {
+			e.jumpToHit()
+		} else {
+			e.setStatus("Search results are read-only. Enter to jump to a match.")
+		}
+		e.quitCount = 0
+		e.reloadCount = 0
+		return true
+	}
+	if e.readOnly && (mutates(cmd) || insert) {
+		if cmd == CmdSave {
+			e.setStatus("Cannot save: read-only mode")
+		} else if key := e.config.KeyMap.keyFor(CmdReadOnly); key != "" {
+			e.setStatus("Read-only mode. %s to allow editing.", key)
+		} else {
+			e.setStatus("Read-only mode.")
+		}
+		e.quitCount = 0
+		e.reloadCount = 0
+		return true
+	}
+
+	if insert {
+		e.insertRune(rune(ev.key))
+	}
+	switch cmd {
+	case CmdSave:
 		if !e.save() {
 			return false
 		}
-	case chordQuit:
+	case CmdQuit:
 		e.quitCount++
 		if e.canForceQuit() {
 			return false
 		}
-		e.setStatus("WARNING: Unsaved changes. Ctrl-Q to force quit.")
+		e.setStatus("WARNING: Unsaved changes. %s to force quit.", e.config.KeyMap.keyFor(CmdQuit))
 		return true
-	case keyHome, keyEnd, keyLeft, keyDown, keyUp, keyRight, keyPageUp, keyPageDown:
-		e.moveCursor(key)
-	case keyBackspace:
+	case CmdHome, CmdEnd, CmdLeft, CmdDown, CmdUp, CmdRight, CmdPageUp, CmdPageDown:
+		e.moveCursor(cursorKeys[cmd])
+	case CmdCenter:
+		e.cursor.centerVertically(e.config.Height, len(e.lines), e.config.ScrollPastEnd)
+	case CmdScrollUp, CmdScrollDown:
+		e.scrollView(cmd)
+	case CmdMacroRecord:
+		if !e.toggleMacroRecording() {
+			return false
+		}
+	case CmdMacroPlay:
+		if !e.playMacro() {
+			return false
+		}
+	case CmdComment:
+		e.toggleComment()
+	case CmdStripANSI:
+		e.stripANSI()
+	case CmdFormat:
+		e.format()
+	case CmdDefinition:
+		e.goToDefinition()
+	case CmdPipe:
+		if !e.pipe() {
+			return false
+		}
+	case CmdWordCount:
+		e.toggleWordCount()
+	case CmdReadOnly:
+		e.toggleReadOnly()
+	case CmdSoftWrap:
+		e.toggleSoftWrap()
+	case CmdNextChange:
+		e.nextHunk()
+	case CmdPrevChange:
+		e.prevHunk()
+	case CmdBookmark:
+		if !e.toggleBookmark() {
+			return false
+		}
+	case CmdNextBookmark:
+		e.nextBookmark()
+	case CmdPrevBookmark:
+		e.prevBookmark()
+	case CmdNextBuffer:
+		e.switchBuffer(1)
+	case CmdPrevBuffer:
+		e.switchBuffer(-1)
+	case CmdSearchAll:
+		if !e.searchAll() {
+			return false
+		}
+	case CmdIndent:
+		// The line past the end of the document has no text to indent, so
+		// Tab inserts the indentation there as it does mid-line.
+		if e.cursor.col == 1 && !e.config.HardTabs && e.currentLine() != nil {
+			e.indent(1)
+		} else {
+			e.insertIndent()
+		}
+	case CmdDedent:
+		e.indent(-1)
+	case CmdBackspace:
 		e.backspace()
-	case keyDel:
+	case CmdDelete:
 		e.delete()
-	case keyLineFeed:
+	case CmdDeleteLines:
+		if !e.promptDeleteLines() {
+			return false
+		}
+	case CmdNewLine:
 		e.newLine()
-	case keyEsc, chordRefresh:
-		// No-op.
-	default:
-		e.insertRune(rune(key))
+	case CmdHelp:
+		e.showHelp = true
+	case CmdReload:
+		e.reloadCount++
+		if err := e.Reload(e.reloadCount >= forceReloadThreshold); err != nil {
+			if errors.Is(err, errUnsavedChanges) {
+				return true // preserve the reload count
+			}
+			e.setStatus("Reload failed: %s", err)
+		}
+	case CmdReloadConfig:
+		e.reloadConfig()
+	case CmdRefresh:
+		if inv, ok := e.renderer.(Invalidator); ok {
+			inv.Invalidate()
+		}
 	}
 
-	// The consecutive quit count is reset each time a non-quit kepress occurs.
+	// The consecutive quit and reload counts are reset each time any other
+	// keypress occurs.
 	e.quitCount = 0
+	e.reloadCount = 0
 	return true
 }
 
+// mutates reports whether cmd modifies or saves the document, and so must be
+// ignored in read-only mode.
+func mutates(cmd Command) bool {
+	switch cmd {
+	case CmdSave, CmdComment, CmdStripANSI, CmdPipe, CmdFormat, CmdIndent, CmdDedent,
+		CmdBackspace, CmdDelete, CmdDeleteLines, CmdNewLine:
+		return true
+	}
+	return false
+}
+
+// cursorKeys maps cursor movement commands to the keys moveCursor handles.
+var cursorKeys = map[Command]keynum{
+	CmdUp:       keyUp,
+	CmdDown:     keyDown,
+	CmdLeft:     keyLeft,
+	CmdRight:    keyRight,
+	CmdHome:     keyHome,
+	CmdEnd:      keyEnd,
+	CmdPageUp:   keyPageUp,
+	CmdPageDown: keyPageDown,
+}
+
+func (e *Editor) toggleReadOnly() {
+	e.readOnly = !e.readOnly
+	if e.readOnly {
+		e.setStatus("Read-only mode on")
+	} else {
+		e.setStatus("Read-only mode off")
+	}
+}
+
+// toggleSoftWrap turns soft wrapping on or off. The cursor stays on the same
+// character, and the viewport is recomputed for the new layout when the next
+// frame is rendered.
+func (e *Editor) toggleSoftWrap() {
+	e.config.SoftWrap = !e.config.SoftWrap
+	if e.config.SoftWrap {
+		e.setStatus("Soft wrap on")
+	} else {
+		e.setStatus("Soft wrap off")
+	}
+}
+
+// readKey returns the next keystroke, either from the macro being replayed or
+// from the editor's KeyReader. If a replayed macro ends partway through a
+// command, such as a prompt, the remaining keystrokes are read from the
+// KeyReader. Keystrokes read from the KeyReader are recorded if a macro
+// recording is in progress.
+func (e *Editor) readKey() ([]byte, error) {
+	if len(e.replayQueue) > 0 {
+		key := e.replayQueue[0]
+		e.replayQueue = e.replayQueue[1:]
+		return key, nil
+	}
+
+	key, err := e.waitForKey()
+	if err != nil {
+		return nil, err
+	}
+	if e.recording != nil {
+		e.recording.record(key)
+	}
+	return key, nil
+}
+
 func (e *Editor) canForceQuit() bool {
-	return !e.dirty || e.quitCount >= forceQuitThreshold
+	return !e.anyDirty() || e.quitCount >= forceQuitThreshold
 }
 
 // render is designed to be called in a tight loop. By returning a
 // boolean, it is easily incorporated into a loop condition. If an error occurs
 // during the render, it is saved to (*editor).writeErr, and render
-// returns false.
+// returns false. If the editor has no renderer, render only keeps the cursor's
+// viewport up to date.
 func (e *Editor) render() bool {
-	e.cursor.scroll(e.config.Width, e.config.Height)
+	e.loadAroundCursor()
+	if e.config.SoftWrap {
+		e.cursor.scrollWrapped(e.lines, e.textWidth(), e.config.Height)
+	} else {
+		e.cursor.scroll(e.currentLine(), e.textWidth(), e.config.Height)
+	}
+	if e.renderer == nil {
+		return true
+	}
+	start := e.now()
 	if err := e.renderer.Render(e.frame()); err != nil {
 		e.writeErr = err
 		return false
 	}
+	e.recordRender(start)
 	return true
 }
 
@@ -244,13 +970,13 @@ func (e *Editor) prompt(msg string) bool {
 			return false
 		}
 
-		rawKey, err := e.r.ReadKey()
+		rawKey, err := e.readKey()
 		if err != nil {
 			e.readErr = err
 			return false
 		}
 
-		key := transliterateKeypress(rawKey)
+		key := transliterateKeypress(rawKey).key
 		if key == keyLineFeed {
 			e.setStatus("")
 			return true
@@ -260,7 +986,7 @@ func (e *Editor) prompt(msg string) bool {
 			return true
 		} else if key == keyBackspace || key == keyDel {
 			e.promptBuf.deleteLastRune()
-		} else if !unicode.IsControl(rune(key)) {
+		} else if key.isText() {
 			e.promptBuf.appendRune(rune(key))
 		}
 	}
@@ -268,54 +994,105 @@ func (e *Editor) prompt(msg string) bool {
 
 // frame returns the current frame.
 func (e *Editor) frame() Frame {
-	return Frame{
-		Cursor:         e.cursor,
-		Lines:          e.lines,
-		Filename:       e.filename,
-		StatusMsg:      e.statusMsg,
-		LastStatusTime: e.lastStatusTime,
-		Dirty:          e.dirty,
+	frame := Frame{
+		Cursor:            e.cursor,
+		Lines:             e.lines,
+		Filename:          e.filename,
+		FileType:          FileType(e.filepath),
+		StatusMsg:         e.statusMsg,
+		LastStatusTime:    e.lastStatusTime,
+		StatusMsgDuration: e.config.StatusMsgDuration,
+		SoftWrap:          e.config.SoftWrap,
+		Gutter:            e.gutter(),
+		ReadOnly:          e.readOnly,
+		Annotations:       e.frameAnnotations(),
+		Dirty:             e.dirty,
+		Recording:         e.recording != nil,
+	}
+	if e.showHelp {
+		frame.Help = e.bindings
 	}
+	if e.showWordCount {
+		frame.ShowWordCount = true
+		frame.Words, _, _ = e.WordCount()
+	}
+	return frame
 }
 
 func (e *Editor) moveCursor(key keynum) {
 	curLineLen := e.currentLine().RuneLen()
 	switch key {
 	case keyPageUp:
-		e.cursor.pageUp(e.config.Height)
+		if e.config.SoftWrap {
+			e.cursor.pageUpWrapped(e.lines, e.textWidth(), e.config.Height)
+		} else {
+			e.cursor.pageUp(e.config.Height)
+		}
 	case keyPageDown:
-		e.cursor.pageDown(e.config.Height, e.len())
+		if e.config.SoftWrap {
+			e.cursor.pageDownWrapped(e.lines, e.textWidth(), e.config.Height)
+		} else {
+			e.cursor.pageDown(e.config.Height, e.len(), e.config.ScrollPastEnd)
+		}
 	case keyHome:
 		e.cursor.home()
 	case keyEnd:
 		e.cursor.end(curLineLen)
 	case keyLeft:
-		e.cursor.left(e.prevLine().RuneLen())
+		if e.cursor.col > 1 { // step over the whole grapheme cluster
+			e.cursor.col = e.currentLine().prevClusterStart(e.cursor.col-1) + 1
+		} else {
+			e.cursor.left(e.prevLine().RuneLen())
+		}
 	case keyDown:
 		e.cursor.down(e.len())
 	case keyUp:
 		e.cursor.up()
 	case keyRight:
-		e.cursor.right(curLineLen, e.len())
+		if e.cursor.col <= curLineLen { // step over the whole grapheme cluster
+			e.cursor.col = e.currentLine().nextClusterStart(e.cursor.col-1) + 1
+		} else {
+			e.cursor.right(curLineLen, e.len())
+		}
 	default:
 		panic(fmt.Errorf("unrecognized cursor key %q", key))
 	}
 
-	e.cursor.snap(e.currentLine().RuneLen())
+	line := e.currentLine()
+	e.cursor.snap(line.RuneLen())
+	// Vertical movement may land inside a grapheme cluster.
+	e.cursor.col = line.clusterStart(e.cursor.col-1) + 1
+}
+
+// scrollView scrolls the screen by one line in the direction given by cmd,
+// moving the cursor only if it would otherwise leave the screen.
+func (e *Editor) scrollView(cmd Command) {
+	switch {
+	case cmd == CmdScrollDown:
+		e.cursor.scrollViewDown(e.config.Height, e.len(), e.config.ScrollPastEnd)
+	case e.config.SoftWrap:
+		e.cursor.scrollViewUpWrapped(e.lines, e.textWidth(), e.config.Height)
+	default:
+		e.cursor.scrollViewUp(e.config.Height)
+	}
+
+	line := e.currentLine()
+	e.cursor.snap(line.RuneLen())
+	e.cursor.col = line.clusterStart(e.cursor.col-1) + 1
 }
 
 func (e *Editor) currentLine() *Line {
 	if e.cursor.line > e.len() {
 		return nil
 	}
-	return e.lines[e.cursor.line-1]
+	return e.lineAt(e.cursor.line - 1)
 }
 
 func (e *Editor) prevLine() *Line {
 	if e.cursor.line <= 1 {
 		return nil
 	}
-	return e.lines[e.cursor.line-2]
+	return e.lineAt(e.cursor.line - 2)
 }
 
 func (e *Editor) len() int {
@@ -326,6 +1103,7 @@ func (e *Editor) insertRune(r rune) {
 	line := e.currentLine()
 	if line == nil {
 		line = newLine()
+		line.tabStop = e.config.TabStop
 		e.lines = append(e.lines, line)
 
 	}
@@ -334,6 +1112,48 @@ func (e *Editor) insertRune(r rune) {
 	e.dirty = true
 }
 
+// insertIndent inserts spaces at the cursor up to the next multiple of the
+// configured indent size, or a tab character if hard tabs are configured.
+func (e *Editor) insertIndent() {
+	if e.config.HardTabs {
+		e.insertRune('\t')
+		return
+	}
+	n := e.config.IndentSize - (e.cursor.col-1)%e.config.IndentSize
+	for i := 0; i < n; i++ {
+		e.insertRune(' ')
+	}
+}
+
+// indent indents the current line by delta levels of the configured indent
+// size, or dedents it if delta is negative. Dedenting removes only leading
+// spaces. The cursor stays on the same character.
+func (e *Editor) indent(delta int) {
+	line := e.currentLine()
+	if line == nil {
+		return
+	}
+
+	var shift int
+	if delta > 0 {
+		indented := line.IndentBy(delta, e.config.IndentSize)
+		shift = indented.RuneLen() - line.RuneLen()
+		e.lines[e.cursor.line-1] = indented
+	} else {
+		maxSpaces := -delta * e.config.IndentSize
+		for shift > -maxSpaces && line.hasRunesAt([]rune{' '}, -shift) {
+			shift--
+		}
+		line.deleteRunesAt(0, -shift)
+	}
+	if shift == 0 {
+		return
+	}
+
+	e.cursor.col = max(1, e.cursor.col+shift)
+	e.dirty = true
+}
+
 func (e *Editor) backspace() {
 	line := e.currentLine()
 	if line == nil {
@@ -347,8 +1167,9 @@ func (e *Editor) backspace() {
 		return
 	}
 
-	line.deleteRuneAt(e.cursor.col - 2)
-	e.cursor.col--
+	start := line.prevClusterStart(e.cursor.col - 1)
+	line.deleteRunesAt(start, e.cursor.col-1-start)
+	e.cursor.col = start + 1
 	e.dirty = true
 }
 
@@ -360,9 +1181,10 @@ func (e *Editor) delete() {
 		return
 	}
 
-	e.cursor.col++
-	e.backspace()
-	e.cursor.col--
+	line := e.currentLine()
+	i := e.cursor.col - 1
+	line.deleteRunesAt(i, line.nextClusterStart(i)-i)
+	e.dirty = true
 }
 
 func (e *Editor) mergeNextLineWithCurrent() {
@@ -387,7 +1209,63 @@ func (e *Editor) mergeCurrentLineWithPrevious() {
 }
 
 func (e *Editor) deleteCurrentLine() {
-	e.lines = append(e.lines[:e.cursor.line-1], e.lines[e.cursor.line:]...)
+	e.deleteLines(e.cursor.line-1, 1)
+}
+
+// promptDeleteLines deletes the number of lines entered at the prompt, starting
+// with the current line. It returns false if the prompt fails.
+func (e *Editor) promptDeleteLines() bool {
+	if !e.prompt("Delete lines: %s") { // IO error
+		return false
+	}
+	input := e.promptBuf.String()
+	e.promptBuf.clear()
+	if input == "" {
+		return true
+	}
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 {
+		e.setStatus("Invalid line count: %s", input)
+		return true
+	}
+	if e.cursor.line > len(e.lines) {
+		return true
+	}
+
+	n = min(n, len(e.lines)-e.cursor.line+1)
+	e.deleteLines(e.cursor.line-1, n)
+	e.cursor.col = 1
+	e.dirty = true
+	e.setStatus("Deleted %d lines", n)
+	return true
+}
+
+// deleteLines deletes up to n lines starting from the 0-indexed line i,
+// shifting the lines that follow only once, however many are deleted. The
+// deleted lines are released to the rune pool, and the vacated slots are
+// cleared so that the lines themselves can be garbage collected. Bookmarks
+// move with the lines that follow.
+func (e *Editor) deleteLines(i, n int) {
+	if i < 0 || i >= len(e.lines) || n <= 0 {
+		return
+	}
+	end := min(i+n, len(e.lines))
+	for _, line := range e.lines[i:end] {
+		line.release()
+	}
+	remaining := copy(e.lines[i:], e.lines[end:])
+	clear(e.lines[i+remaining:])
+	e.lines = e.lines[:i+remaining]
+	e.shiftBookmarks(i, i-end)
+}
+
+// insertLine inserts line before the 0-indexed line i without allocating a
+// temporary slice. Bookmarks move with the lines that follow.
+func (e *Editor) insertLine(i int, line *Line) {
+	e.lines = append(e.lines, nil)
+	copy(e.lines[i+1:], e.lines[i:])
+	e.lines[i] = line
+	e.shiftBookmarks(i, 1)
 }
 
 func (e *Editor) newLine() {
@@ -395,26 +1273,138 @@ func (e *Editor) newLine() {
 		return
 	}
 	currentLine := e.currentLine()
-	runesToCopy := currentLine.Runes()[e.cursor.col-1:]
-	newLineCap := intutil.Max(len(runesToCopy), lineRunesToPreallocate)
-	newLineRunes := make([]rune, len(runesToCopy), newLineCap)
-	copy(newLineRunes, runesToCopy)
-	currentLine.runes = currentLine.runes[:e.cursor.col-1]
-	newLine := newLineFromRunes(newLineRunes)
-	e.lines = append(e.lines[:e.cursor.line], append([]*Line{newLine}, e.lines[e.cursor.line:]...)...)
+	left, right := currentLine.SplitAt(e.cursor.col - 1)
+	currentLine.release()
+	e.lines[e.cursor.line-1] = left
+	e.insertLine(e.cursor.line, right)
 	e.cursor.line++
 	e.cursor.col = 1
 }
 
+// toggleComment comments out the current line using the line comment syntax of
+// the file's language, or uncomments it if it is already commented. The
+// comment prefix is inserted before the first non-space character of the line.
+func (e *Editor) toggleComment() {
+	line := e.currentLine()
+	if line == nil {
+		return
+	}
+	prefix, ok := commentPrefixes[filepath.Ext(e.filename)]
+	if !ok {
+		e.setStatus("No comment syntax known for %s", e.filename)
+		return
+	}
+
+	prefixRunes := []rune(prefix)
+	start := line.firstNonSpace()
+	var delta int
+	if line.hasRunesAt(prefixRunes, start) {
+		delta = -len(prefixRunes)
+		// Remove the space separating the prefix from the comment, if any.
+		if line.hasRunesAt([]rune{' '}, start+len(prefixRunes)) {
+			delta--
+		}
+		line.deleteRunesAt(start, -delta)
+	} else {
+		prefixRunes = append(prefixRunes, ' ')
+		delta = len(prefixRunes)
+		line.insertRunesAt(prefixRunes, start)
+	}
+
+	// Keep the cursor on the same character if it follows the comment prefix.
+	if e.cursor.col-1 >= start {
+		e.cursor.col = max(start+1, e.cursor.col+delta)
+	}
+	e.dirty = true
+}
+
+// stripANSI removes ANSI escape sequences, such as the color codes in pasted
+// terminal output, from every line of the document.
+func (e *Editor) stripANSI() {
+	var nStripped int
+	for _, line := range e.lines {
+		before := line.RuneLen()
+		line.runes = escseq.Strip(line.Runes())
+		if line.RuneLen() != before {
+			nStripped++
+		}
+	}
+	if nStripped == 0 {
+		e.setStatus("No escape codes found")
+		return
+	}
+	e.cursor.snap(e.currentLine().RuneLen())
+	e.setStatus("Stripped escape codes from %d lines", nStripped)
+	e.dirty = true
+}
+
+// String returns the document as it will be saved, with each line terminated
+// by the editor's line ending. The last line is terminated only if the file
+// the document was opened from ended with a line ending, or if the document is
+// new.
 func (e *Editor) String() string {
+	eol := e.lineEndingForSave()
 	var builder strings.Builder
-	for _, l := range e.lines {
+	for i, l := range e.lines {
 		builder.WriteString(l.String())
-		builder.WriteByte('\n')
+		if i < len(e.lines)-1 || e.finalNewline {
+			builder.WriteString(eol)
+		}
 	}
 	return builder.String()
 }
 
+// normalizeFinalNewline removes trailing blank lines from the document and
+// ensures the last line is followed by a line ending when saved. The cursor is
+// moved up if its line was removed.
+func (e *Editor) normalizeFinalNewline() {
+	n := len(e.lines)
+	for n > 0 && e.lines[n-1].RuneLen() == 0 {
+		n--
+	}
+	e.shiftBookmarks(n, n-len(e.lines))
+	e.lines = e.lines[:n]
+	e.finalNewline = true
+	if e.cursor.line > n+1 {
+		e.cursor.line = n + 1
+		e.cursor.col = 1
+	}
+}
+
+// trimTrailingWhitespace removes whitespace from the end of every line. The
+// cursor is moved left if it was in the whitespace removed.
+func (e *Editor) trimTrailingWhitespace() {
+	for i, line := range e.lines {
+		runes := line.Runes()
+		n := len(runes)
+		for n > 0 && unicode.IsSpace(runes[n-1]) {
+			n--
+		}
+		if n == len(runes) {
+			continue
+		}
+		trimmed, whitespace := line.SplitAt(n)
+		line.release()
+		whitespace.release()
+		e.lines[i] = trimmed
+	}
+	if e.cursor.line <= len(e.lines) {
+		e.cursor.col = min(e.cursor.col, e.lines[e.cursor.line-1].RuneLen()+1)
+	}
+}
+
+// lineEndingForSave returns the configured line ending if set, otherwise the
+// line ending detected on open, falling back to LF.
+func (e *Editor) lineEndingForSave() string {
+	if e.config.LineEnding != "" {
+		return e.config.LineEnding
+	}
+	if e.lineEnding != "" {
+		return e.lineEnding
+	}
+	return LineEndingLF
+}
+
 func (e *Editor) save() bool {
 	if !e.dirty {
 		return true
@@ -433,99 +1423,300 @@ func (e *Editor) save() bool {
 		e.promptBuf.clear()
 	}
 
-	f, err := os.OpenFile(e.filepath, os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		e.setStatus("Changes not saved! IO error: %s", err)
-		return true
+	// Don't silently overwrite changes made by another program.
+	if e.changedOnDisk() {
+		if !e.prompt("File changed on disk. (o)verwrite, (r)eload or (c)ancel? %s") { // IO error
+			return false
+		}
+		answer := strings.ToLower(e.promptBuf.String())
+		e.promptBuf.clear()
+		switch answer {
+		case "o":
+		case "r":
+			if err := e.Reload(true); err != nil {
+				e.setStatus("Reload failed: %s", err)
+			}
+			return true
+		default:
+			e.setStatus("Save aborted")
+			return true
+		}
+	}
+
+	if e.config.TrimTrailingWhitespace {
+		e.trimTrailingWhitespace()
+	}
+	if e.config.EnsureFinalNewline {
+		e.normalizeFinalNewline()
+	}
+
+	// A failed backup shouldn't prevent the user from saving their work.
+	var backupErr error
+	if e.config.Backup && !e.backedUp {
+		backupErr = backup(e.filepath)
+		e.backedUp = backupErr == nil
 	}
-	defer f.Close()
 
 	document := e.String()
-	if _, err := f.WriteString(document); err != nil {
+	if e.bom || e.config.PreserveBOM {
+		document = utf8BOM + document
+	}
+	err := writeFileAtomic(e.filepath, func(w io.Writer) error {
+		_, err := io.WriteString(w, document)
+		return err
+	})
+	if err != nil {
 		e.setStatus("Changes not saved! IO error: %s", err)
 		return true
 	}
 
-	e.setStatus("Saved")
+	if info, err := os.Stat(e.filepath); err == nil {
+		e.diskStamp = stampOf(info)
+	}
+	if backupErr != nil {
+		e.setStatus("Saved, but backup failed: %s", backupErr)
+	} else {
+		e.setStatus("Saved")
+	}
 	e.dirty = false
+	e.snapshot()
 	return true
 }
 
+// writeFileAtomic replaces the contents of the file at path with the output of
+// write. The output is written to a temporary file in the same directory,
+// which is synced and renamed over the original, so that the original is left
+// intact if writing fails. The original file's permissions are preserved, and
+// new files are created with mode 0644 less the umask. If the rename crosses
+// devices, the temporary file is copied to path instead.
+func writeFileAtomic(path string, write func(w io.Writer) error) (err error) {
+	// Replace the target of a symlink rather than the link itself.
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	// New files are created with the default permissions, less the umask.
+	// Existing files keep their permissions exactly.
+	info, statErr := os.Stat(path)
+	tmpPerm := fs.FileMode(0644)
+	if statErr == nil {
+		tmpPerm = 0600 // until the original permissions are applied
+	}
+	tmp, err := createTemp(filepath.Dir(path), filepath.Base(path), tmpPerm)
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return fmt.Errorf("write %s: %w", tmp.Name(), err)
+	}
+	if statErr == nil {
+		if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+			return fmt.Errorf("chmod %s: %w", tmp.Name(), err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", tmp.Name(), err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if errors.Is(err, syscall.EXDEV) {
+		err = copyFile(tmp.Name(), path)
+		if err == nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// createTemp creates a new hidden file in dir, named for base, with the given
+// permissions less the umask.
+func createTemp(dir, base string, perm fs.FileMode) (*os.File, error) {
+	for i := 0; i < maxTempAttempts; i++ {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d-%d.tmp", base, os.Getpid(), i))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, fmt.Errorf("no unused temporary file name for %s in %s", base, dir)
+}
+
+// copyFile overwrites the file at dst with the contents of the file at src.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// backup copies the file at path to path+"~", preserving its permissions. If
+// there is no file at path, there is nothing to back up.
+func backup(path string) error {
+	src, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	backupPath := path + "~"
+	dst, err := os.OpenFile(backupPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("create %s: %w", backupPath, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("copy %s to %s: %w", path, backupPath, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", backupPath, err)
+	}
+	return nil
+}
+
 func (e *Editor) setStatus(format string, a ...any) {
 	e.statusMsg = fmt.Sprintf(format, a...)
-	e.lastStatusTime = time.Now()
+	e.lastStatusTime = e.now()
+}
+
+// now returns the current time according to the editor's clock.
+func (e *Editor) now() time.Time {
+	if e.clock == nil {
+		return time.Now()
+	}
+	return e.clock()
 }
 
-// transliterateKeypress interprets a raw keypress or chord as a UTF-8-encoded rune.
-func transliterateKeypress(kp []byte) keynum {
+// transliterateKeypress interprets a raw keypress or chord as a UTF-8-encoded
+// rune or special key, together with any modifiers reported by the terminal.
+func transliterateKeypress(kp []byte) keyEvent {
 	if len(kp) == 0 {
-		return 0
+		return keyEvent{}
 	}
 	// Transliterate escape sequences. Due to differences between terminal
 	// emulators, there may be several ways to represent the same escape
 	// sequence.
 	if isEscapeSequence(kp) {
-		if kp[1] == '[' {
-			switch len(kp) {
-			case 4:
-				if kp[3] == '~' {
-					switch kp[2] {
-					case '1':
-						return keyHome
-					case '3':
-						return keyDel
-					case '4':
-						return keyEnd
-					case '5':
-						return keyPageUp
-					case '6':
-						return keyPageDown
-					case '7':
-						return keyHome
-					case '8':
-						return keyEnd
-					}
+		if seq, args, ok := escseq.Decode(kp); ok {
+			if key, ok := escapeKeys[seq]; ok {
+				return keyEvent{key: key}
+			}
+			if key, ok := modifiedKeys[seq]; ok {
+				return keyEvent{key: key, mods: modifiers(args[0])}
+			}
+			switch seq {
+			case escseq.EscKeyVT:
+				if key, ok := vtKeys[args[0]]; ok {
+					return keyEvent{key: key}
 				}
-			case 3:
-				switch kp[2] {
-				case 'A':
-					return keyUp
-				case 'B':
-					return keyDown
-				case 'C':
-					return keyRight
-				case 'D':
-					return keyLeft
-				case 'H':
-					return keyHome
-				case 'F':
-					return keyEnd
+			case escseq.EscKeyVTMod:
+				if key, ok := vtKeys[args[0]]; ok {
+					return keyEvent{key: key, mods: modifiers(args[1])}
 				}
 			}
-		} else if kp[1] == 'O' {
-			switch kp[2] {
-			case 'H':
-				return keyHome
-			case 'F':
-				return keyEnd
-			}
 		}
 	}
 
+	// Terminals send Alt-CHAR as ESC followed by CHAR.
+	if len(kp) == 2 && kp[0] == '\x1b' {
+		return keyEvent{key: keynum(kp[1]) | altMask}
+	}
+
 	// Map special characters to keys.
 	switch kp[0] {
 	case chordBackspace, 127:
-		return keyBackspace
-	case '\x04':
-		return keyDel
+		return keyEvent{key: keyBackspace}
 	case '\x1b':
-		return keyEsc
+		return keyEvent{key: keyEsc}
 	case '\r':
-		return keyLineFeed
+		return keyEvent{key: keyLineFeed}
 	}
 
 	r, _ := utf8.DecodeRune(kp)
-	return keynum(r)
+	return keyEvent{key: keynum(r)}
+}
+
+// modifiers converts the modifier parameter of an xterm-style escape
+// sequence, which is one more than a bitmask of the modifiers held, to a
+// modifier. Modifiers other than Shift, Alt and Ctrl, such as Meta, are
+// ignored.
+func modifiers(param int) modifier {
+	if param < 1 {
+		return 0
+	}
+	return modifier(param-1) & (modShift | modAlt | modCtrl)
+}
+
+// escapeKeys maps the escape sequences of keys without parameters to keys.
+var escapeKeys = map[escseq.EscSeq]keynum{
+	escseq.EscKeyUp:       keyUp,
+	escseq.EscKeyDown:     keyDown,
+	escseq.EscKeyRight:    keyRight,
+	escseq.EscKeyLeft:     keyLeft,
+	escseq.EscKeyHome:     keyHome,
+	escseq.EscKeyEnd:      keyEnd,
+	escseq.EscKeyShiftTab: keyShiftTab,
+	escseq.EscKeyHomeSS3:  keyHome,
+	escseq.EscKeyEndSS3:   keyEnd,
+	escseq.EscKeyF1SS3:    keyF1,
+}
+
+// modifiedKeys maps the escape sequences of keys pressed with modifiers to
+// keys. The modifiers are given by the sequence's parameter.
+var modifiedKeys = map[escseq.EscSeq]keynum{
+	escseq.EscKeyUpMod:    keyUp,
+	escseq.EscKeyDownMod:  keyDown,
+	escseq.EscKeyRightMod: keyRight,
+	escseq.EscKeyLeftMod:  keyLeft,
+	escseq.EscKeyHomeMod:  keyHome,
+	escseq.EscKeyEndMod:   keyEnd,
+}
+
+// vtKeys maps the parameters of escseq.EscKeyVT sequences to keys.
+var vtKeys = map[int]keynum{
+	1:  keyHome,
+	3:  keyDel,
+	4:  keyEnd,
+	5:  keyPageUp,
+	6:  keyPageDown,
+	7:  keyHome,
+	8:  keyEnd,
+	11: keyF1,
+	15: keyF5,
+	24: keyF12,
 }
 
 // isEscapeSequence returns true if the keypress represents an escape sequence.
@@ -0,0 +1,1746 @@
+package editor
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/angusgmorrison/gila/escseq"
+)
+
+func Test_Editor_toggleComment(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		filename  string
+		line      string
+		col       int
+		wantLine  string
+		wantCol   int
+		wantDirty bool
+	}{
+		{
+			name: "when the line is not commented " +
+				"it inserts the prefix before the first non-space character",
+			filename:  "main.go",
+			line:      "\tfmt.Println()",
+			col:       6,
+			wantLine:  "\t// fmt.Println()",
+			wantCol:   9,
+			wantDirty: true,
+		},
+		{
+			name: "when the line is commented " +
+				"it removes the prefix and the following space",
+			filename:  "main.go",
+			line:      "    // fmt.Println()",
+			col:       9,
+			wantLine:  "    fmt.Println()",
+			wantCol:   6,
+			wantDirty: true,
+		},
+		{
+			name: "when the line is commented without a space " +
+				"it removes only the prefix",
+			filename:  "script.py",
+			line:      "#print()",
+			col:       3,
+			wantLine:  "print()",
+			wantCol:   2,
+			wantDirty: true,
+		},
+		{
+			name: "when the cursor is inside the removed prefix " +
+				"it moves the cursor to the start of the uncommented text",
+			filename:  "query.sql",
+			line:      "-- SELECT 1",
+			col:       2,
+			wantLine:  "SELECT 1",
+			wantCol:   1,
+			wantDirty: true,
+		},
+		{
+			name: "when the cursor precedes the prefix " +
+				"it does not move the cursor",
+			filename:  "main.go",
+			line:      "    x := 1",
+			col:       2,
+			wantLine:  "    // x := 1",
+			wantCol:   2,
+			wantDirty: true,
+		},
+		{
+			name: "when the file type has no known comment syntax " +
+				"it leaves the line unchanged",
+			filename:  "notes.txt",
+			line:      "hello",
+			col:       1,
+			wantLine:  "hello",
+			wantCol:   1,
+			wantDirty: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := &Editor{buffer: &buffer{
+				filename: tc.filename,
+				lines:    []*Line{newLineFromString(tc.line)},
+				cursor:   &Cursor{line: 1, col: tc.col},
+			}}
+			e.toggleComment()
+
+			if got := e.lines[0].String(); got != tc.wantLine {
+				t.Errorf("expected line %q, got %q", tc.wantLine, got)
+			}
+			if e.cursor.col != tc.wantCol {
+				t.Errorf("expected cursor col %d, got %d", tc.wantCol, e.cursor.col)
+			}
+			if e.dirty != tc.wantDirty {
+				t.Errorf("expected dirty %t, got %t", tc.wantDirty, e.dirty)
+			}
+		})
+	}
+}
+
+func Test_Editor_stripANSI(t *testing.T) {
+	t.Parallel()
+
+	e := &Editor{buffer: &buffer{
+		lines: []*Line{
+			newLineFromString("\x1b[1;32mok\x1b[0m  main.go"),
+			newLineFromString("plain"),
+			newLineFromString("\x1b[31mFAIL\x1b[m"),
+		},
+		cursor: &Cursor{line: 3, col: 14},
+	}}
+	e.stripANSI()
+
+	want := []string{"ok  main.go", "plain", "FAIL"}
+	for i, w := range want {
+		if got := e.lines[i].String(); got != w {
+			t.Errorf("line %d: expected %q, got %q", i+1, w, got)
+		}
+	}
+	if e.cursor.col != 5 {
+		t.Errorf("expected cursor to snap to col 5, got %d", e.cursor.col)
+	}
+	if !e.dirty {
+		t.Error("expected editor to be dirty")
+	}
+}
+
+func Test_Editor_indentSizeAndTabStop(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tabs.txt")
+	if err := os.WriteFile(path, []byte("\tx\n\n"), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+
+	e := New(nil, nil, WithConfig(Config{TabStop: 4, IndentSize: 2}))
+	if err := e.open(path, nil); err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	if got, want := e.lines[0].String(), "\tx"; got != want {
+		t.Errorf("expected tab to be kept as %q, got %q", want, got)
+	}
+	if got, want := e.lines[0].DisplayWidth(1), 4; got != want {
+		t.Errorf("expected tab to be displayed %d cells wide, got %d", want, got)
+	}
+
+	e.cursor.line = 2
+	e.insertIndent()
+	if got, want := e.lines[1].String(), "  "; got != want {
+		t.Errorf("after first indent: expected %q, got %q", want, got)
+	}
+	e.insertRune('y')
+	e.insertIndent()
+	if got, want := e.lines[1].String(), "  y "; got != want {
+		t.Errorf("after second indent: expected %q, got %q", want, got)
+	}
+	if e.cursor.col != 5 {
+		t.Errorf("expected cursor col 5, got %d", e.cursor.col)
+	}
+}
+
+func Test_Editor_insertIndent_hardTabs(t *testing.T) {
+	t.Parallel()
+
+	e := NewHeadless("x\n",
+		WithConfig(Config{Width: 80, Height: 24, HardTabs: true}),
+		WithKeyReader(Keys([]byte("\t"), []byte("\x1b[C"), []byte("\t"))),
+	)
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := e.String(), "\tx\t\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func Test_Editor_lineEndings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		contents   string
+		lineEnding string
+		wantLines  []string
+		want       string
+	}{
+		{
+			name:      "when the file uses LF it is saved with LF",
+			contents:  "a\nb\n",
+			wantLines: []string{"a", "b"},
+			want:      "a\nb\n",
+		},
+		{
+			name:      "when the file uses CRLF it is saved with CRLF",
+			contents:  "a\r\nb\r\n",
+			wantLines: []string{"a", "b"},
+			want:      "a\r\nb\r\n",
+		},
+		{
+			name:      "when the file has mixed endings it is saved with the dominant ending",
+			contents:  "a\r\nb\nc\r\n",
+			wantLines: []string{"a", "b", "c"},
+			want:      "a\r\nb\r\nc\r\n",
+		},
+		{
+			name:      "when the file has equal numbers of each ending it is saved with LF",
+			contents:  "a\r\nb\n",
+			wantLines: []string{"a", "b"},
+			want:      "a\nb\n",
+		},
+		{
+			name:      "when the file has no trailing newline the last line is kept",
+			contents:  "a\r\nb",
+			wantLines: []string{"a", "b"},
+			want:      "a\r\nb",
+		},
+		{
+			name:       "when LF is configured it overrides the detected ending",
+			contents:   "a\r\nb\r\n",
+			lineEnding: LineEndingLF,
+			wantLines:  []string{"a", "b"},
+			want:       "a\nb\n",
+		},
+		{
+			name:       "when CRLF is configured it overrides the detected ending",
+			contents:   "a\nb\n",
+			lineEnding: LineEndingCRLF,
+			wantLines:  []string{"a", "b"},
+			want:       "a\r\nb\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "endings.txt")
+			if err := os.WriteFile(path, []byte(tc.contents), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+
+			e := New(nil, nil, WithConfig(Config{LineEnding: tc.lineEnding}))
+			if err := e.open(path, nil); err != nil {
+				t.Fatalf("open %s: %v", path, err)
+			}
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+
+			e.dirty = true
+			if !e.save() {
+				t.Fatalf("unexpected save failure: %s", e.statusMsg)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read saved file: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected saved contents %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_finalNewline(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "when the file ends with a newline it is preserved",
+			contents: "a\nb\n",
+			want:     "a\nb\nc\n",
+		},
+		{
+			name:     "when the file doesn't end with a newline none is added",
+			contents: "a\nb",
+			want:     "a\nb\nc",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "final.txt")
+			if err := os.WriteFile(path, []byte(tc.contents), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+
+			e := New(nil, nil)
+			if err := e.open(path, nil); err != nil {
+				t.Fatalf("open %s: %v", path, err)
+			}
+			e.lines = append(e.lines, newLineFromString("c"))
+			if got := e.String(); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_bom(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		contents    string
+		preserveBOM bool
+		want        string
+	}{
+		{
+			name:     "when the file has a BOM it is written back",
+			contents: utf8BOM + "héllo\n",
+			want:     utf8BOM + "héllo!\n",
+		},
+		{
+			name:        "when the file has a BOM and PreserveBOM is set it is written back",
+			contents:    utf8BOM + "héllo\n",
+			preserveBOM: true,
+			want:        utf8BOM + "héllo!\n",
+		},
+		{
+			name:        "when the file has no BOM and PreserveBOM is set one is added",
+			contents:    "héllo\n",
+			preserveBOM: true,
+			want:        utf8BOM + "héllo!\n",
+		},
+		{
+			name:     "when the file has no BOM none is added",
+			contents: "héllo\n",
+			want:     "héllo!\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "bom.txt")
+			if err := os.WriteFile(path, []byte(tc.contents), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+
+			e := New(nil, nil, WithConfig(Config{PreserveBOM: tc.preserveBOM}))
+			if err := e.open(path, nil); err != nil {
+				t.Fatalf("open %s: %v", path, err)
+			}
+			if got, want := e.lines[0].String(), "héllo"; got != want {
+				t.Errorf("expected first line %q, got %q", want, got)
+			}
+
+			e.cursor.end(e.lines[0].RuneLen())
+			if e.cursor.col != 6 {
+				t.Errorf("expected cursor col 6 at end of line, got %d", e.cursor.col)
+			}
+			e.insertRune('!')
+			if !e.save() {
+				t.Fatalf("unexpected save failure: %s", e.statusMsg)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read saved file: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected saved contents %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_bom_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		contents    string
+		preserveBOM bool
+		want        string
+	}{
+		{
+			name:     "when the file has a BOM it is saved unchanged",
+			contents: utf8BOM + "one\r\ntwo\r\n",
+			want:     utf8BOM + "one\r\ntwo\r\n",
+		},
+		{
+			name:        "when the file has no BOM and PreserveBOM is set one is added",
+			contents:    "one\r\ntwo\r\n",
+			preserveBOM: true,
+			want:        utf8BOM + "one\r\ntwo\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "bom.txt")
+			if err := os.WriteFile(path, []byte(tc.contents), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+
+			e := New(nil, nil, WithConfig(Config{PreserveBOM: tc.preserveBOM}))
+			if err := e.open(path, nil); err != nil {
+				t.Fatalf("open %s: %v", path, err)
+			}
+			// Edit and undo the edit, so that the save writes the file.
+			e.insertRune('!')
+			e.backspace()
+			if !e.dirty {
+				t.Fatal("expected the edit to mark the buffer as modified")
+			}
+			if !e.save() {
+				t.Fatalf("unexpected save failure: %s", e.statusMsg)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read saved file: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected saved contents %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_open_binary(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		contents  string
+		wantErr   error
+		wantLines []string
+	}{
+		{
+			name:     "when the file contains a NUL byte it is refused",
+			contents: "ELF\x00\x01\x02",
+			wantErr:  ErrBinaryFile,
+		},
+		{
+			name:     "when the file contains a NUL byte past the default read buffer it is refused",
+			contents: strings.Repeat("a", 5000) + "\x00",
+			wantErr:  ErrBinaryFile,
+		},
+		{
+			name:      "when the file contains invalid UTF-8 it is opened with replacement characters",
+			contents:  "caf\xe9\n",
+			wantLines: []string{"caf\uFFFD"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "binary")
+			if err := os.WriteFile(path, []byte(tc.contents), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+
+			e := New(nil, nil)
+			err := e.open(path, nil)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_open_binaryKeepsBuffer(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	text := filepath.Join(dir, "text.txt")
+	binDir := filepath.Join(dir, "bin")
+	binary := filepath.Join(binDir, "binary")
+	if err := os.WriteFile(text, []byte("\ufeffone\r\ntwo"), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(binary, []byte("ELF\x00\x01\x02"), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	ec := "root = true\n[*]\nindent_style = tab\ntab_width = 8\n"
+	if err := os.WriteFile(filepath.Join(binDir, ".editorconfig"), []byte(ec), 0644); err != nil {
+		t.Fatalf("write .editorconfig: %v", err)
+	}
+
+	e := New(nil, nil)
+	if err := e.open(text, nil); err != nil {
+		t.Fatalf("open %s: %v", text, err)
+	}
+	wantConfig := e.config
+	if err := e.open(binary, nil); !errors.Is(err, ErrBinaryFile) {
+		t.Fatalf("expected ErrBinaryFile, got %v", err)
+	}
+
+	if e.filepath != text || e.filename != "text.txt" {
+		t.Errorf("expected the buffer to keep file %s, got %s (%s)", text, e.filepath, e.filename)
+	}
+	if !e.bom || e.finalNewline || e.lineEnding != LineEndingCRLF {
+		t.Errorf("expected the buffer to keep its format, got bom %t, final newline %t, line ending %q",
+			e.bom, e.finalNewline, e.lineEnding)
+	}
+	if !reflect.DeepEqual(e.config, wantConfig) {
+		t.Errorf("expected the configuration to be unchanged as %+v, got %+v", wantConfig, e.config)
+	}
+	if got, want := lineStrings(e.lines), []string{"one", "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected lines %q, got %q", want, got)
+	}
+}
+
+func Test_Editor_read_failure(t *testing.T) {
+	t.Parallel()
+
+	e := NewHeadless("\ufeffone\r\ntwo")
+	if err := e.read(strings.NewReader("\x00binary\n")); !errors.Is(err, ErrBinaryFile) {
+		t.Fatalf("expected ErrBinaryFile, got %v", err)
+	}
+	if got, want := e.String(), "one\r\ntwo"; got != want {
+		t.Errorf("expected the document to be unchanged as %q, got %q", want, got)
+	}
+	if !e.bom {
+		t.Error("expected the byte-order mark to be kept")
+	}
+}
+
+func Test_Editor_Reload_failure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "reload.txt")
+	if err := os.WriteFile(path, []byte("\ufeffone\r\ntwo"), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	e := New(nil, nil, WithConfig(Config{Width: 80, Height: 24}))
+	if err := e.open(path, nil); err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	e.cursor.line = 2
+	e.insertRune('!')
+	wantConfig := e.config
+
+	// The reloaded file is binary and has .editorconfig settings that differ
+	// from the buffer's.
+	if err := os.WriteFile(path, []byte("\x00\x01\x02\n"), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	ec := "root = true\n[*]\nindent_style = tab\ntab_width = 8\nend_of_line = lf\n"
+	if err := os.WriteFile(filepath.Join(dir, ".editorconfig"), []byte(ec), 0644); err != nil {
+		t.Fatalf("write .editorconfig: %v", err)
+	}
+
+	if err := e.Reload(true); !errors.Is(err, ErrBinaryFile) {
+		t.Fatalf("expected ErrBinaryFile, got %v", err)
+	}
+	if got, want := lineStrings(e.lines), []string{"one", "!two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected lines %q, got %q", want, got)
+	}
+	if e.filepath != path || e.filename != "reload.txt" {
+		t.Errorf("expected the buffer to keep file %s, got %s (%s)", path, e.filepath, e.filename)
+	}
+	if !e.bom || e.finalNewline || e.lineEnding != LineEndingCRLF {
+		t.Errorf("expected the buffer to keep its format, got bom %t, final newline %t, line ending %q",
+			e.bom, e.finalNewline, e.lineEnding)
+	}
+	if !e.dirty {
+		t.Error("expected the buffer to keep its unsaved changes")
+	}
+	if e.cursor.line != 2 || e.cursor.col != 2 {
+		t.Errorf("expected cursor at 2:2, got %d:%d", e.cursor.line, e.cursor.col)
+	}
+	if !reflect.DeepEqual(e.config, wantConfig) {
+		t.Errorf("expected the configuration to be unchanged as %+v, got %+v", wantConfig, e.config)
+	}
+}
+
+func Test_Editor_Reload(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "reload.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	e := New(nil, nil)
+	if err := e.open(path, nil); err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	e.cursor.line = 2
+	e.insertRune('!')
+
+	if err := e.Reload(false); !errors.Is(err, errUnsavedChanges) {
+		t.Fatalf("expected errUnsavedChanges, got %v", err)
+	}
+	if got, want := e.lines[1].String(), "!two"; got != want {
+		t.Errorf("expected unforced reload to keep %q, got %q", want, got)
+	}
+
+	if err := e.Reload(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := lineStrings(e.lines), []string{"one", "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected lines %q, got %q", want, got)
+	}
+	if e.dirty {
+		t.Error("expected editor not to be dirty")
+	}
+	if e.cursor.line != 1 || e.cursor.col != 1 {
+		t.Errorf("expected cursor at 1:1, got %d:%d", e.cursor.line, e.cursor.col)
+	}
+}
+
+func Test_Editor_Reload_keys(t *testing.T) {
+	t.Parallel()
+
+	const f5 = "\x1b[15~"
+	testCases := []struct {
+		name      string
+		keys      *fakeKeyReader
+		wantLines []string
+	}{
+		{
+			name:      "when F5 is pressed once with unsaved changes it does nothing",
+			keys:      keys("x", f5),
+			wantLines: []string{"xone"},
+		},
+		{
+			name:      "when F5 is pressed twice in a row with unsaved changes it reloads",
+			keys:      keys("x", f5, f5),
+			wantLines: []string{"one"},
+		},
+		{
+			name:      "when another key separates the presses of F5 it does nothing",
+			keys:      keys("x", f5, "\x1b[C", f5),
+			wantLines: []string{"xone"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "reload.txt")
+			if err := os.WriteFile(path, []byte("one\n"), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+			e := New(tc.keys, nil)
+			if err := e.open(path, nil); err != nil {
+				t.Fatalf("open %s: %v", path, err)
+			}
+			for e.processKeypress() {
+			}
+
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_Load(t *testing.T) {
+	t.Parallel()
+
+	e := New(nil, nil)
+	if err := e.Load(strings.NewReader("piped\r\ntext\r\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"piped", "text"}
+	if got := lineStrings(e.lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected lines %q, got %q", want, got)
+	}
+	if e.filename != defaultFilename {
+		t.Errorf("expected filename %q, got %q", defaultFilename, e.filename)
+	}
+	if !e.dirty {
+		t.Error("expected editor to be dirty")
+	}
+	if got, want := e.String(), "piped\r\ntext\r\n"; got != want {
+		t.Errorf("expected document %q, got %q", want, got)
+	}
+}
+
+func Test_New_tabDefaults(t *testing.T) {
+	t.Parallel()
+
+	e := New(nil, nil)
+	if e.config.TabStop != defaultTabStop {
+		t.Errorf("expected default TabStop %d, got %d", defaultTabStop, e.config.TabStop)
+	}
+	if e.config.IndentSize != defaultIndentSize {
+		t.Errorf("expected default IndentSize %d, got %d", defaultIndentSize, e.config.IndentSize)
+	}
+}
+
+func Test_Editor_Run_nilRenderer(t *testing.T) {
+	t.Parallel()
+
+	kr := keys("h", "i", "\r", "x", "\x1b[A", "\x1b[F", "!")
+	e := New(kr, nil, WithConfig(Config{Width: 80, Height: 24}))
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"hi!", "x"}
+	if got := lineStrings(e.lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected lines %q, got %q", want, got)
+	}
+	if !e.dirty {
+		t.Error("expected editor to be dirty")
+	}
+}
+
+// failingRenderer returns renderErr from Render and clearErr from Clear.
+type failingRenderer struct {
+	fakeRenderer
+	renderErr, clearErr error
+}
+
+func (r *failingRenderer) Render(frame Frame) error {
+	if r.renderErr != nil {
+		return r.renderErr
+	}
+	return r.fakeRenderer.Render(frame)
+}
+
+func (r *failingRenderer) Clear() error {
+	return r.clearErr
+}
+
+// failingKeyReader returns err from every read.
+type failingKeyReader struct {
+	err error
+}
+
+func (kr failingKeyReader) ReadKey() ([]byte, error) {
+	return nil, kr.err
+}
+
+func Test_New_nilDependencies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		kr   KeyReader
+		r    Renderer
+		opts []EditorOption
+	}{
+		{name: "when both are nil it runs without drawing or reading keys"},
+		{name: "when the KeyReader is nil it reads no keys", r: NullRenderer{}},
+		{name: "when the Renderer is nil it processes keys without drawing", kr: keys("a")},
+		{
+			name: "when WithKeyReader sets a nil KeyReader it reads no keys",
+			kr:   keys("a"),
+			opts: []EditorOption{WithKeyReader(nil)},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(tc.kr, tc.r, tc.opts...)
+			if err := e.Run(); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_Editor_Run_errors(t *testing.T) {
+	t.Parallel()
+
+	errRead := errors.New("read failed")
+	errRender := errors.New("render failed")
+	errClear := errors.New("clear failed")
+
+	testCases := []struct {
+		name     string
+		kr       KeyReader
+		r        *failingRenderer
+		wantErrs []error
+	}{
+		{
+			name:     "when reading and clearing fail it returns both errors",
+			kr:       failingKeyReader{err: errRead},
+			r:        &failingRenderer{clearErr: errClear},
+			wantErrs: []error{errRead, errClear},
+		},
+		{
+			name:     "when rendering and clearing fail it returns both errors",
+			kr:       keys("a"),
+			r:        &failingRenderer{renderErr: errRender, clearErr: errClear},
+			wantErrs: []error{errRender, errClear},
+		},
+		{
+			name:     "when only clearing fails it returns the error",
+			kr:       keys("a"),
+			r:        &failingRenderer{clearErr: errClear},
+			wantErrs: []error{errClear},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(tc.kr, tc.r, WithConfig(Config{Width: 80, Height: 24}))
+			err := e.Run()
+			for _, want := range tc.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("expected error to include %v, got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+// invalidatingRenderer counts calls to Invalidate.
+type invalidatingRenderer struct {
+	fakeRenderer
+	invalidated int
+}
+
+func (r *invalidatingRenderer) Invalidate() {
+	r.invalidated++
+}
+
+func Test_Editor_refresh(t *testing.T) {
+	t.Parallel()
+
+	r := &invalidatingRenderer{}
+	e := New(keys("\x0c"), r, WithConfig(Config{Width: 80, Height: 24}))
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.invalidated != 1 {
+		t.Errorf("expected the renderer to be invalidated once, got %d", r.invalidated)
+	}
+}
+
+func Test_Editor_processKeypress_text(t *testing.T) {
+	t.Parallel()
+
+	// NBSP and the ideographic space aren't graphic characters, but they are
+	// text that can be typed.
+	e := NewHeadless("", WithKeyReader(Keys([]byte("\u00a0"), []byte("\u3000"), []byte("a"), []byte("\x1b[A"))))
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := e.Buffer(), "\u00a0\u3000a\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func Test_Editor_tab_virtualLine(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+		keys    [][]byte
+		want    string
+	}{
+		{
+			name:    "when the document is empty Tab inserts an indent",
+			content: "",
+			keys:    [][]byte{[]byte("\t")},
+			want:    "    \n",
+		},
+		{
+			name:    "when the cursor is past the last line Tab inserts an indent",
+			content: "x\n",
+			keys:    [][]byte{[]byte("\x1b[B"), []byte("\t")},
+			want:    "x\n    \n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := NewHeadless(tc.content, WithKeyReader(Keys(tc.keys...)))
+			if err := e.Run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := e.Buffer(); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_indent(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		line     string
+		col      int
+		delta    int
+		wantLine string
+		wantCol  int
+	}{
+		{
+			name:     "indent an unindented line",
+			line:     "x := 1",
+			col:      1,
+			delta:    1,
+			wantLine: "  x := 1",
+			wantCol:  3,
+		},
+		{
+			name:     "indent an empty line",
+			line:     "",
+			col:      1,
+			delta:    1,
+			wantLine: "  ",
+			wantCol:  3,
+		},
+		{
+			name:     "dedent a fully indented line",
+			line:     "    x := 1",
+			col:      7,
+			delta:    -1,
+			wantLine: "  x := 1",
+			wantCol:  5,
+		},
+		{
+			name:     "dedent a partially indented line",
+			line:     " x := 1",
+			col:      4,
+			delta:    -1,
+			wantLine: "x := 1",
+			wantCol:  3,
+		},
+		{
+			name:     "dedent with the cursor inside the indent",
+			line:     "  x := 1",
+			col:      2,
+			delta:    -1,
+			wantLine: "x := 1",
+			wantCol:  1,
+		},
+		{
+			name:     "dedent an unindented line",
+			line:     "x := 1",
+			col:      3,
+			delta:    -1,
+			wantLine: "x := 1",
+			wantCol:  3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := &Editor{
+				buffer: &buffer{
+					lines:  []*Line{newLineFromString(tc.line)},
+					cursor: &Cursor{line: 1, col: tc.col},
+				},
+				config: Config{IndentSize: 2},
+			}
+			e.indent(tc.delta)
+
+			if got := e.lines[0].String(); got != tc.wantLine {
+				t.Errorf("expected line %q, got %q", tc.wantLine, got)
+			}
+			if e.cursor.col != tc.wantCol {
+				t.Errorf("expected cursor col %d, got %d", tc.wantCol, e.cursor.col)
+			}
+			if wantDirty := tc.line != tc.wantLine; e.dirty != wantDirty {
+				t.Errorf("expected dirty %t, got %t", wantDirty, e.dirty)
+			}
+		})
+	}
+}
+
+func Test_transliterateKeypress(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		kp       []byte
+		want     keynum
+		wantMods modifier
+	}{
+		{name: "empty", kp: nil, want: 0},
+		{name: "printable", kp: []byte("a"), want: 'a'},
+		{name: "multibyte", kp: []byte("é"), want: 'é'},
+		{name: "tab", kp: []byte("\t"), want: chordIndent},
+		{name: "shift-tab", kp: []byte("\x1b[Z"), want: keyShiftTab},
+		{name: "escape", kp: []byte("\x1b"), want: keyEsc},
+		{name: "F5", kp: []byte("\x1b[15~"), want: keyF5},
+		{name: "F12", kp: []byte("\x1b[24~"), want: keyF12},
+		{name: "arrow", kp: []byte("\x1b[A"), want: keyUp},
+		{name: "delete", kp: []byte("\x1b[3~"), want: keyDel},
+		{name: "ctrl-d is not delete", kp: []byte("\x04"), want: '\x04'},
+		{name: "F1", kp: []byte("\x1bOP"), want: keyF1},
+		{name: "SS3 home", kp: []byte("\x1bOH"), want: keyHome},
+		{name: "VT end", kp: []byte("\x1b[8~"), want: keyEnd},
+		{name: "unknown VT key", kp: []byte("\x1b[99~"), want: keyEsc},
+		{name: "alt", kp: []byte("\x1bn"), want: altMask | 'n'},
+		{name: "alt-O", kp: []byte("\x1bO"), want: altMask | 'O'},
+		{name: "ctrl-right", kp: []byte("\x1b[1;5C"), want: keyRight, wantMods: modCtrl},
+		{name: "shift-up", kp: []byte("\x1b[1;2A"), want: keyUp, wantMods: modShift},
+		{name: "alt-left", kp: []byte("\x1b[1;3D"), want: keyLeft, wantMods: modAlt},
+		{name: "ctrl-shift-end", kp: []byte("\x1b[1;6F"), want: keyEnd, wantMods: modCtrl | modShift},
+		{name: "ctrl-alt-shift-home", kp: []byte("\x1b[1;8H"), want: keyHome, wantMods: modCtrl | modAlt | modShift},
+		{name: "meta is ignored", kp: []byte("\x1b[1;9B"), want: keyDown},
+		{name: "ctrl-delete", kp: []byte("\x1b[3;5~"), want: keyDel, wantMods: modCtrl},
+		{name: "shift-page-up", kp: []byte("\x1b[5;2~"), want: keyPageUp, wantMods: modShift},
+		{name: "unknown modified VT key", kp: []byte("\x1b[99;5~"), want: keyEsc},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := transliterateKeypress(tc.kp)
+			if got.key != tc.want {
+				t.Errorf("expected key %q, got %q", tc.want, got.key)
+			}
+			if got.mods != tc.wantMods {
+				t.Errorf("expected modifiers %03b, got %03b", tc.wantMods, got.mods)
+			}
+		})
+	}
+}
+
+// FuzzTransliterateKeypress checks that arbitrary terminal input transliterates
+// to a key in the Unicode range, a function key or an Alt chord, without
+// panicking. The corpus is seeded with every escape sequence the editor
+// decodes and the special single-byte keys, and by the malformed sequences in
+// testdata/fuzz/FuzzTransliterateKeypress.
+func FuzzTransliterateKeypress(f *testing.F) {
+	for _, seq := range []escseq.EscSeq{
+		escseq.EscKeyUp, escseq.EscKeyDown, escseq.EscKeyRight, escseq.EscKeyLeft,
+		escseq.EscKeyHome, escseq.EscKeyEnd, escseq.EscKeyShiftTab,
+		escseq.EscKeyHomeSS3, escseq.EscKeyEndSS3, escseq.EscKeyF1SS3,
+	} {
+		f.Add([]byte(seq))
+	}
+	for _, seq := range []escseq.EscSeq{
+		escseq.EscKeyUpMod, escseq.EscKeyDownMod, escseq.EscKeyRightMod,
+		escseq.EscKeyLeftMod, escseq.EscKeyHomeMod, escseq.EscKeyEndMod,
+		escseq.EscKeyVT,
+	} {
+		f.Add([]byte(escseq.Format(seq, 5)))
+	}
+	f.Add([]byte(escseq.Format(escseq.EscKeyVTMod, 3, 5)))
+	for _, b := range []byte{0, chordBackspace, chordIndent, '\x04', '\r', '\x1b', 127} {
+		f.Add([]byte{b})
+	}
+	f.Add([]byte("\x1bn"))
+	f.Add([]byte("é"))
+
+	f.Fuzz(func(t *testing.T, kp []byte) {
+		ev := transliterateKeypress(kp)
+
+		key := ev.key
+		switch {
+		case key >= 0 && key <= unicode.MaxRune:
+		case key >= keyBackspace && key <= keyUp:
+		case key&altMask != 0 && key&^altMask <= 0xff:
+		default:
+			t.Errorf("%q: key %d is outside the Unicode and function key ranges", kp, key)
+		}
+		if ev.mods&^(modShift|modAlt|modCtrl) != 0 {
+			t.Errorf("%q: unknown modifiers %03b", kp, ev.mods)
+		}
+	})
+}
+
+func Test_Editor_setStatus(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := New(nil, nil)
+	e.clock = func() time.Time { return now }
+	e.setStatus("Saved %d lines", 3)
+
+	frame := e.frame()
+	if frame.StatusMsg != "Saved 3 lines" {
+		t.Errorf("expected status %q, got %q", "Saved 3 lines", frame.StatusMsg)
+	}
+	if !frame.LastStatusTime.Equal(now) {
+		t.Errorf("expected status time %v, got %v", now, frame.LastStatusTime)
+	}
+}
+
+func Test_Editor_readOnly(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		keys      *fakeKeyReader
+		wantLines []string
+		wantDirty bool
+	}{
+		{
+			name:      "when text is typed it is ignored",
+			keys:      keys("x", "y"),
+			wantLines: []string{"one", "two"},
+		},
+		{
+			name:      "when backspace, delete and enter are pressed they are ignored",
+			keys:      keys("\x1b[B", "\x7f", "\x1b[3~", "\r"),
+			wantLines: []string{"one", "two"},
+		},
+		{
+			name:      "when read-only mode is toggled off, typing edits the document",
+			keys:      keys("\x1br", "x"),
+			wantLines: []string{"xone", "two"},
+			wantDirty: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "read_only.txt")
+			if err := os.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+			e := New(tc.keys, nil, WithConfig(Config{Width: 80, Height: 24, ReadOnly: true}))
+			if err := e.open(path, nil); err != nil {
+				t.Fatalf("open %s: %v", path, err)
+			}
+			for e.processKeypress() {
+			}
+
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+			if e.dirty != tc.wantDirty {
+				t.Errorf("expected dirty %t, got %t", tc.wantDirty, e.dirty)
+			}
+		})
+	}
+}
+
+func Test_Editor_readOnly_save(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "read_only.txt")
+	if err := os.WriteFile(path, []byte("one\n"), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	e := New(keys("\x13"), nil, WithConfig(Config{Width: 80, Height: 24, ReadOnly: true}))
+	if err := e.open(path, nil); err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	e.lines[0] = NewLine("changed")
+	for e.processKeypress() {
+	}
+	if e.statusMsg != "Cannot save: read-only mode" {
+		t.Errorf("expected status %q, got %q", "Cannot save: read-only mode", e.statusMsg)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if string(got) != "one\n" {
+		t.Errorf("expected the file to be unchanged, got %q", got)
+	}
+}
+
+func Test_Editor_save_backup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("when the file exists, the original is backed up on the first save only", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "backup.txt")
+		if err := os.WriteFile(path, []byte("original\n"), 0644); err != nil {
+			t.Fatalf("write test file: %v", err)
+		}
+		e := New(keys("a", "\x13", "b", "\x13"), nil, WithConfig(Config{Width: 80, Height: 24, Backup: true}))
+		if err := e.open(path, nil); err != nil {
+			t.Fatalf("open %s: %v", path, err)
+		}
+		for e.processKeypress() {
+		}
+
+		got, err := os.ReadFile(path + "~")
+		if err != nil {
+			t.Fatalf("read backup: %v", err)
+		}
+		if want := "original\n"; string(got) != want {
+			t.Errorf("expected backup %q, got %q", want, got)
+		}
+		if got, want := e.statusMsg, "Saved"; got != want {
+			t.Errorf("expected status %q, got %q", want, got)
+		}
+	})
+
+	t.Run("when the backup fails, the file is still saved", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "backup.txt")
+		if err := os.WriteFile(path, []byte("original\n"), 0644); err != nil {
+			t.Fatalf("write test file: %v", err)
+		}
+		// A directory in place of the backup file can't be opened for writing.
+		if err := os.Mkdir(path+"~", 0755); err != nil {
+			t.Fatalf("create directory: %v", err)
+		}
+		e := New(keys("a", "\x13"), nil, WithConfig(Config{Width: 80, Height: 24, Backup: true}))
+		if err := e.open(path, nil); err != nil {
+			t.Fatalf("open %s: %v", path, err)
+		}
+		for e.processKeypress() {
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if want := "aoriginal\n"; string(got) != want {
+			t.Errorf("expected file %q, got %q", want, got)
+		}
+		if !strings.HasPrefix(e.statusMsg, "Saved, but backup failed") {
+			t.Errorf("expected status to report the failed backup, got %q", e.statusMsg)
+		}
+	})
+}
+
+func Test_writeFileAtomic(t *testing.T) {
+	t.Parallel()
+
+	t.Run("when writing fails, the original file is left intact", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		path := filepath.Join(dir, "atomic.txt")
+		if err := os.WriteFile(path, []byte("original\n"), 0644); err != nil {
+			t.Fatalf("write test file: %v", err)
+		}
+		errWrite := errors.New("disk full")
+		err := writeFileAtomic(path, func(w io.Writer) error {
+			if _, err := io.WriteString(w, "partial"); err != nil {
+				return err
+			}
+			return errWrite
+		})
+		if !errors.Is(err, errWrite) {
+			t.Fatalf("expected error %v, got %v", errWrite, err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if want := "original\n"; string(got) != want {
+			t.Errorf("expected file %q, got %q", want, got)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("read %s: %v", dir, err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("expected the temporary file to be removed, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("when writing succeeds, the file is replaced and its mode preserved", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "atomic.txt")
+		if err := os.WriteFile(path, []byte("original\n"), 0600); err != nil {
+			t.Fatalf("write test file: %v", err)
+		}
+		err := writeFileAtomic(path, func(w io.Writer) error {
+			_, err := io.WriteString(w, "replaced\n")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if want := "replaced\n"; string(got) != want {
+			t.Errorf("expected file %q, got %q", want, got)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat %s: %v", path, err)
+		}
+		if got, want := info.Mode().Perm(), os.FileMode(0600); got != want {
+			t.Errorf("expected mode %v, got %v", want, got)
+		}
+	})
+}
+
+func Test_Editor_save_permissions(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "private.txt")
+	if err := os.WriteFile(path, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	e := New(keys("x", "\x13"), nil, WithConfig(Config{Width: 80, Height: 24}))
+	if err := e.open(path, nil); err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	for e.processKeypress() {
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat %s: %v", path, err)
+	}
+	if got, want := info.Mode().Perm(), os.FileMode(0600); got != want {
+		t.Errorf("expected mode %v, got %v", want, got)
+	}
+	if e.dirty {
+		t.Errorf("expected the document to be saved, got status %q", e.statusMsg)
+	}
+}
+
+func Test_Editor_save_changedOnDisk(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		answer    string
+		wantFile  string
+		wantLines []string
+	}{
+		{
+			name:      "when the user cancels, the file is not saved",
+			answer:    "c",
+			wantFile:  "external\n",
+			wantLines: []string{"xoriginal"},
+		},
+		{
+			name:      "when the user overwrites, the file is saved",
+			answer:    "o",
+			wantFile:  "xoriginal\n",
+			wantLines: []string{"xoriginal"},
+		},
+		{
+			name:      "when the user reloads, the buffer is replaced by the file",
+			answer:    "r",
+			wantFile:  "external\n",
+			wantLines: []string{"external"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "changed.txt")
+			if err := os.WriteFile(path, []byte("original\n"), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+			e := New(keys("x", "\x13", tc.answer, "\r"), nil, WithConfig(Config{Width: 80, Height: 24}))
+			if err := e.open(path, nil); err != nil {
+				t.Fatalf("open %s: %v", path, err)
+			}
+			// Simulate another program modifying the file.
+			if err := os.WriteFile(path, []byte("external\n"), 0644); err != nil {
+				t.Fatalf("modify test file: %v", err)
+			}
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(path, later, later); err != nil {
+				t.Fatalf("change mtime: %v", err)
+			}
+			for e.processKeypress() {
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read %s: %v", path, err)
+			}
+			if string(got) != tc.wantFile {
+				t.Errorf("expected file %q, got %q", tc.wantFile, got)
+			}
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_save_preservesTabs(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tabs.go")
+	if err := os.WriteFile(path, []byte("func f() {\n\treturn\n}\n"), 0644); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+	e := New(keys("\x1b[B", "\x1b[C", "x", "\x13"), nil, WithConfig(Config{Width: 80, Height: 24, TabStop: 8}))
+	if err := e.open(path, nil); err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	for e.processKeypress() {
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if want := "func f() {\n\txreturn\n}\n"; string(got) != want {
+		t.Errorf("expected file %q, got %q", want, got)
+	}
+}
+
+func Test_Editor_save_ensureFinalNewline(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "when the file has no final newline one is added",
+			contents: "one\ntwo",
+			want:     "xone\ntwo\n",
+		},
+		{
+			name:     "when the file has one final newline it is kept",
+			contents: "one\ntwo\n",
+			want:     "xone\ntwo\n",
+		},
+		{
+			name:     "when the file has two final newlines the blank line is removed",
+			contents: "one\ntwo\n\n",
+			want:     "xone\ntwo\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "newline.txt")
+			if err := os.WriteFile(path, []byte(tc.contents), 0644); err != nil {
+				t.Fatalf("write test file: %v", err)
+			}
+			e := New(keys("x", "\x13"), nil, WithConfig(Config{Width: 80, Height: 24, EnsureFinalNewline: true}))
+			if err := e.open(path, nil); err != nil {
+				t.Fatalf("open %s: %v", path, err)
+			}
+			for e.processKeypress() {
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read %s: %v", path, err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected file %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_deleteLines(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		i, n int
+		want []string
+	}{
+		{name: "when deleting from the middle the tail shifts up", i: 1, n: 2, want: []string{"a", "d"}},
+		{name: "when n runs past the end it deletes to the end", i: 2, n: 5, want: []string{"a", "b"}},
+		{name: "when i is out of bounds it does nothing", i: 4, n: 1, want: []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(nil, nil)
+			for _, s := range []string{"a", "b", "c", "d"} {
+				e.lines = append(e.lines, NewLine(s))
+			}
+			backing := e.lines[:cap(e.lines)]
+			e.deleteLines(tc.i, tc.n)
+
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected lines %q, got %q", tc.want, got)
+			}
+			for j := len(e.lines); j < 4; j++ {
+				if backing[j] != nil {
+					t.Errorf("expected vacated slot %d to be cleared", j)
+				}
+			}
+		})
+	}
+}
+
+func Test_Editor_promptDeleteLines(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		line       int
+		keys       []string
+		wantLines  []string
+		wantDirty  bool
+		wantStatus string
+	}{
+		{
+			name:       "when a count is entered it deletes that many lines from the cursor",
+			line:       2,
+			keys:       []string{"\x0b", "2", "\r"},
+			wantLines:  []string{"a", "d"},
+			wantDirty:  true,
+			wantStatus: "Deleted 2 lines",
+		},
+		{
+			name:       "when the count runs past the end it deletes to the end",
+			line:       3,
+			keys:       []string{"\x0b", "9", "\r"},
+			wantLines:  []string{"a", "b"},
+			wantDirty:  true,
+			wantStatus: "Deleted 2 lines",
+		},
+		{
+			name:       "when the count is invalid it deletes nothing",
+			line:       1,
+			keys:       []string{"\x0b", "x", "\r"},
+			wantLines:  []string{"a", "b", "c", "d"},
+			wantStatus: "Invalid line count: x",
+		},
+		{
+			name:      "when the prompt is cancelled it deletes nothing",
+			line:      1,
+			keys:      []string{"\x0b", "2", "\x1b"},
+			wantLines: []string{"a", "b", "c", "d"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(keys(tc.keys...), &fakeRenderer{}, WithConfig(Config{Width: 80, Height: 24}))
+			for _, s := range []string{"a", "b", "c", "d"} {
+				e.lines = append(e.lines, NewLine(s))
+			}
+			e.cursor.line, e.cursor.col = tc.line, 2
+			for e.processKeypress() {
+			}
+
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+			if e.dirty != tc.wantDirty {
+				t.Errorf("expected dirty %t, got %t", tc.wantDirty, e.dirty)
+			}
+			if e.statusMsg != tc.wantStatus {
+				t.Errorf("expected status %q, got %q", tc.wantStatus, e.statusMsg)
+			}
+		})
+	}
+}
+
+func Test_Editor_newLine_doesNotShareRunes(t *testing.T) {
+	t.Parallel()
+
+	e := New(nil, nil)
+	e.lines = []*Line{newLineFromString("hello world")}
+	e.cursor.line, e.cursor.col = 1, 6
+	e.newLine()
+	if len(e.lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(e.lines))
+	}
+
+	// Releasing the first line and reusing its runes must not affect the
+	// second, as it would if the second were a reslice of the first.
+	e.deleteLines(0, 1)
+	for i := 0; i < 4; i++ {
+		newLine().insertRunesAt([]rune("xxxxxxxxxxxx"), 0)
+	}
+	if got := e.lines[0].String(); got != " world" {
+		t.Errorf("expected %q, got %q", " world", got)
+	}
+}
+
+func Benchmark_Editor_deleteLines(b *testing.B) {
+	const (
+		nLines   = 20000
+		nDeleted = 10000
+	)
+	newEditor := func() *Editor {
+		e := New(nil, nil)
+		for i := 0; i < nLines; i++ {
+			e.lines = append(e.lines, NewLine("line"))
+		}
+		return e
+	}
+
+	b.Run("one at a time", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			b.StopTimer()
+			e := newEditor()
+			b.StartTimer()
+			for i := 0; i < nDeleted; i++ {
+				e.deleteLines(0, 1)
+			}
+		}
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			b.StopTimer()
+			e := newEditor()
+			b.StartTimer()
+			e.deleteLines(0, nDeleted)
+		}
+	})
+}
+
+func Test_Editor_toggleSoftWrap(t *testing.T) {
+	t.Parallel()
+
+	const altW = "\x1bw"
+	e := NewHeadless(strings.Repeat("a", 100)+"\n",
+		WithKeyReader(Keys([]byte(altW), []byte(altW))), WithConfig(Config{Width: 40, Height: 12}))
+	e.cursor.col = 90
+
+	testCases := []struct {
+		softWrap      bool
+		wantX, wantY  int
+		wantColOffset int
+	}{
+		{softWrap: false, wantX: 40, wantY: 1, wantColOffset: 50},
+		{softWrap: true, wantX: 10, wantY: 3, wantColOffset: 0},
+		{softWrap: false, wantX: 40, wantY: 1, wantColOffset: 50},
+	}
+	for i, tc := range testCases {
+		if i > 0 && !e.processKeypress() {
+			t.Fatalf("toggle %d: unexpected exit", i)
+		}
+		e.render()
+
+		if e.config.SoftWrap != tc.softWrap {
+			t.Errorf("toggle %d: expected soft wrap %t, got %t", i, tc.softWrap, e.config.SoftWrap)
+		}
+		if e.cursor.line != 1 || e.cursor.col != 90 {
+			t.Errorf("toggle %d: expected cursor at 1:90, got %d:%d", i, e.cursor.line, e.cursor.col)
+		}
+		if x, y := e.cursor.X(), e.cursor.Y(); x != tc.wantX || y != tc.wantY {
+			t.Errorf("toggle %d: expected the cursor on screen at %d,%d, got %d,%d", i, tc.wantX, tc.wantY, x, y)
+		}
+		if e.cursor.colOffset != tc.wantColOffset {
+			t.Errorf("toggle %d: expected column offset %d, got %d", i, tc.wantColOffset, e.cursor.colOffset)
+		}
+	}
+}
+
+func Test_Editor_processKeypress_scrollView(t *testing.T) {
+	t.Parallel()
+
+	// Scroll down three lines with Ctrl-Down and Alt-E, pushing the cursor
+	// from line 1 to line 4, then back up one line with Ctrl-Y, which leaves
+	// the cursor where it is.
+	kr := Keys([]byte("\x1b[1;5B"), []byte("\x1b[1;5B"), []byte("\x1be"), []byte("\x19"))
+	e := NewHeadless("line 1\nline 2\nline 3\nshort\nline 5\nline 6\n",
+		WithKeyReader(kr), WithConfig(Config{Width: 80, Height: 5}))
+	e.cursor.col = 7
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.cursor.lineOffset != 2 {
+		t.Errorf("expected line offset 2, got %d", e.cursor.lineOffset)
+	}
+	// The cursor snaps to the end of the shorter line it was pushed onto.
+	if e.cursor.line != 4 || e.cursor.col != 6 {
+		t.Errorf("expected cursor at 4:6, got %d:%d", e.cursor.line, e.cursor.col)
+	}
+}
@@ -0,0 +1,67 @@
+package editor
+
+import "github.com/angusgmorrison/gila/editorconfig"
+
+// loadEditorConfig returns the .editorconfig settings for the file at path.
+// Invalid .editorconfig files are logged and ignored.
+func (e *Editor) loadEditorConfig(path string) editorconfig.EC {
+	ec, err := editorconfig.Load(path)
+	if err != nil {
+		e.debugf("ignoring .editorconfig: %v\n", err)
+	}
+	return ec
+}
+
+// applyEditorConfig overrides the editor's configuration with the
+// .editorconfig settings of the active buffer.
+func (e *Editor) applyEditorConfig() {
+	e.config = e.configWith(e.editorConfig)
+}
+
+// configWith returns the editor's configuration overridden by the
+// .editorconfig settings ec. Settings that ec doesn't give revert to those the
+// editor was created with.
+func (e *Editor) configWith(ec editorconfig.EC) Config {
+	c := e.config
+	c.TabStop = e.baseConfig.TabStop
+	if ec.TabWidth > 0 {
+		c.TabStop = ec.TabWidth
+	}
+	c.IndentSize = e.baseConfig.IndentSize
+	if ec.IndentSize > 0 {
+		c.IndentSize = ec.IndentSize
+	}
+	switch ec.IndentStyle {
+	case "tab":
+		c.HardTabs = true
+	case "space":
+		c.HardTabs = false
+	default:
+		c.HardTabs = e.baseConfig.HardTabs
+	}
+	switch ec.EndOfLine {
+	case "lf":
+		c.LineEnding = LineEndingLF
+	case "crlf":
+		c.LineEnding = LineEndingCRLF
+	default: // CR line endings aren't supported
+		c.LineEnding = e.baseConfig.LineEnding
+	}
+	switch ec.TrimTrailingWhitespace {
+	case "true":
+		c.TrimTrailingWhitespace = true
+	case "false":
+		c.TrimTrailingWhitespace = false
+	default:
+		c.TrimTrailingWhitespace = e.baseConfig.TrimTrailingWhitespace
+	}
+	switch ec.Charset {
+	case "utf-8-bom":
+		c.PreserveBOM = true
+	case "utf-8":
+		c.PreserveBOM = false
+	default: // other encodings aren't supported
+		c.PreserveBOM = e.baseConfig.PreserveBOM
+	}
+	return c
+}
@@ -0,0 +1,178 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Editor_OpenBuffer_editorConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		".editorconfig": "root = true\n\n[*.go]\nindent_style = tab\ntab_width = 8\nend_of_line = crlf\n",
+		"main.go":       "package main\n",
+		"notes.txt":     "notes\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base := Config{Width: 80, Height: 24, TabStop: 2, IndentSize: 2}
+	e := New(nil, nil, WithConfig(base))
+	if err := e.OpenBuffer(filepath.Join(dir, "main.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := e.OpenBuffer(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first file opened is active.
+	if !e.config.HardTabs || e.config.TabStop != 8 || e.config.IndentSize != 8 ||
+		e.config.LineEnding != LineEndingCRLF {
+		t.Errorf("expected .editorconfig settings to apply to main.go, got %+v", e.config)
+	}
+	if got := e.lines[0].tabWidth(); got != 8 {
+		t.Errorf("expected main.go to be read with a tab width of 8, got %d", got)
+	}
+
+	e.switchBuffer(1)
+	if e.config.HardTabs || e.config.TabStop != 2 || e.config.IndentSize != 2 || e.config.LineEnding != "" {
+		t.Errorf("expected the base configuration for notes.txt, got %+v", e.config)
+	}
+}
+
+func Test_Editor_OpenBuffer_editorConfigFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		".editorconfig": "root = true\n\n[*.bin]\nindent_style = tab\ntab_width = 8\n",
+		"notes.txt":     "notes\n",
+		"data.bin":      "\x00\x01\x02",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base := Config{Width: 80, Height: 24, TabStop: 2, IndentSize: 2}
+	e := New(nil, nil, WithConfig(base))
+	if err := e.OpenBuffer(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := e.OpenBuffer(filepath.Join(dir, "data.bin")); err == nil {
+		t.Fatal("expected an error opening a binary file")
+	}
+
+	if e.config.HardTabs || e.config.TabStop != 2 || e.config.IndentSize != 2 {
+		t.Errorf("expected the base configuration for notes.txt, got %+v", e.config)
+	}
+}
+
+func Test_Editor_save_trimTrailingWhitespace(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("one  \ntwo\t\n\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Type at the end of the first line, then save.
+	e := New(keys("\x1b[F", " ", "\x13"), nil, WithConfig(Config{Width: 80, Height: 24, TrimTrailingWhitespace: true}))
+	if err := e.open(path, nil); err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	for e.processKeypress() {
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "one\ntwo\n\nthree\n"; string(got) != want {
+		t.Errorf("expected file %q, got %q", want, got)
+	}
+	if e.cursor.col != 4 {
+		t.Errorf("expected the cursor to move to the end of the trimmed line, got column %d", e.cursor.col)
+	}
+}
+
+func Test_Editor_save_editorConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		editorConfig string
+		base         Config
+		want         string
+	}{
+		{
+			name:         "when trim_trailing_whitespace is false it overrides the base config",
+			editorConfig: "trim_trailing_whitespace = false\n",
+			base:         Config{TrimTrailingWhitespace: true},
+			want:         "one  \n",
+		},
+		{
+			name:         "when trim_trailing_whitespace is true it overrides the base config",
+			editorConfig: "trim_trailing_whitespace = true\n",
+			want:         "one\n",
+		},
+		{
+			name:         "when trim_trailing_whitespace isn't set the base config applies",
+			editorConfig: "indent_style = tab\n",
+			base:         Config{TrimTrailingWhitespace: true},
+			want:         "one\n",
+		},
+		{
+			name:         "when charset is utf-8-bom it writes a BOM",
+			editorConfig: "charset = utf-8-bom\n",
+			want:         utf8BOM + "one  \n",
+		},
+		{
+			name:         "when charset is utf-8 it overrides the base config's BOM",
+			editorConfig: "charset = utf-8\n",
+			base:         Config{PreserveBOM: true},
+			want:         "one  \n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			ec := "root = true\n\n[*]\n" + tc.editorConfig
+			if err := os.WriteFile(filepath.Join(dir, ".editorconfig"), []byte(ec), 0644); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "file.txt")
+			if err := os.WriteFile(path, []byte("one  \n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			base := tc.base
+			base.Width, base.Height = 80, 24
+			// Edit the document so that it is saved, then save.
+			e := New(keys("x", "\x7f", "\x13"), nil, WithConfig(base))
+			if err := e.OpenBuffer(path); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for e.processKeypress() {
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected file %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
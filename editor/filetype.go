@@ -0,0 +1,52 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// fileTypes maps file extensions to the name of the language or format of the
+// corresponding files.
+var fileTypes = map[string]string{
+	".bash": "bash",
+	".c":    "c",
+	".cpp":  "cpp",
+	".css":  "css",
+	".go":   "go",
+	".h":    "c",
+	".html": "html",
+	".java": "java",
+	".js":   "javascript",
+	".json": "json",
+	".lua":  "lua",
+	".md":   "markdown",
+	".py":   "python",
+	".rb":   "ruby",
+	".rs":   "rust",
+	".sh":   "sh",
+	".sql":  "sql",
+	".toml": "toml",
+	".ts":   "typescript",
+	".txt":  "text",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".zsh":  "zsh",
+}
+
+// fileTypeNames maps the names of files that are conventionally written
+// without an extension to their file types.
+var fileTypeNames = map[string]string{
+	"Dockerfile": "dockerfile",
+	"Makefile":   "make",
+	"go.mod":     "gomod",
+}
+
+// FileType returns the language or format of the named file, as detected from
+// its name or extension, or "" if it is unknown.
+func FileType(filename string) string {
+	base := filepath.Base(filename)
+	if ft, ok := fileTypeNames[base]; ok {
+		return ft
+	}
+	return fileTypes[strings.ToLower(filepath.Ext(base))]
+}
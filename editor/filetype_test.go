@@ -0,0 +1,48 @@
+package editor
+
+import "testing"
+
+func Test_FileType(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		filename string
+		want     string
+	}{
+		{filename: "main.go", want: "go"},
+		{filename: "/src/gila/editor/editor.go", want: "go"},
+		{filename: "README.MD", want: "markdown"},
+		{filename: "config.yml", want: "yaml"},
+		{filename: "Makefile", want: "make"},
+		{filename: "go.mod", want: "gomod"},
+		{filename: "notes.unknown", want: ""},
+		{filename: "LICENSE", want: ""},
+		{filename: defaultFilename, want: ""},
+		{filename: "", want: ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			if got := FileType(tc.filename); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_frame_fileType(t *testing.T) {
+	t.Parallel()
+
+	e := New(nil, nil)
+	if got := e.frame().FileType; got != "" {
+		t.Errorf("expected no file type for an unnamed buffer, got %q", got)
+	}
+	e.filepath = "/tmp/main.go"
+	if got := e.frame().FileType; got != "go" {
+		t.Errorf("expected file type %q, got %q", "go", got)
+	}
+}
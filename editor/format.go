@@ -0,0 +1,70 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Formatter reformats source code.
+type Formatter interface {
+	Format(src []byte) ([]byte, error)
+}
+
+// GofmtFormatter formats Go source code by running gofmt.
+type GofmtFormatter struct{}
+
+var _ Formatter = GofmtFormatter{}
+
+// Format returns src as formatted by gofmt. If gofmt fails, such as when src
+// has syntax errors, the error includes gofmt's standard error.
+func (GofmtFormatter) Format(src []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("gofmt")
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// formatters maps file types to the formatter used for them.
+var formatters = map[string]Formatter{
+	"go": GofmtFormatter{},
+}
+
+// Format replaces the document with its text as formatted by f. The cursor
+// stays as close to its position as the formatted text allows. If f fails, the
+// document is unchanged.
+func (e *Editor) Format(f Formatter) error {
+	out, err := f.Format([]byte(e.String()))
+	if err != nil {
+		return err
+	}
+	return e.replaceText(bytes.NewReader(out))
+}
+
+// format formats the document with the formatter for its file type.
+func (e *Editor) format() {
+	ft := FileType(e.filepath)
+	f, ok := formatters[ft]
+	switch {
+	case !ok && ft == "":
+		e.setStatus("No formatter for this file")
+		return
+	case !ok:
+		e.setStatus("No formatter for %s files", ft)
+		return
+	}
+	if err := e.Format(f); err != nil {
+		e.setStatus("Format failed: %s", err)
+		return
+	}
+	e.setStatus("Formatted %s", e.filename)
+}
@@ -0,0 +1,146 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// formatterFunc adapts a function to the Formatter interface.
+type formatterFunc func(src []byte) ([]byte, error)
+
+func (f formatterFunc) Format(src []byte) ([]byte, error) {
+	return f(src)
+}
+
+func Test_Editor_Format(t *testing.T) {
+	t.Parallel()
+
+	noop := formatterFunc(func(src []byte) ([]byte, error) { return src, nil })
+	upper := formatterFunc(func(src []byte) ([]byte, error) { return bytes.ToUpper(src), nil })
+	firstLine := formatterFunc(func(src []byte) ([]byte, error) {
+		line, _, _ := bytes.Cut(src, []byte("\n"))
+		return append(line, '\n'), nil
+	})
+
+	testCases := []struct {
+		name      string
+		formatter Formatter
+		wantLines []string
+		wantDirty bool
+		wantLine  int
+		wantCol   int
+	}{
+		{
+			name:      "when the formatter changes nothing the document is unmodified",
+			formatter: noop,
+			wantLines: []string{"first line", "second"},
+			wantLine:  2,
+			wantCol:   5,
+		},
+		{
+			name:      "when the formatter changes the text the document is replaced",
+			formatter: upper,
+			wantLines: []string{"FIRST LINE", "SECOND"},
+			wantDirty: true,
+			wantLine:  2,
+			wantCol:   5,
+		},
+		{
+			name:      "when the cursor's line is removed the cursor moves to the closest position",
+			formatter: firstLine,
+			wantLines: []string{"first line"},
+			wantDirty: true,
+			wantLine:  2,
+			wantCol:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := NewHeadless("first line\nsecond\n")
+			e.cursor.line, e.cursor.col = 2, 5
+			if err := e.Format(tc.formatter); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+			if e.dirty != tc.wantDirty {
+				t.Errorf("expected dirty %t, got %t", tc.wantDirty, e.dirty)
+			}
+			if e.cursor.line != tc.wantLine || e.cursor.col != tc.wantCol {
+				t.Errorf("expected cursor at %d:%d, got %d:%d", tc.wantLine, tc.wantCol, e.cursor.line, e.cursor.col)
+			}
+		})
+	}
+}
+
+func Test_Editor_Format_failure(t *testing.T) {
+	t.Parallel()
+
+	failing := formatterFunc(func([]byte) ([]byte, error) { return nil, errors.New("syntax error") })
+	e := NewHeadless("keep\n")
+	if err := e.Format(failing); err == nil {
+		t.Error("expected an error")
+	}
+	if got, want := e.String(), "keep\n"; got != want {
+		t.Errorf("expected document %q, got %q", want, got)
+	}
+	if e.dirty {
+		t.Error("expected document to be unmodified")
+	}
+}
+
+func Test_Editor_processKeypress_format(t *testing.T) {
+	t.Parallel()
+
+	t.Run("when the file is Go source it is formatted with gofmt", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := exec.LookPath("gofmt"); err != nil {
+			t.Skip("gofmt not found")
+		}
+		path := filepath.Join(t.TempDir(), "main.go")
+		if err := os.WriteFile(path, []byte("package main\nfunc main(){}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		e := New(keys("\x1bf"), nil, WithConfig(Config{Width: 80, Height: 24}))
+		if err := e.Run(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := e.String(), "package main\n\nfunc main() {}\n"; got != want {
+			t.Errorf("expected document %q, got %q", want, got)
+		}
+		if want := "Formatted main.go"; e.statusMsg != want {
+			t.Errorf("expected status %q, got %q", want, e.statusMsg)
+		}
+	})
+
+	t.Run("when the file type has no formatter the document is unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "notes.txt")
+		if err := os.WriteFile(path, []byte("some  text\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		e := New(keys("\x1bf"), nil, WithConfig(Config{Width: 80, Height: 24}))
+		if err := e.Run(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := e.String(), "some  text\n"; got != want {
+			t.Errorf("expected document %q, got %q", want, got)
+		}
+		if want := "No formatter for text files"; e.statusMsg != want {
+			t.Errorf("expected status %q, got %q", want, e.statusMsg)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package editor
+
+import "unicode"
+
+// Runes with special roles in grapheme clusters.
+const (
+	zeroWidthJoiner        = '\u200d'
+	emojiModifierFirst     = '\U0001f3fb'
+	emojiModifierLast      = '\U0001f3ff'
+	regionalIndicatorFirst = '\U0001f1e6'
+	regionalIndicatorLast  = '\U0001f1ff'
+	tagFirst, tagLast      = '\U000e0020', '\U000e007f'
+	// emojiPresentation requests that the preceding character be drawn as a
+	// double-width emoji.
+	emojiPresentation = '\ufe0f'
+)
+
+// isGraphemeExtender reports whether r attaches to the preceding rune to form a
+// single user-perceived character, like a combining accent, variation selector
+// or emoji skin tone modifier.
+func isGraphemeExtender(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == zeroWidthJoiner ||
+		(r >= emojiModifierFirst && r <= emojiModifierLast) ||
+		(r >= tagFirst && r <= tagLast)
+}
+
+// isZeroWidth reports whether r occupies no terminal cells of its own because
+// it is drawn as part of the preceding character. Variation selectors are
+// nonspacing marks (Mn).
+func isZeroWidth(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me) ||
+		r == zeroWidthJoiner ||
+		(r >= emojiModifierFirst && r <= emojiModifierLast) ||
+		(r >= tagFirst && r <= tagLast)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicatorFirst && r <= regionalIndicatorLast
+}
+
+// clusterEnd returns the index one past the end of the grapheme cluster that
+// starts at index i of rs. This is a simplification of the Unicode extended
+// grapheme cluster rules that covers combining marks, emoji modifiers and ZWJ
+// sequences, and regional indicator pairs (flags).
+func clusterEnd(rs []rune, i int) int {
+	if i >= len(rs) {
+		return len(rs)
+	}
+	j := i + 1
+	if isRegionalIndicator(rs[i]) && j < len(rs) && isRegionalIndicator(rs[j]) {
+		j++
+	}
+	for j < len(rs) {
+		switch {
+		case isGraphemeExtender(rs[j]):
+			j++
+		case rs[j-1] == zeroWidthJoiner:
+			j++ // the joiner binds the next character to the cluster
+		default:
+			return j
+		}
+	}
+	return j
+}
+
+// clusterStart returns the index of the first rune of the grapheme cluster
+// containing index i of the line. If i is beyond the end of the line, it is
+// returned unchanged.
+func (l *Line) clusterStart(i int) int {
+	rs := l.Runes()
+	if i >= len(rs) {
+		return i
+	}
+	var start int
+	for end := clusterEnd(rs, 0); end <= i; end = clusterEnd(rs, end) {
+		start = end
+	}
+	return start
+}
+
+// nextClusterStart returns the index of the first rune of the grapheme cluster
+// following the one containing index i, or the length of the line if there is
+// none.
+func (l *Line) nextClusterStart(i int) int {
+	rs := l.Runes()
+	if i >= len(rs) {
+		return len(rs)
+	}
+	return clusterEnd(rs, l.clusterStart(i))
+}
+
+// prevClusterStart returns the index of the first rune of the grapheme cluster
+// preceding index i, or 0 if i is at or before the start of the line.
+func (l *Line) prevClusterStart(i int) int {
+	if i <= 0 {
+		return 0
+	}
+	return l.clusterStart(min(i, l.RuneLen()) - 1)
+}
+
+// clusterWidth returns the number of terminal cells occupied by the grapheme
+// cluster rs. A cluster is as wide as its first rune, or two cells for a flag
+// or a character with emoji presentation. Characters joined to the cluster by a
+// zero width joiner are drawn as a single glyph and add no width.
+func clusterWidth(rs []rune) int {
+	if len(rs) == 0 {
+		return 0
+	}
+	if isRegionalIndicator(rs[0]) && len(rs) > 1 && isRegionalIndicator(rs[1]) {
+		return 2
+	}
+	width := RuneWidth(rs[0])
+	for _, r := range rs[1:] {
+		if r == emojiPresentation {
+			return 2
+		}
+	}
+	return width
+}
+
+// RuneWidths returns the number of terminal cells occupied by each rune of the
+// line. The first rune of each grapheme cluster is attributed the width of the
+// whole cluster, and the rest are zero. Tabs extend to the next tab stop.
+func (l *Line) RuneWidths() []int {
+	rs := l.Runes()
+	widths := make([]int, len(rs))
+	tabStop := l.tabWidth()
+	var col int
+	for i := 0; i < len(rs); {
+		end := clusterEnd(rs, i)
+		if rs[i] == '\t' {
+			widths[i] = tabStop - col%tabStop
+		} else {
+			widths[i] = clusterWidth(rs[i:end])
+		}
+		col += widths[i]
+		i = end
+	}
+	return widths
+}
@@ -0,0 +1,207 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+// clusterStarts returns the index of the first rune of each grapheme cluster in
+// s.
+func clusterStarts(s string) []int {
+	rs := []rune(s)
+	var starts []int
+	for i := 0; i < len(rs); i = clusterEnd(rs, i) {
+		starts = append(starts, i)
+	}
+	return starts
+}
+
+func Test_clusterEnd(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		s    string
+		want []int
+	}{
+		{name: "ASCII", s: "abc", want: []int{0, 1, 2}},
+		{name: "combining acute accent", s: "e\u0301x", want: []int{0, 2}},
+		{name: "multiple combining marks", s: "a\u0300\u0323b", want: []int{0, 3}},
+		{name: "emoji with skin tone modifier", s: "\U0001f44d\U0001f3fdx", want: []int{0, 2}},
+		{name: "ZWJ sequence", s: "\U0001f468\u200d\U0001f469\u200d\U0001f467!", want: []int{0, 5}},
+		{name: "emoji with variation selector", s: "\u2764\ufe0f!", want: []int{0, 2}},
+		{name: "flags", s: "\U0001f1ec\U0001f1e7\U0001f1eb\U0001f1f7", want: []int{0, 2}},
+		{name: "odd regional indicator", s: "\U0001f1ec\U0001f1e7\U0001f1eb", want: []int{0, 2}},
+		{name: "leading combining mark", s: "\u0301a", want: []int{0, 1}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := clusterStarts(tc.s); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected cluster starts %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Line_clusterBoundaries(t *testing.T) {
+	t.Parallel()
+
+	// "a", "e" + U+0301 COMBINING ACUTE ACCENT, "b"
+	line := newLineFromString("ae\u0301b")
+	testCases := []struct {
+		name     string
+		i        int
+		wantCur  int
+		wantNext int
+		wantPrev int
+	}{
+		{name: "start of line", i: 0, wantCur: 0, wantNext: 1, wantPrev: 0},
+		{name: "start of cluster", i: 1, wantCur: 1, wantNext: 3, wantPrev: 0},
+		{name: "inside cluster", i: 2, wantCur: 1, wantNext: 3, wantPrev: 1},
+		{name: "after cluster", i: 3, wantCur: 3, wantNext: 4, wantPrev: 1},
+		{name: "end of line", i: 4, wantCur: 4, wantNext: 4, wantPrev: 3},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := line.clusterStart(tc.i); got != tc.wantCur {
+				t.Errorf("clusterStart(%d): expected %d, got %d", tc.i, tc.wantCur, got)
+			}
+			if got := line.nextClusterStart(tc.i); got != tc.wantNext {
+				t.Errorf("nextClusterStart(%d): expected %d, got %d", tc.i, tc.wantNext, got)
+			}
+			if got := line.prevClusterStart(tc.i); got != tc.wantPrev {
+				t.Errorf("prevClusterStart(%d): expected %d, got %d", tc.i, tc.wantPrev, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_graphemeEditing(t *testing.T) {
+	t.Parallel()
+
+	const (
+		left      = "\x1b[D"
+		right     = "\x1b[C"
+		end       = "\x1b[F"
+		home      = "\x1b[H"
+		up        = "\x1b[A"
+		del       = "\x1b[3~"
+		backspace = "\x7f"
+	)
+
+	testCases := []struct {
+		name      string
+		lines     []string
+		keys      *fakeKeyReader
+		wantLines []string
+		wantCol   int
+	}{
+		{
+			name:      "right steps over a combining sequence",
+			lines:     []string{"e\u0301x"},
+			keys:      keys(right),
+			wantLines: []string{"e\u0301x"},
+			wantCol:   3,
+		},
+		{
+			name:      "left steps over a combining sequence",
+			lines:     []string{"xe\u0301"},
+			keys:      keys(end, left),
+			wantLines: []string{"xe\u0301"},
+			wantCol:   2,
+		},
+		{
+			name:      "backspace deletes a whole emoji with modifier",
+			lines:     []string{"a\U0001f44d\U0001f3fd"},
+			keys:      keys(end, backspace),
+			wantLines: []string{"a"},
+			wantCol:   2,
+		},
+		{
+			name:      "delete removes a whole ZWJ sequence",
+			lines:     []string{"\U0001f468\u200d\U0001f469b"},
+			keys:      keys(del),
+			wantLines: []string{"b"},
+			wantCol:   1,
+		},
+		{
+			name:      "moving up snaps to the start of a cluster",
+			lines:     []string{"ae\u0301", "abc"},
+			keys:      keys("\x1b[B", right, right, up),
+			wantLines: []string{"ae\u0301", "abc"},
+			wantCol:   2,
+		},
+		{
+			name:      "ASCII is unaffected",
+			lines:     []string{"abc"},
+			keys:      keys(right, right, backspace, home, del),
+			wantLines: []string{"c"},
+			wantCol:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(tc.keys, nil, WithConfig(Config{Width: 80, Height: 24}))
+			for _, l := range tc.lines {
+				e.lines = append(e.lines, newLineFromString(l))
+			}
+			for e.processKeypress() {
+			}
+
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+			if e.cursor.col != tc.wantCol {
+				t.Errorf("expected cursor col %d, got %d", tc.wantCol, e.cursor.col)
+			}
+		})
+	}
+}
+
+func Test_Line_RuneWidths(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		s    string
+		want []int
+	}{
+		{name: "ASCII", s: "ab", want: []int{1, 1}},
+		{name: "emoji", s: "\U0001f44b!", want: []int{2, 1}},
+		{name: "emoji with skin tone modifier", s: "\U0001f44b\U0001f3fd!", want: []int{2, 0, 1}},
+		{name: "ZWJ sequence", s: "\U0001f468\u200d\U0001f469\u200d\U0001f467!", want: []int{2, 0, 0, 0, 0, 1}},
+		{name: "emoji presentation selector", s: "\u2764\ufe0f!", want: []int{2, 0, 1}},
+		{name: "flag", s: "\U0001f1ec\U0001f1e7!", want: []int{2, 0, 1}},
+		{name: "combining mark", s: "e\u0301", want: []int{1, 0}},
+		{name: "tab at the start of a tab stop", s: "\tx", want: []int{4, 1}},
+		{name: "tab within a tab stop", s: "ab\tx", want: []int{1, 1, 2, 1}},
+		{name: "tab after a wide character", s: "\u4e16\t", want: []int{2, 2}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := NewLine(tc.s).RuneWidths(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected widths %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package editor
+
+import "strings"
+
+// Default screen dimensions of a headless editor, which determine how far
+// paging keys move the cursor.
+const (
+	headlessWidth  = 80
+	headlessHeight = 24
+)
+
+// NewHeadless returns an editor containing content that draws nothing. Unless
+// a KeyReader is provided with WithKeyReader, it reads no keys. This allows
+// keypresses to be applied to a document without a terminal, for tests and
+// batch processing:
+//
+//	e := NewHeadless("hello", WithKeyReader(Keys([]byte("x"))))
+//	err := e.Run()
+//	e.Buffer() // "xhello\n"
+//
+// The document is unnamed, so saving prompts for a filename.
+func NewHeadless(content string, opts ...EditorOption) *Editor {
+	opts = append([]EditorOption{
+		WithConfig(Config{Width: headlessWidth, Height: headlessHeight}),
+	}, opts...)
+	e := New(Keys(), NullRenderer{}, opts...)
+	// Reading from a string can't fail.
+	_ = e.read(strings.NewReader(content))
+	e.snapshot()
+	return e
+}
+
+// Buffer returns the document as it would be saved. It is an alias for String.
+func (e *Editor) Buffer() string {
+	return e.String()
+}
+
+// HeadlessKeyReader is a KeyReader that returns a fixed sequence of
+// keypresses, followed by EOF.
+type HeadlessKeyReader struct {
+	keys [][]byte
+	idx  int
+}
+
+var _ KeyReader = (*HeadlessKeyReader)(nil)
+
+// Keys returns a HeadlessKeyReader that reads each of keys in turn. Each key
+// is the raw input for a single keypress, such as "a", "\x13" (Ctrl-S) or
+// "\x1b[A" (up arrow).
+func Keys(keys ...[]byte) *HeadlessKeyReader {
+	return &HeadlessKeyReader{keys: keys}
+}
+
+// ReadKey returns the next keypress, or an empty keypress, which the editor
+// interprets as EOF, once all keys have been read.
+func (kr *HeadlessKeyReader) ReadKey() ([]byte, error) {
+	if kr.idx >= len(kr.keys) {
+		return nil, nil
+	}
+	key := kr.keys[kr.idx]
+	kr.idx++
+	return key, nil
+}
+
+// NullRenderer is a Renderer that discards all output.
+type NullRenderer struct{}
+
+var _ Renderer = NullRenderer{}
+
+func (NullRenderer) Render(Frame) error {
+	return nil
+}
+
+func (NullRenderer) RenderProgress(int, int, string) error {
+	return nil
+}
+
+func (NullRenderer) Clear() error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package editor
+
+import "testing"
+
+func Test_NewHeadless(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+		keys    []string
+		want    string
+	}{
+		{
+			name:    "when no keys are pressed the buffer is unchanged",
+			content: "hello\nworld\n",
+			want:    "hello\nworld\n",
+		},
+		{
+			name:    "when text is typed it is inserted at the cursor",
+			content: "world\n",
+			keys:    []string{"h", "i", " "},
+			want:    "hi world\n",
+		},
+		{
+			name:    "when the cursor is moved before typing the text is inserted there",
+			content: "one\ntwo\n",
+			keys:    []string{"\x1b[B", "\x1b[F", "!"},
+			want:    "one\ntwo!\n",
+		},
+		{
+			name:    "when an unbound modified arrow is pressed it acts as the plain arrow",
+			content: "ab\n",
+			keys:    []string{"\x1b[1;5C", "!"},
+			want:    "a!b\n",
+		},
+		{
+			name:    "when enter and backspace are pressed lines are split and joined",
+			content: "ab\ncd\n",
+			keys:    []string{"\x1b[C", "\r", "\x1b[B", "\x7f"},
+			want:    "a\nbcd\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var keys [][]byte
+			for _, k := range tc.keys {
+				keys = append(keys, []byte(k))
+			}
+			e := NewHeadless(tc.content, WithKeyReader(Keys(keys...)))
+			if err := e.Run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := e.Buffer(); got != tc.want {
+				t.Errorf("expected buffer %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_NewHeadless_readOnly(t *testing.T) {
+	t.Parallel()
+
+	const content = "one\ntwo\n"
+	keys := Keys([]byte("\x1b[B"), []byte("x"), []byte("\r"), []byte("\x7f"), []byte("\x1b[3~"))
+	e := NewHeadless(content, WithConfig(Config{Width: 80, Height: 24, ReadOnly: true}), WithKeyReader(keys))
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := e.Buffer(); got != content {
+		t.Errorf("expected buffer %q, got %q", content, got)
+	}
+	if e.cursor.line != 2 {
+		t.Errorf("expected navigation to move the cursor to line 2, got line %d", e.cursor.line)
+	}
+}
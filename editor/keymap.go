@@ -0,0 +1,401 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Command names an editor action that a key can be bound to.
+type Command string
+
+const (
+	CmdSave         Command = "save"
+	CmdQuit         Command = "quit"
+	CmdRefresh      Command = "refresh"
+	CmdIndent       Command = "indent"
+	CmdDedent       Command = "dedent"
+	CmdComment      Command = "comment"
+	CmdStripANSI    Command = "strip-ansi"
+	CmdPipe         Command = "pipe"
+	CmdFormat       Command = "format"
+	CmdMacroRecord  Command = "macro-record"
+	CmdMacroPlay    Command = "macro-play"
+	CmdWordCount    Command = "word-count"
+	CmdNextChange   Command = "next-change"
+	CmdPrevChange   Command = "prev-change"
+	CmdReadOnly     Command = "read-only"
+	CmdBookmark     Command = "bookmark"
+	CmdNextBookmark Command = "next-bookmark"
+	CmdPrevBookmark Command = "prev-bookmark"
+	CmdNextBuffer   Command = "next-buffer"
+	CmdPrevBuffer   Command = "prev-buffer"
+	CmdUp           Command = "up"
+	CmdDown         Command = "down"
+	CmdLeft         Command = "left"
+	CmdRight        Command = "right"
+	CmdHome         Command = "home"
+	CmdEnd          Command = "end"
+	CmdPageUp       Command = "page-up"
+	CmdPageDown     Command = "page-down"
+	CmdCenter       Command = "center"
+	CmdScrollUp     Command = "scroll-up"
+	CmdScrollDown   Command = "scroll-down"
+	CmdBackspace    Command = "backspace"
+	CmdDelete       Command = "delete"
+	CmdNewLine      Command = "new-line"
+	CmdHelp         Command = "help"
+	CmdReload       Command = "reload"
+	CmdDefinition   Command = "go-to-definition"
+	CmdSoftWrap     Command = "soft-wrap"
+	CmdDeleteLines  Command = "delete-lines"
+	CmdReloadConfig Command = "reload-config"
+	CmdSearchAll    Command = "search-all"
+)
+
+// commands is the set of valid commands.
+var commands = map[Command]bool{
+	CmdSave: true, CmdQuit: true, CmdRefresh: true, CmdIndent: true,
+	CmdDedent: true, CmdComment: true, CmdStripANSI: true, CmdPipe: true,
+	CmdFormat: true, CmdMacroRecord: true, CmdMacroPlay: true, CmdWordCount: true,
+	CmdNextChange: true, CmdPrevChange: true, CmdReadOnly: true,
+	CmdBookmark: true, CmdNextBookmark: true, CmdPrevBookmark: true,
+	CmdNextBuffer: true, CmdPrevBuffer: true, CmdUp: true, CmdDown: true,
+	CmdLeft: true, CmdRight: true, CmdHome: true, CmdEnd: true, CmdPageUp: true,
+	CmdPageDown: true, CmdCenter: true, CmdScrollUp: true, CmdScrollDown: true,
+	CmdBackspace: true, CmdDelete: true, CmdNewLine: true, CmdHelp: true,
+	CmdReload: true, CmdDefinition: true, CmdSoftWrap: true, CmdDeleteLines: true,
+	CmdReloadConfig: true, CmdSearchAll: true,
+}
+
+// KeyMap binds keys to commands. Keys are named as in the help overlay: a
+// printable character, or a special key such as "Tab", "Enter", "Backspace",
+// "Delete", "Esc", "Up", "PgDn" or "F5", optionally prefixed by modifiers, as
+// in "Ctrl-S", "Alt-R" and "Ctrl-Right". Names are case-insensitive, except
+// for unmodified printable characters.
+//
+// Not every combination can be distinguished by the terminal: Ctrl-I is Tab,
+// Ctrl-M is Enter and Ctrl-H is Backspace.
+type KeyMap map[string]Command
+
+// DefaultKeyMap returns the editor's default key bindings.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		"Ctrl-S":    CmdSave,
+		"Ctrl-Q":    CmdQuit,
+		"Ctrl-L":    CmdRefresh,
+		"Tab":       CmdIndent,
+		"Shift-Tab": CmdDedent,
+		"Ctrl-/":    CmdComment,
+		"Ctrl-T":    CmdStripANSI,
+		"Alt-|":     CmdPipe,
+		"Alt-F":     CmdFormat,
+		"Ctrl-R":    CmdMacroRecord,
+		"Ctrl-E":    CmdMacroPlay,
+		"Ctrl-W":    CmdWordCount,
+		"Alt-N":     CmdNextChange,
+		"Alt-P":     CmdPrevChange,
+		"Alt-R":     CmdReadOnly,
+		"Ctrl-B":    CmdBookmark,
+		"Ctrl-N":    CmdNextBookmark,
+		"Ctrl-P":    CmdPrevBookmark,
+		"Alt-.":     CmdNextBuffer,
+		"Alt-,":     CmdPrevBuffer,
+		"Alt-S":     CmdSearchAll,
+		"Up":        CmdUp,
+		"Down":      CmdDown,
+		"Left":      CmdLeft,
+		"Right":     CmdRight,
+		"Home":      CmdHome,
+		"End":       CmdEnd,
+		"PgUp":      CmdPageUp,
+		"PgDn":      CmdPageDown,
+		"Alt-Z":     CmdCenter,
+		"Alt-W":     CmdSoftWrap,
+		"Ctrl-Y":    CmdScrollUp,
+		"Alt-E":     CmdScrollDown, // Ctrl-E, its classic partner, plays macros
+		"Ctrl-Up":   CmdScrollUp,
+		"Ctrl-Down": CmdScrollDown,
+		"Backspace": CmdBackspace,
+		"Delete":    CmdDelete,
+		"Ctrl-K":    CmdDeleteLines,
+		"Enter":     CmdNewLine,
+		"F1":        CmdHelp,
+		"F5":        CmdReload,
+		"Alt-C":     CmdReloadConfig,
+		"F12":       CmdDefinition,
+	}
+}
+
+// Bind binds the named key to cmd, replacing any existing binding of the same
+// key, however it is named.
+func (km KeyMap) Bind(key string, cmd Command) error {
+	if !commands[cmd] {
+		return fmt.Errorf("key %q: unknown command %q", key, cmd)
+	}
+	if err := km.Unbind(key); err != nil {
+		return err
+	}
+	km[key] = cmd
+	return nil
+}
+
+// Unbind removes any binding of the named key, however it is named.
+func (km KeyMap) Unbind(key string) error {
+	ev, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	for name := range km {
+		if other, err := parseKey(name); err == nil && other == ev {
+			delete(km, name)
+		}
+	}
+	return nil
+}
+
+// Validate returns an error describing every key name in km that can't be
+// parsed and every unknown command.
+func (km KeyMap) Validate() error {
+	_, err := km.compile()
+	return err
+}
+
+// compile returns the key events bound by km. Entries whose names can't be
+// parsed or whose commands are unknown are skipped and reported in err.
+func (km KeyMap) compile() (map[keyEvent]Command, error) {
+	events := make(map[keyEvent]Command, len(km))
+	var errs []error
+	for _, name := range km.names() {
+		ev, err := parseKey(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !commands[km[name]] {
+			errs = append(errs, fmt.Errorf("key %q: unknown command %q", name, km[name]))
+			continue
+		}
+		events[ev] = km[name]
+	}
+	return events, errors.Join(errs...)
+}
+
+// keyFor returns the name of a key bound to cmd, or "" if there is none. If
+// several keys are bound to cmd, the first in sorted order is returned.
+func (km KeyMap) keyFor(cmd Command) string {
+	if names := km.keysFor(cmd); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// keysFor returns the names of the keys bound to cmd in sorted order.
+func (km KeyMap) keysFor(cmd Command) []string {
+	var names []string
+	for _, name := range km.names() {
+		if km[name] == cmd {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// bindings returns the rows of the help overlay for the keys bound in km.
+// Commands without a key are left out.
+func (km KeyMap) bindings() []Binding {
+	var bindings []Binding
+	for _, entry := range helpEntries {
+		keys := km.helpKeys(entry)
+		if keys == "" {
+			continue
+		}
+		bindings = append(bindings, Binding{
+			Keys:        keys + entry.suffix,
+			Description: entry.description,
+		})
+	}
+	return bindings
+}
+
+// helpKeys names the keys of a help overlay row, or returns "" if none of its
+// commands has a key. A row for a single command lists every key bound to it,
+// while a row for several commands names one key for each, as in "Alt-N/P".
+func (km KeyMap) helpKeys(entry helpEntry) string {
+	if len(entry.cmds) == 1 {
+		return strings.Join(km.keysFor(entry.cmds[0]), ", ")
+	}
+
+	defaults := DefaultKeyMap()
+	var names []string
+	isDefault := true
+	for _, cmd := range entry.cmds {
+		if name := km.keyFor(cmd); name != "" {
+			names = append(names, name)
+			isDefault = isDefault && name == defaults.keyFor(cmd)
+		} else {
+			isDefault = false
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	if isDefault && entry.label != "" {
+		return entry.label
+	}
+	return joinKeys(names)
+}
+
+// joinKeys joins key names with slashes, naming modifiers they share only
+// once, as in "Alt-N/P".
+func joinKeys(names []string) string {
+	prefix := modifierPrefix(names[0])
+	for _, name := range names[1:] {
+		if modifierPrefix(name) != prefix {
+			return strings.Join(names, "/")
+		}
+	}
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = name[len(prefix):]
+	}
+	return prefix + strings.Join(keys, "/")
+}
+
+// modifierPrefix returns the modifiers that prefix a key name, such as "Ctrl-"
+// in "Ctrl-S", or "" if it has none. The last character is always part of the
+// key, so that the "-" in "Alt--" is the key.
+func modifierPrefix(name string) string {
+	return name[:strings.LastIndex(name[:len(name)-1], "-")+1]
+}
+
+// helpHint returns the status message shown when the editor starts, which
+// names the keys bound to help, save and quit.
+func (km KeyMap) helpHint() string {
+	hints := []struct {
+		cmd   Command
+		label string
+	}{
+		{CmdHelp, "key bindings"},
+		{CmdSave, "save"},
+		{CmdQuit, "quit"},
+	}
+	var parts []string
+	for _, hint := range hints {
+		if name := km.keyFor(hint.cmd); name != "" {
+			parts = append(parts, name+" = "+hint.label)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Help: " + strings.Join(parts, " | ")
+}
+
+// names returns the key names of km in sorted order.
+func (km KeyMap) names() []string {
+	names := make([]string, 0, len(km))
+	for name := range km {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// specialKeys maps the lowercase names of keys that don't produce a printable
+// character to keys.
+var specialKeys = map[string]keynum{
+	"tab":       chordIndent,
+	"shift-tab": keyShiftTab,
+	"enter":     keyLineFeed,
+	"backspace": keyBackspace,
+	"delete":    keyDel,
+	"del":       keyDel,
+	"esc":       keyEsc,
+	"up":        keyUp,
+	"down":      keyDown,
+	"left":      keyLeft,
+	"right":     keyRight,
+	"home":      keyHome,
+	"end":       keyEnd,
+	"pgup":      keyPageUp,
+	"pgdn":      keyPageDown,
+	"f1":        keyF1,
+	"f5":        keyF5,
+	"f12":       keyF12,
+}
+
+// modifiable reports whether the terminal reports modifiers held with key.
+func modifiable(key keynum) bool {
+	switch key {
+	case keyUp, keyDown, keyLeft, keyRight, keyHome, keyEnd, keyPageUp, keyPageDown, keyDel, keyF5, keyF12:
+		return true
+	}
+	return false
+}
+
+// parseKey returns the key event that the terminal produces for the named key.
+// See KeyMap for the format of name.
+func parseKey(name string) (keyEvent, error) {
+	if key, ok := specialKeys[strings.ToLower(name)]; ok {
+		return keyEvent{key: key}, nil
+	}
+
+	var mods modifier
+	base := name
+	for {
+		lower := strings.ToLower(base)
+		switch {
+		case strings.HasPrefix(lower, "ctrl-") && len(base) > len("ctrl-"):
+			mods |= modCtrl
+			base = base[len("ctrl-"):]
+		case strings.HasPrefix(lower, "alt-") && len(base) > len("alt-"):
+			mods |= modAlt
+			base = base[len("alt-"):]
+		case strings.HasPrefix(lower, "shift-") && len(base) > len("shift-"):
+			mods |= modShift
+			base = base[len("shift-"):]
+		default:
+			return keyWithModifiers(name, base, mods)
+		}
+	}
+}
+
+// keyWithModifiers returns the key event for the key named base pressed with
+// mods. name is the full key name, for use in errors.
+func keyWithModifiers(name, base string, mods modifier) (keyEvent, error) {
+	if key, ok := specialKeys[strings.ToLower(base)]; ok {
+		if mods != 0 && !modifiable(key) {
+			return keyEvent{}, fmt.Errorf("key %q: modifiers can't be detected with %s", name, base)
+		}
+		return keyEvent{key: key, mods: mods}, nil
+	}
+
+	r, size := utf8.DecodeRuneInString(base)
+	if base == "" || size != len(base) || !unicode.IsPrint(r) {
+		return keyEvent{}, fmt.Errorf("key %q: unknown key %q", name, base)
+	}
+	switch mods {
+	case 0:
+		return keyEvent{key: keynum(r)}, nil
+	case modCtrl:
+		// Terminals send Ctrl-/ as Ctrl-_.
+		if r == '/' {
+			r = '_'
+		}
+		r = unicode.ToLower(r)
+		if (r < 'a' || r > 'z') && !strings.ContainsRune("[\\]^_", r) {
+			return keyEvent{}, fmt.Errorf("key %q: Ctrl can only be combined with letters and [\\]^_/", name)
+		}
+		// Interpret the control character as it would be read, so that
+		// Ctrl-M is Enter, for example.
+		return transliterateKeypress([]byte{byte(r) & ctrlMask}), nil
+	case modAlt:
+		return keyEvent{key: keynum(unicode.ToLower(r)) | altMask}, nil
+	default:
+		return keyEvent{}, fmt.Errorf("key %q: only one of Ctrl or Alt may be combined with a character", name)
+	}
+}
@@ -0,0 +1,375 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseKey(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		key     string
+		want    keyEvent
+		wantErr bool
+	}{
+		{name: "printable", key: "x", want: keyEvent{key: 'x'}},
+		{name: "printable is case-sensitive", key: "X", want: keyEvent{key: 'X'}},
+		{name: "ctrl", key: "Ctrl-S", want: keyEvent{key: 's' & ctrlMask}},
+		{name: "ctrl is case-insensitive", key: "ctrl-s", want: keyEvent{key: 's' & ctrlMask}},
+		{name: "ctrl-slash", key: "Ctrl-/", want: keyEvent{key: '_' & ctrlMask}},
+		{name: "ctrl-m is enter", key: "Ctrl-M", want: keyEvent{key: keyLineFeed}},
+		{name: "ctrl-h is backspace", key: "Ctrl-H", want: keyEvent{key: keyBackspace}},
+		{name: "alt", key: "Alt-R", want: keyEvent{key: altMask | 'r'}},
+		{name: "special", key: "PgDn", want: keyEvent{key: keyPageDown}},
+		{name: "special is case-insensitive", key: "shift-tab", want: keyEvent{key: keyShiftTab}},
+		{name: "modified special", key: "Ctrl-Right", want: keyEvent{key: keyRight, mods: modCtrl}},
+		{name: "several modifiers", key: "Ctrl-Shift-Up", want: keyEvent{key: keyUp, mods: modCtrl | modShift}},
+		{name: "unknown key", key: "Ctrl-Foo", wantErr: true},
+		{name: "unmodifiable special", key: "Ctrl-Enter", wantErr: true},
+		{name: "ctrl with a digit", key: "Ctrl-1", wantErr: true},
+		{name: "ctrl and alt with a character", key: "Ctrl-Alt-X", wantErr: true},
+		{name: "empty", key: "", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseKey(tc.key)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_KeyMap_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		km      KeyMap
+		wantErr bool
+	}{
+		{name: "default", km: DefaultKeyMap()},
+		{name: "invalid key", km: KeyMap{"Ctrl-Foo": CmdQuit}, wantErr: true},
+		{name: "unknown command", km: KeyMap{"Ctrl-X": "explode"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tc.km.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("expected error %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_KeyMap_Bind(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		key     string
+		cmd     Command
+		want    KeyMap
+		wantErr bool
+	}{
+		{
+			name: "when the key is unbound it adds the binding",
+			key:  "Ctrl-X",
+			cmd:  CmdQuit,
+			want: KeyMap{"Ctrl-Q": CmdQuit, "Ctrl-X": CmdQuit},
+		},
+		{
+			name: "when the key is bound under another name it replaces the binding",
+			key:  "ctrl-q",
+			cmd:  CmdSave,
+			want: KeyMap{"ctrl-q": CmdSave},
+		},
+		{
+			name:    "when the key is invalid it returns an error",
+			key:     "Ctrl-Foo",
+			cmd:     CmdQuit,
+			want:    KeyMap{"Ctrl-Q": CmdQuit},
+			wantErr: true,
+		},
+		{
+			name:    "when the command is unknown it returns an error",
+			key:     "Ctrl-X",
+			cmd:     "explode",
+			want:    KeyMap{"Ctrl-Q": CmdQuit},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			km := KeyMap{"Ctrl-Q": CmdQuit}
+			if err := km.Bind(tc.key, tc.cmd); (err != nil) != tc.wantErr {
+				t.Errorf("expected error %t, got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(km, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, km)
+			}
+		})
+	}
+}
+
+func Test_KeyMap_Unbind(t *testing.T) {
+	t.Parallel()
+
+	km := KeyMap{"Ctrl-Q": CmdQuit, "Ctrl-S": CmdSave}
+	if err := km.Unbind("CTRL-q"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (KeyMap{"Ctrl-S": CmdSave}); !reflect.DeepEqual(km, want) {
+		t.Errorf("expected %v, got %v", want, km)
+	}
+	if err := km.Unbind("Ctrl-Foo"); err == nil {
+		t.Errorf("expected an error unbinding an invalid key")
+	}
+}
+
+// findBinding returns the binding described by description, if any.
+func findBinding(bindings []Binding, description string) (Binding, bool) {
+	for _, b := range bindings {
+		if b.Description == description {
+			return b, true
+		}
+	}
+	return Binding{}, false
+}
+
+func Test_KeyMap_bindings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		edit        func(km KeyMap)
+		description string
+		wantKeys    string
+		wantAbsent  bool
+	}{
+		{
+			name:        "when quit is remapped it shows the new key",
+			edit:        func(km KeyMap) { km.Unbind("Ctrl-Q"); km.Bind("Ctrl-X", CmdQuit) },
+			description: "Quit",
+			wantKeys:    "Ctrl-X",
+		},
+		{
+			name:        "when a pair shares modifiers they are named once",
+			description: "Jump to the next/previous unsaved change",
+			wantKeys:    "Alt-N/P",
+		},
+		{
+			name:        "when a pair has different modifiers each key is named in full",
+			edit:        func(km KeyMap) { km.Bind("Ctrl-J", CmdPrevChange); km.Unbind("Alt-P") },
+			description: "Jump to the next/previous unsaved change",
+			wantKeys:    "Alt-N/Ctrl-J",
+		},
+		{
+			name:        "when a command has several keys they are all listed",
+			description: "Scroll down by one line",
+			wantKeys:    "Alt-E, Ctrl-Down",
+		},
+		{
+			name:        "when the arrows are bound by default they are labelled together",
+			description: "Move the cursor",
+			wantKeys:    "Arrows",
+		},
+		{
+			name:        "when an arrow is remapped each key is named",
+			edit:        func(km KeyMap) { km.Unbind("Up"); km.Bind("Ctrl-K", CmdUp) },
+			description: "Move the cursor",
+			wantKeys:    "Ctrl-K/Down/Left/Right",
+		},
+		{
+			name:        "when a suffixed command is remapped the suffix is kept",
+			edit:        func(km KeyMap) { km.Unbind("Ctrl-E"); km.Bind("Alt-E", CmdMacroPlay) },
+			description: "Play a macro",
+			wantKeys:    "Alt-E a-z",
+		},
+		{
+			name:        "when a command is unbound it is left out",
+			edit:        func(km KeyMap) { km.Unbind("F5") },
+			description: "Reload the file from disk",
+			wantAbsent:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			km := DefaultKeyMap()
+			if tc.edit != nil {
+				tc.edit(km)
+			}
+			got, ok := findBinding(km.bindings(), tc.description)
+			if tc.wantAbsent {
+				if ok {
+					t.Errorf("expected no binding for %q, got %+v", tc.description, got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected a binding for %q", tc.description)
+			}
+			if got.Keys != tc.wantKeys {
+				t.Errorf("expected keys %q, got %q", tc.wantKeys, got.Keys)
+			}
+		})
+	}
+}
+
+func Test_Editor_helpHint_keyMap(t *testing.T) {
+	t.Parallel()
+
+	if want, got := "Help: F1 = key bindings | Ctrl-S = save | Ctrl-Q = quit", New(nil, nil).statusMsg; got != want {
+		t.Errorf("expected default status %q, got %q", want, got)
+	}
+
+	km := DefaultKeyMap()
+	km.Unbind("Ctrl-Q")
+	km.Bind("Ctrl-X", CmdQuit)
+	km.Unbind("F1")
+	e := New(nil, nil, WithConfig(Config{KeyMap: km}))
+
+	if want := "Help: Ctrl-S = save | Ctrl-X = quit"; e.statusMsg != want {
+		t.Errorf("expected status %q, got %q", want, e.statusMsg)
+	}
+	if b, ok := findBinding(e.bindings, "Quit"); !ok || b.Keys != "Ctrl-X" {
+		t.Errorf("expected quit to be shown as Ctrl-X, got %+v", b)
+	}
+}
+
+func Test_Editor_processKeypress_keyMap(t *testing.T) {
+	t.Parallel()
+
+	keyMap := DefaultKeyMap()
+	delete(keyMap, "Ctrl-Q")
+	keyMap["Ctrl-X"] = CmdQuit
+	keyMap["Alt-J"] = CmdDown
+	keyMap["Ctrl-Right"] = CmdEnd
+	keyMap["Ctrl-D"] = CmdPageDown
+
+	testCases := []struct {
+		name     string
+		keys     []string
+		wantQuit bool
+		want     []string
+		wantLine int
+		wantCol  int
+	}{
+		{
+			name:     "when a key is rebound to quit it quits",
+			keys:     []string{"\x18"},
+			wantQuit: true,
+			want:     []string{"one", "two"},
+			wantLine: 1,
+			wantCol:  1,
+		},
+		{
+			name:     "when a key's binding is removed it does nothing",
+			keys:     []string{"\x11"},
+			want:     []string{"one", "two"},
+			wantLine: 1,
+			wantCol:  1,
+		},
+		{
+			name:     "when an Alt chord is bound to a movement it moves the cursor",
+			keys:     []string{"\x1bj"},
+			want:     []string{"one", "two"},
+			wantLine: 2,
+			wantCol:  1,
+		},
+		{
+			name:     "when a modified key is bound it takes precedence over the unmodified key",
+			keys:     []string{"\x1b[1;5C"},
+			want:     []string{"one", "two"},
+			wantLine: 1,
+			wantCol:  4,
+		},
+		{
+			name:     "when Ctrl-D is bound it runs the command instead of deleting",
+			keys:     []string{"\x04"},
+			want:     []string{"one", "two"},
+			wantLine: 3,
+			wantCol:  1,
+		},
+		{
+			name:     "when Delete is pressed it deletes forward",
+			keys:     []string{"\x1b[3~"},
+			want:     []string{"ne", "two"},
+			wantLine: 1,
+			wantCol:  1,
+		},
+		{
+			name:     "when a printable key is unbound it is inserted",
+			keys:     []string{"!"},
+			want:     []string{"!one", "two"},
+			wantLine: 1,
+			wantCol:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(keys(tc.keys...), nil, WithConfig(Config{Width: 80, Height: 24, KeyMap: keyMap}))
+			e.lines = []*Line{newLineFromString("one"), newLineFromString("two")}
+			quit := false
+			for range tc.keys {
+				if !e.processKeypress() {
+					quit = true
+					break
+				}
+			}
+
+			if quit != tc.wantQuit {
+				t.Errorf("expected quit %t, got %t", tc.wantQuit, quit)
+			}
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected lines %q, got %q", tc.want, got)
+			}
+			if e.cursor.line != tc.wantLine || e.cursor.col != tc.wantCol {
+				t.Errorf("expected cursor at %d:%d, got %d:%d", tc.wantLine, tc.wantCol, e.cursor.line, e.cursor.col)
+			}
+		})
+	}
+}
+
+func Test_Editor_readOnly_keyMap(t *testing.T) {
+	t.Parallel()
+
+	keyMap := DefaultKeyMap()
+	delete(keyMap, "Alt-R")
+	keyMap["F5"] = CmdReadOnly
+	e := New(keys("x"), nil, WithConfig(Config{Width: 80, Height: 24, ReadOnly: true, KeyMap: keyMap}))
+	e.processKeypress()
+
+	if want := "Read-only mode. F5 to allow editing."; e.statusMsg != want {
+		t.Errorf("expected status %q, got %q", want, e.statusMsg)
+	}
+}
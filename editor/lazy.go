@@ -0,0 +1,110 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// defaultLazyLoadSize is the size in bytes above which files are loaded
+// lazily if Config.LazyLoadSize is zero.
+const defaultLazyLoadSize = 64 << 20
+
+// lazyChunkLines is the number of lines read at once when a line outside the
+// loaded window is needed, such as while searching.
+const lazyChunkLines = 4096
+
+// lazyLines holds the state of a buffer whose file is too large to read into
+// memory. Its lines are indexed on disk, and only a window of them is loaded
+// into buffer.lines; the other entries are nil until they are needed. Lazily
+// loaded buffers are read-only.
+type lazyLines struct {
+	f     *os.File
+	index *lineIndex
+	// The loaded lines are those in [start, end).
+	start, end int
+}
+
+// openLazy indexes the lines of f, the file at path, and replaces the buffer
+// with a lazily loaded view of it. f is kept open until the buffer is
+// replaced. Like open, it leaves the buffer unchanged if the file can't be
+// read.
+func (e *Editor) openLazy(f *os.File, path string, size int64, tabStop int) error {
+	prefix := make([]byte, binarySniffLen)
+	n, err := f.ReadAt(prefix, 0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	prefix = prefix[:n]
+	var bomLen int64
+	if bytes.HasPrefix(prefix, []byte(utf8BOM)) {
+		bomLen = int64(len(utf8BOM))
+	}
+	if bytes.IndexByte(prefix, 0) >= 0 {
+		return fmt.Errorf("read %s: %w", path, ErrBinaryFile)
+	}
+	index, err := newLineIndex(io.NewSectionReader(f, bomLen, size-bomLen), size-bomLen, tabStop)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	e.setDocument(&document{lines: make([]*Line, index.len()), finalNewline: true, bom: bomLen > 0})
+	e.lazy = &lazyLines{f: f, index: index}
+	return nil
+}
+
+// closeLazy closes the file of a lazily loaded buffer, if the buffer is one,
+// and returns it to the normal mode.
+func (e *Editor) closeLazy() {
+	if e.lazy == nil {
+		return
+	}
+	if err := e.lazy.f.Close(); err != nil {
+		e.debugf("close %s: %v\n", e.filepath, err)
+	}
+	e.lazy = nil
+}
+
+// loadAroundCursor loads the lines within two screen heights of the cursor in
+// a lazily loaded buffer, so that the screen can be drawn and the cursor can
+// move by a page without reading from disk.
+func (e *Editor) loadAroundCursor() {
+	if e.lazy == nil {
+		return
+	}
+	margin := 2 * max(1, e.config.Height)
+	e.loadLines(e.cursor.line-1-margin, e.cursor.line+margin)
+}
+
+// lineAt returns the 0-indexed line i. If it is in a lazily loaded buffer and
+// isn't in memory, it is read from disk along with the lines around it.
+func (e *Editor) lineAt(i int) *Line {
+	if e.lines[i] == nil && e.lazy != nil {
+		e.loadLines(i-lazyChunkLines/2, i+lazyChunkLines/2)
+	}
+	return e.lines[i]
+}
+
+// loadLines loads the lines in [start, end) of a lazily loaded buffer,
+// clamped to the bounds of the document, in place of the lines loaded before.
+// Read errors are reported in the status bar, and leave the lines unloaded.
+func (e *Editor) loadLines(start, end int) {
+	lz := e.lazy
+	start, end = max(0, start), min(end, len(e.lines))
+	if start == lz.start && end == lz.end {
+		return
+	}
+	for i, line := range e.lines[lz.start:lz.end] {
+		line.release()
+		e.lines[lz.start+i] = nil
+	}
+	lz.start, lz.end = 0, 0
+	lines, err := lz.index.lines(start, end)
+	if err != nil {
+		e.setStatus("Read failed: %s", err)
+		return
+	}
+	copy(e.lines[start:], lines)
+	lz.start, lz.end = start, end
+}
@@ -0,0 +1,173 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeLines writes a file of n lines, "line 1" to "line n", with needle
+// appended to line 900, and returns its path.
+func writeLines(t *testing.T, n int) string {
+	t.Helper()
+
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %d", i)
+		if i == 900 {
+			b.WriteString(" needle")
+		}
+		b.WriteString("\n")
+	}
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// loadedLines returns the number of lines of e that are in memory.
+func loadedLines(e *Editor) int {
+	var n int
+	for _, line := range e.lines {
+		if line != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func Test_Editor_open_lazy(t *testing.T) {
+	t.Parallel()
+
+	path := writeLines(t, 1000)
+	r := &fakeRenderer{}
+	e := New(nil, r, WithConfig(Config{Width: 80, Height: 7, LazyLoadSize: 100}))
+	if err := e.OpenBuffer(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { e.closeLazy() })
+
+	// The document is read in full from a copy that isn't loaded lazily.
+	full := New(nil, nil)
+	if err := full.open(path, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := lineStrings(full.lines)
+
+	if e.lazy == nil {
+		t.Fatal("expected the file to be loaded lazily")
+	}
+	if len(e.lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d", len(want), len(e.lines))
+	}
+
+	t.Run("it draws the lines on screen", func(t *testing.T) {
+		e.JumpTo(Position{Line: 500, Col: 1})
+		if !e.render() {
+			t.Fatal("render failed")
+		}
+		offset := e.cursor.LineOffset()
+		for i := offset; i < offset+e.config.Height; i++ {
+			if got := r.last.Lines[i].String(); got != want[i] {
+				t.Errorf("expected line %d to be %q, got %q", i+1, want[i], got)
+			}
+		}
+		// Two screens either side of the cursor.
+		if got, max := loadedLines(e), 4*e.config.Height+1; got > max {
+			t.Errorf("expected at most %d lines in memory, got %d", max, got)
+		}
+	})
+
+	t.Run("it finds matches in lines that aren't loaded", func(t *testing.T) {
+		e.JumpTo(Position{Line: 1, Col: 1})
+		e.render()
+		pos, ok := e.Search("needle")
+		if !ok || pos != (Position{Line: 900, Col: 10}) {
+			t.Errorf("expected a match at 900:10, got %+v, %t", pos, ok)
+		}
+		if got := loadedLines(e); got > lazyChunkLines {
+			t.Errorf("expected at most %d lines in memory, got %d", lazyChunkLines, got)
+		}
+	})
+
+	t.Run("it counts the words of every line", func(t *testing.T) {
+		gotWords, gotLines, gotBytes := e.WordCount()
+		wantWords, wantLines, wantBytes := full.WordCount()
+		if gotWords != wantWords || gotLines != wantLines || gotBytes != wantBytes {
+			t.Errorf("expected %d words, %d lines and %d bytes, got %d, %d and %d",
+				wantWords, wantLines, wantBytes, gotWords, gotLines, gotBytes)
+		}
+	})
+
+	t.Run("it can't be edited", func(t *testing.T) {
+		e.r = keys("x", "\r", "\x13")
+		e.JumpTo(Position{Line: 3, Col: 1})
+		for e.processKeypress() {
+		}
+		if got := e.lineAt(2).String(); got != want[2] {
+			t.Errorf("expected line 3 to be unchanged, got %q", got)
+		}
+		if e.dirty {
+			t.Error("expected the buffer not to be dirty")
+		}
+		if want := "Read-only: big.txt is too large to edit"; e.statusMsg != want {
+			t.Errorf("expected status %q, got %q", want, e.statusMsg)
+		}
+	})
+}
+
+func Test_Editor_open_lazyFormat(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		content   string
+		wantLines []string
+		wantErr   error
+	}{
+		{
+			name:      "when the file starts with a BOM it is removed",
+			content:   utf8BOM + "one\r\ntwo\n",
+			wantLines: []string{"one", "two"},
+		},
+		{
+			name:    "when the file is binary it isn't opened",
+			content: "one\x00two\n",
+			wantErr: ErrBinaryFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "file.txt")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			e := New(nil, nil, WithConfig(Config{Width: 80, Height: 24, LazyLoadSize: 1}))
+			err := e.open(path, nil)
+			t.Cleanup(func() { e.closeLazy() })
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				if e.lazy != nil || len(e.lines) != 0 {
+					t.Error("expected the buffer to be unchanged")
+				}
+				return
+			}
+			e.render()
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+		})
+	}
+}
@@ -1,21 +1,62 @@
 package editor
 
 import (
-	"strings"
+	"sync"
+	"unicode"
 	"unicode/utf8"
+
+	"github.com/angusgmorrison/gila/intutil"
 )
 
 const (
-	tabStop                = 4
+	defaultTabStop         = 4
 	lineRunesToPreallocate = 128
 )
 
+// runePool recycles the backing slices of deleted lines. Only slices of
+// exactly lineRunesToPreallocate capacity are pooled, so that a single long
+// line can't pin a large allocation.
+var runePool = sync.Pool{
+	New: func() any {
+		rs := make([]rune, 0, lineRunesToPreallocate)
+		return &rs
+	},
+}
+
+// allocRunes returns an empty rune slice with capacity for at least n runes,
+// taken from runePool if n is small enough.
+func allocRunes(n int) []rune {
+	if n > lineRunesToPreallocate {
+		return make([]rune, 0, n)
+	}
+	return (*runePool.Get().(*[]rune))[:0]
+}
+
+// freeRunes returns rs to runePool. rs must not be used afterwards.
+func freeRunes(rs []rune) {
+	if cap(rs) != lineRunesToPreallocate {
+		return
+	}
+	rs = rs[:0]
+	runePool.Put(&rs)
+}
+
 // Line represents a single line of text.
+//
+// Lines read from a file hold their text as a string until their runes are
+// first needed, so that lines that are never displayed or edited don't
+// require a rune slice of their own.
 // TODO: []rune represents the cleanest way of handling UTF-8-encoded
-// strings, but requires allocations to copy from string when reading
-// input, and to string when writing output. Can this be avoided?
+// strings, but requires allocations to copy to string when writing output.
 type Line struct {
 	runes []rune
+	// text is the content of the line while pending is true, after which
+	// runes holds the content.
+	text    string
+	pending bool
+	// tabStop is the display width of tab stops. If zero, defaultTabStop is
+	// used.
+	tabStop int
 }
 
 // RuneLen returns the length of the line as it appears to the user.
@@ -23,6 +64,9 @@ func (l *Line) RuneLen() int {
 	if l == nil {
 		return 0
 	}
+	if l.pending {
+		return utf8.RuneCountInString(l.text)
+	}
 	return len(l.runes)
 }
 
@@ -31,6 +75,9 @@ func (l *Line) String() string {
 	if l == nil {
 		return ""
 	}
+	if l.pending {
+		return l.text
+	}
 	return string(l.runes)
 }
 
@@ -38,12 +85,127 @@ func (l *Line) Runes() []rune {
 	if l == nil {
 		return nil
 	}
+	l.materialize()
 	return l.runes
 }
 
+// materialize converts a pending line's text to runes.
+func (l *Line) materialize() {
+	if !l.pending {
+		return
+	}
+	l.runes = allocRunes(utf8.RuneCountInString(l.text))
+	for _, r := range l.text {
+		l.runes = append(l.runes, r)
+	}
+	l.text, l.pending = "", false
+}
+
+// DisplayWidth returns the number of terminal cells occupied by the first n
+// runes of the line, measured by grapheme cluster.
+func (l *Line) DisplayWidth(n int) int {
+	n = min(n, l.RuneLen())
+	var width int
+	for _, w := range l.RuneWidths()[:max(n, 0)] {
+		width += w
+	}
+	return width
+}
+
+// IndentLevel returns the number of full tab stops spanned by the line's
+// leading whitespace. Tabs advance to the next tab stop.
+func (l *Line) IndentLevel(tabStop int) int {
+	if tabStop <= 0 {
+		return 0
+	}
+	var width int
+	for _, r := range l.Runes() {
+		if r == '\t' {
+			width += tabStop - width%tabStop
+		} else if unicode.IsSpace(r) {
+			width++
+		} else {
+			break
+		}
+	}
+	return width / tabStop
+}
+
+// TrimLeadingWhitespace returns a copy of the line with its leading whitespace
+// removed. The receiver is not modified.
+func (l *Line) TrimLeadingWhitespace() *Line {
+	return l.clone(l.firstNonSpace())
+}
+
+// IndentBy returns a copy of the line with n*tabStop spaces prepended. The
+// receiver is not modified.
+func (l *Line) IndentBy(n int, tabStop int) *Line {
+	nSpaces := max(0, n*tabStop)
+	runes := make([]rune, nSpaces, nSpaces+l.RuneLen())
+	for i := range runes {
+		runes[i] = ' '
+	}
+	indented := newLineFromRunes(append(runes, l.Runes()...))
+	indented.tabStop = l.tabStop
+	return indented
+}
+
+// WrapPoints returns the indices of the runes that begin each screen row when
+// the line is soft-wrapped to width cells. The first row always begins at 0,
+// and an empty line occupies a single row. Rows break after the last
+// whitespace before the word that would overflow them, so that words aren't
+// split across rows. A word too long to fit on a row by itself is broken at
+// the rune that would overflow the row. If the last row is full, an empty row
+// follows it to hold the cursor at the end of the line.
+func (l *Line) WrapPoints(width int) []int {
+	starts := []int{0}
+	if width <= 0 {
+		return starts
+	}
+	runes, widths := l.Runes(), l.RuneWidths()
+	var rowWidth int
+	// The index of the first rune of the last word on the row that follows
+	// another word, or -1 if there is none.
+	wordStart := -1
+	inWord := false
+	for i, w := range widths {
+		if rowWidth > 0 && rowWidth+w > width {
+			start := i
+			if wordStart > 0 && !unicode.IsSpace(runes[i]) {
+				start = wordStart
+			}
+			starts = append(starts, start)
+			rowWidth = 0
+			for _, w := range widths[start:i] {
+				rowWidth += w
+			}
+			wordStart, inWord = -1, false
+		}
+		rowWidth += w
+		switch {
+		case unicode.IsSpace(runes[i]):
+			inWord = false
+		case !inWord && i > 0 && unicode.IsSpace(runes[i-1]) && i > starts[len(starts)-1]:
+			wordStart, inWord = i, true
+		default:
+			inWord = true
+		}
+	}
+	if rowWidth >= width {
+		starts = append(starts, l.RuneLen())
+	}
+	return starts
+}
+
+// NewLine returns a line containing s, whose tabs are displayed with the
+// default tab stop.
+func NewLine(s string) *Line {
+	return newLineFromString(s)
+}
+
 func newLine() *Line {
 	return &Line{
-		runes: make([]rune, 0, lineRunesToPreallocate),
+		runes: allocRunes(0),
 	}
 }
 
@@ -54,43 +216,81 @@ func newLineFromRunes(r []rune) *Line {
 }
 
 func newLineFromString(s string) *Line {
-	// Replace tabs with spaces to override terminal tab stop setting.
-	tabs := strings.Count(s, "\t")
-	spaces := tabs * (tabStop - 1) // the additional spaces required to replace tabs
-	runes := utf8.RuneCountInString(s)
-	render := make([]rune, 0, runes+spaces)
+	return newLineWithTabStop(s, 0)
+}
+
+// newLineWithTabStop returns a new line from s whose tabs are displayed up to
+// the next multiple of tabStop, overriding the terminal's tab stop setting. If
+// tabStop is zero, the default is used.
+// Tabs are kept in the line so that they are saved unchanged. Invalid UTF-8
+// sequences are replaced by U+FFFD.
+func newLineWithTabStop(s string, tabStop int) *Line {
+	runes := allocRunes(utf8.RuneCountInString(s))
 	for _, r := range s {
-		if r == '\t' {
-			render = append(render, ' ')
-			for len(render)%tabStop != 0 {
-				render = append(render, ' ')
-			}
-		} else {
-			render = append(render, r)
-		}
+		runes = append(runes, r)
 	}
+	return &Line{
+		runes:   runes,
+		tabStop: tabStop,
+	}
+}
 
+// newPendingLine returns a line that holds s as a string until its runes are
+// needed. Tabs are displayed with tabStop, or the default if it is zero. If s
+// is not valid UTF-8, its runes are decoded immediately, so that String
+// returns the text with invalid bytes replaced by U+FFFD.
+func newPendingLine(s string, tabStop int) *Line {
+	if !utf8.ValidString(s) {
+		return newLineWithTabStop(s, tabStop)
+	}
 	return &Line{
-		runes: render,
+		text:    s,
+		pending: true,
+		tabStop: tabStop,
 	}
 }
 
+// tabWidth returns the display width of the line's tab stops.
+func (l *Line) tabWidth() int {
+	if l == nil || l.tabStop <= 0 {
+		return defaultTabStop
+	}
+	return l.tabStop
+}
+
 func (l *Line) insertRuneAt(r rune, i int) {
+	l.materialize()
 	if i < 0 || i > l.RuneLen() {
 		i = l.RuneLen()
 	}
 	l.runes = append(l.runes[:i], append([]rune{r}, l.runes[i:]...)...)
 }
 
+// insertRunesAt inserts rs before the rune at index i. If i is out of bounds,
+// rs is appended to the line.
+func (l *Line) insertRunesAt(rs []rune, i int) {
+	l.materialize()
+	if i < 0 || i > l.RuneLen() {
+		i = l.RuneLen()
+	}
+	tail := l.RuneLen() - i
+	l.runes = append(l.runes, rs...)
+	copy(l.runes[i+len(rs):], l.runes[i:i+tail])
+	copy(l.runes[i:], rs)
+}
+
 func (l *Line) appendRune(r rune) {
+	l.materialize()
 	l.runes = append(l.runes, r)
 }
 
 func (l *Line) clear() {
+	l.materialize()
 	l.runes = l.runes[:0]
 }
 
 func (l *Line) deleteRuneAt(i int) {
+	l.materialize()
 	len := l.RuneLen()
 	if len == 0 {
 		return
@@ -101,7 +301,250 @@ func (l *Line) deleteRuneAt(i int) {
 	l.runes = append(l.runes[:i], l.runes[i+1:]...)
 }
 
+// deleteRunesAt deletes up to n runes starting from index i. If i is out of
+// bounds, it does nothing.
+func (l *Line) deleteRunesAt(i, n int) {
+	l.materialize()
+	if i < 0 || i >= l.RuneLen() || n <= 0 {
+		return
+	}
+	end := min(i+n, l.RuneLen())
+	l.runes = append(l.runes[:i], l.runes[end:]...)
+}
+
+// hasRunesAt reports whether the runes of the line starting from index i begin
+// with rs.
+func (l *Line) hasRunesAt(rs []rune, i int) bool {
+	l.materialize()
+	if i < 0 || i+len(rs) > l.RuneLen() {
+		return false
+	}
+	for j, r := range rs {
+		if l.runes[i+j] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// index returns the index of the first occurrence of rs in the line at or
+// after index i, or -1 if there is none. An empty rs never occurs.
+func (l *Line) index(rs []rune, i int) int {
+	return indexRunes(l.Runes(), rs, i)
+}
+
+// lastIndex returns the index of the last occurrence of rs in the line that
+// starts at or before index i, or -1 if there is none.
+func (l *Line) lastIndex(rs []rune, i int) int {
+	last := -1
+	for j := l.index(rs, 0); j >= 0 && j <= i; j = l.index(rs, j+1) {
+		last = j
+	}
+	return last
+}
+
+// SearchFirst returns the 0-indexed rune position of the first occurrence of
+// pattern in the line. The search is case-sensitive, and an empty pattern is
+// never found.
+func (l *Line) SearchFirst(pattern []rune) (col int, found bool) {
+	if l == nil {
+		return 0, false
+	}
+	if i := l.index(pattern, 0); i >= 0 {
+		return i, true
+	}
+	return 0, false
+}
+
+// SearchFirstFold is like SearchFirst, but ignores case, comparing runes as
+// mapped by unicode.ToLower.
+func (l *Line) SearchFirstFold(pattern []rune) (col int, found bool) {
+	if l == nil {
+		return 0, false
+	}
+	if i := indexRunes(toLower(l.Runes()), toLower(pattern), 0); i >= 0 {
+		return i, true
+	}
+	return 0, false
+}
+
+// SearchAll returns the 0-indexed rune positions of every non-overlapping
+// occurrence of pattern in the line, scanning left to right, as ReplaceAll
+// does. The search is case-sensitive, and an empty pattern is never found.
+func (l *Line) SearchAll(pattern []rune) []int {
+	if l == nil {
+		return nil
+	}
+	var matches []int
+	for i := l.index(pattern, 0); i >= 0; i = l.index(pattern, i+len(pattern)) {
+		matches = append(matches, i)
+	}
+	return matches
+}
+
+// searchThreshold is the length of text above which indexRunes uses
+// Boyer-Moore-Horspool rather than comparing the pattern at every position.
+const searchThreshold = 1000
+
+// indexRunes returns the index of the first occurrence of pattern in s at or
+// after index from, or -1 if there is none. An empty pattern never occurs.
+func indexRunes(s, pattern []rune, from int) int {
+	if len(pattern) == 0 || from < 0 || from+len(pattern) > len(s) {
+		return -1
+	}
+	if len(s)-from > searchThreshold && len(pattern) > 1 {
+		return indexHorspool(s, pattern, from)
+	}
+	for i := from; i+len(pattern) <= len(s); i++ {
+		if equalRunes(s[i:i+len(pattern)], pattern) {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexHorspool is indexRunes using the Boyer-Moore-Horspool algorithm: after
+// a mismatch, the pattern skips ahead according to the last rune of the
+// window, which for long texts and patterns skips most positions entirely.
+func indexHorspool(s, pattern []rune, from int) int {
+	last := len(pattern) - 1
+	shifts := make(map[rune]int, last)
+	for i, r := range pattern[:last] {
+		shifts[r] = last - i
+	}
+	for i := from; i+len(pattern) <= len(s); {
+		if equalRunes(s[i:i+len(pattern)], pattern) {
+			return i
+		}
+		shift, ok := shifts[s[i+last]]
+		if !ok {
+			shift = len(pattern)
+		}
+		i += shift
+	}
+	return -1
+}
+
+func equalRunes(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// toLower returns a copy of rs with each rune mapped by unicode.ToLower.
+func toLower(rs []rune) []rune {
+	lower := make([]rune, len(rs))
+	for i, r := range rs {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+// Replace replaces the first occurrence of search in the line with
+// replacement, reporting whether a replacement was made. An empty search
+// matches nothing.
+func (l *Line) Replace(search, replacement []rune) (changed bool) {
+	i := l.index(search, 0)
+	if i < 0 {
+		return false
+	}
+	tail := len(l.runes) - i - len(search)
+	switch diff := len(replacement) - len(search); {
+	case diff > 0:
+		l.runes = append(l.runes, replacement[:diff]...)
+		copy(l.runes[i+len(replacement):], l.runes[i+len(search):i+len(search)+tail])
+	case diff < 0:
+		copy(l.runes[i+len(replacement):], l.runes[i+len(search):])
+		l.runes = l.runes[:len(l.runes)+diff]
+	}
+	copy(l.runes[i:], replacement)
+	return true
+}
+
+// ReplaceAll replaces every non-overlapping occurrence of search in the line
+// with replacement, scanning left to right, and returns the number of
+// replacements made. Text introduced by a replacement is not searched again. If
+// the replacement differs in length from search, the line is rebuilt in a
+// single pass.
+func (l *Line) ReplaceAll(search, replacement []rune) (count int) {
+	matches := l.SearchAll(search)
+	if len(matches) == 0 {
+		return 0
+	}
+	if len(replacement) == len(search) {
+		for _, i := range matches {
+			copy(l.runes[i:], replacement)
+		}
+		return len(matches)
+	}
+
+	replaced := allocRunes(len(l.runes) + len(matches)*(len(replacement)-len(search)))
+	prev := 0
+	for _, i := range matches {
+		replaced = append(replaced, l.runes[prev:i]...)
+		replaced = append(replaced, replacement...)
+		prev = i + len(search)
+	}
+	replaced = append(replaced, l.runes[prev:]...)
+	freeRunes(l.runes)
+	l.runes = replaced
+	return len(matches)
+}
+
+// firstNonSpace returns the index of the first rune in the line that isn't
+// whitespace. If the line consists only of whitespace, it returns the length of
+// the line.
+func (l *Line) firstNonSpace() int {
+	for i, r := range l.Runes() {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return l.RuneLen()
+}
+
+// clone returns a copy of the line's runes from index i onwards.
+func (l *Line) clone(i int) *Line {
+	runes := l.Runes()[i:]
+	cloned := append(allocRunes(len(runes)), runes...)
+	c := newLineFromRunes(cloned)
+	c.tabStop = l.tabStop
+	return c
+}
+
+// SplitAt returns new lines holding the runes of the line before and from the
+// 0-indexed column col, which is clamped to [0, RuneLen()]. The line itself is
+// unchanged.
+func (l *Line) SplitAt(col int) (left, right *Line) {
+	runes := l.Runes()
+	col = intutil.Clamp(col, 0, len(runes))
+	left = newLineFromRunes(append(allocRunes(col), runes[:col]...))
+	right = newLineFromRunes(append(allocRunes(len(runes)-col), runes[col:]...))
+	if l != nil {
+		left.tabStop, right.tabStop = l.tabStop, l.tabStop
+	}
+	return left, right
+}
+
+// Join returns a new line holding the runes of the line followed by those of
+// other. Neither line is changed. It is the inverse of SplitAt.
+func (l *Line) Join(other *Line) *Line {
+	runes, otherRunes := l.Runes(), other.Runes()
+	joined := newLineFromRunes(append(append(allocRunes(len(runes)+len(otherRunes)), runes...), otherRunes...))
+	if l != nil {
+		joined.tabStop = l.tabStop
+	}
+	return joined
+}
+
 func (l *Line) deleteLastRune() {
+	l.materialize()
 	len := l.RuneLen()
 	if len == 0 {
 		return
@@ -109,6 +552,27 @@ func (l *Line) deleteLastRune() {
 	l.runes = l.runes[:len-1]
 }
 
+// append appends the runes of other to the line. If the line lacks capacity,
+// it grows once to at least double its capacity, so that merging many lines
+// into one reallocates a logarithmic number of times.
 func (l *Line) append(other *Line) {
-	l.runes = append(l.runes, other.runes...)
+	l.materialize()
+	otherRunes := other.Runes()
+	needed := len(l.runes) + len(otherRunes)
+	if needed > cap(l.runes) {
+		grown := make([]rune, len(l.runes), max(needed, 2*cap(l.runes)))
+		copy(grown, l.runes)
+		freeRunes(l.runes)
+		l.runes = grown
+	}
+	l.runes = append(l.runes, otherRunes...)
+}
+
+// release returns the line's backing slice to runePool once the line has been
+// removed from the document. The line is empty afterwards.
+func (l *Line) release() {
+	if !l.pending {
+		freeRunes(l.runes)
+	}
+	*l = Line{tabStop: l.tabStop}
 }
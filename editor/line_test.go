@@ -2,6 +2,7 @@ package editor
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -119,6 +120,113 @@ func Test_Line_Runes(t *testing.T) {
 	}
 }
 
+func Test_Line_DisplayWidth(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		line *Line
+		n    int
+		want int
+	}{
+		{name: "nil line", line: nil, n: 3, want: 0},
+		{name: "ASCII", line: newLineFromString("hello"), n: 3, want: 3},
+		{name: "mixed ASCII and CJK", line: newLineFromString("a世b界"), n: 3, want: 4},
+		{name: "whole line", line: newLineFromString("a世b界"), n: 4, want: 6},
+		{name: "n beyond the end of the line", line: newLineFromString("世界"), n: 10, want: 4},
+		{name: "negative n", line: newLineFromString("世界"), n: -1, want: 0},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.line.DisplayWidth(tc.n); got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Line_WrapPoints(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		line  *Line
+		width int
+		want  []int
+	}{
+		{name: "nil line", line: nil, width: 4, want: []int{0}},
+		{name: "empty line", line: newLineFromString(""), width: 4, want: []int{0}},
+		{name: "shorter than the width", line: newLineFromString("abc"), width: 4, want: []int{0}},
+		{
+			name:  "exactly the width, leaving room for the cursor",
+			line:  newLineFromString("abcd"),
+			width: 4,
+			want:  []int{0, 4},
+		},
+		{name: "wraps onto several rows", line: newLineFromString("abcdefghij"), width: 4, want: []int{0, 4, 8}},
+		{
+			name:  "wide character that doesn't fit moves to the next row",
+			line:  newLineFromString("abc世d"),
+			width: 4,
+			want:  []int{0, 3},
+		},
+		{
+			name:  "combining mark stays on a full row",
+			line:  newLineFromString("abce\u0301f"),
+			width: 4,
+			want:  []int{0, 5},
+		},
+		{name: "zero width", line: newLineFromString("abc"), width: 0, want: []int{0}},
+		{
+			name:  "breaks before the word that overflows the row",
+			line:  newLineFromString("ab cde fg"),
+			width: 5,
+			want:  []int{0, 3, 7},
+		},
+		{
+			name:  "word ending exactly at the width stays on the row",
+			line:  newLineFromString("abc de fgh"),
+			width: 6,
+			want:  []int{0, 6},
+		},
+		{
+			name:  "whitespace that overflows the row starts the next",
+			line:  newLineFromString("abcd efg"),
+			width: 4,
+			want:  []int{0, 4, 8},
+		},
+		{
+			name:  "word longer than the width is broken",
+			line:  newLineFromString("ab cdefghij k"),
+			width: 4,
+			want:  []int{0, 3, 7, 11},
+		},
+		{
+			name:  "wide characters are kept with their word",
+			line:  newLineFromString("a 世界 b"),
+			width: 5,
+			want:  []int{0, 2, 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.line.WrapPoints(tc.width); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
 func Test_newLine(t *testing.T) {
 	t.Parallel()
 
@@ -154,19 +262,19 @@ func Test_newLineFromString(t *testing.T) {
 			},
 		},
 		{
-			name: "when a tab occurs at the start of a tab stop " +
-				"it is replaced by tabStop spaces",
+			name: "when the string contains tabs " +
+				"they are kept",
 			s: "hell\tworld",
 			want: &Line{
-				runes: []rune("hell    world"),
+				runes: []rune("hell\tworld"),
 			},
 		},
 		{
-			name: "when a tab occurs n characters into a tab stop " +
-				"it is replaced by tabStop-n spaces",
-			s: "hello\tworld",
+			name: "when the string contains invalid UTF-8 " +
+				"each invalid byte is replaced by U+FFFD",
+			s: "a\xff\xfeb",
 			want: &Line{
-				runes: []rune("hello   world"),
+				runes: []rune("a\uFFFD\uFFFDb"),
 			},
 		},
 	}
@@ -221,6 +329,89 @@ func Test_newLineFromRunes(t *testing.T) {
 
 }
 
+func Test_newPendingLine(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		s           string
+		wantPending bool
+		wantString  string
+		wantRuneLen int
+	}{
+		{
+			name: "when the string is valid UTF-8 " +
+				"it defers decoding the runes",
+			s:           "h\u00e9llo",
+			wantPending: true,
+			wantString:  "h\u00e9llo",
+			wantRuneLen: 5,
+		},
+		{
+			name: "when the string is not valid UTF-8 " +
+				"it decodes the runes immediately",
+			s:           "a\xffb",
+			wantPending: false,
+			wantString:  "a\ufffdb",
+			wantRuneLen: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := newPendingLine(tc.s, 4)
+			if l.pending != tc.wantPending {
+				t.Errorf("expected pending %t, got %t", tc.wantPending, l.pending)
+			}
+			if got := l.String(); got != tc.wantString {
+				t.Errorf("expected String %q, got %q", tc.wantString, got)
+			}
+			if got := l.RuneLen(); got != tc.wantRuneLen {
+				t.Errorf("expected RuneLen %d, got %d", tc.wantRuneLen, got)
+			}
+			if l.tabStop != 4 {
+				t.Errorf("expected tabStop 4, got %d", l.tabStop)
+			}
+		})
+	}
+
+	t.Run("when a pending line is mutated it matches an eagerly decoded line", func(t *testing.T) {
+		t.Parallel()
+
+		pending := newPendingLine("h\u00e9llo", 0)
+		eager := newLineFromString("h\u00e9llo")
+		pending.insertRuneAt('!', 2)
+		eager.insertRuneAt('!', 2)
+		if pending.pending {
+			t.Errorf("expected line to no longer be pending")
+		}
+		if !reflect.DeepEqual(pending.Runes(), eager.Runes()) {
+			t.Errorf("expected runes %q, got %q", string(eager.Runes()), string(pending.Runes()))
+		}
+		if pending.String() != eager.String() {
+			t.Errorf("expected String %q, got %q", eager.String(), pending.String())
+		}
+	})
+
+	t.Run("when a pending line is appended to another line it is left pending", func(t *testing.T) {
+		t.Parallel()
+
+		l := newLineFromString("ab")
+		other := newPendingLine("cd", 0)
+		l.append(other)
+		if got := l.String(); got != "abcd" {
+			t.Errorf("expected %q, got %q", "abcd", got)
+		}
+		if other.String() != "cd" {
+			t.Errorf("expected other to be unchanged, got %q", other.String())
+		}
+	})
+}
+
 func Test_Line_insertRuneAt(t *testing.T) {
 	t.Parallel()
 
@@ -494,3 +685,1118 @@ func Test_Line_append(t *testing.T) {
 		})
 	}
 }
+
+func Test_Line_insertRunesAt(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		l    *Line
+		rs   []rune
+		i    int
+		want *Line
+	}{
+		{
+			name: "when the line is empty " +
+				"it inserts the runes at the start",
+			l:    newLine(),
+			rs:   []rune("ab"),
+			i:    0,
+			want: newLineFromString("ab"),
+		},
+		{
+			name: "when the line is not empty " +
+				"it inserts the runes at the specified index",
+			l:    newLineFromString("hello"),
+			rs:   []rune("ab"),
+			i:    2,
+			want: newLineFromString("heabllo"),
+		},
+		{
+			name: "when the index is < 0 " +
+				"it inserts the runes at the end",
+			l:    newLineFromString("hello"),
+			rs:   []rune("ab"),
+			i:    -1,
+			want: newLineFromString("helloab"),
+		},
+		{
+			name: "when the index is > len " +
+				"it inserts the runes at the end",
+			l:    newLineFromString("hello"),
+			rs:   []rune("ab"),
+			i:    10,
+			want: newLineFromString("helloab"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.l.insertRunesAt(tc.rs, tc.i)
+
+			if !reflect.DeepEqual(tc.l, tc.want) {
+				t.Errorf("expected %#v, got %#v", tc.want, tc.l)
+			}
+		})
+	}
+}
+
+func Test_Line_deleteRunesAt(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		l    *Line
+		i, n int
+		want *Line
+	}{
+		{
+			name: "when the line is empty " +
+				"it does nothing",
+			l:    newLine(),
+			i:    0,
+			n:    2,
+			want: newLine(),
+		},
+		{
+			name: "when the index is out of bounds " +
+				"it does nothing",
+			l:    newLineFromString("hello"),
+			i:    5,
+			n:    2,
+			want: newLineFromString("hello"),
+		},
+		{
+			name: "when the range is within the line " +
+				"it deletes n runes from the index",
+			l:    newLineFromString("hello"),
+			i:    1,
+			n:    3,
+			want: newLineFromString("ho"),
+		},
+		{
+			name: "when the range extends beyond the end of the line " +
+				"it deletes to the end of the line",
+			l:    newLineFromString("hello"),
+			i:    3,
+			n:    10,
+			want: newLineFromString("hel"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.l.deleteRunesAt(tc.i, tc.n)
+
+			if !reflect.DeepEqual(tc.l, tc.want) {
+				t.Errorf("expected %#v, got %#v", tc.want, tc.l)
+			}
+		})
+	}
+}
+
+func Test_Line_hasRunesAt(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		l    *Line
+		rs   []rune
+		i    int
+		want bool
+	}{
+		{
+			name: "match at start",
+			l:    newLineFromString("// hello"),
+			rs:   []rune("//"),
+			i:    0,
+			want: true,
+		},
+		{
+			name: "match after indent",
+			l:    newLineFromString("  // hello"),
+			rs:   []rune("//"),
+			i:    2,
+			want: true,
+		},
+		{
+			name: "no match",
+			l:    newLineFromString("hello"),
+			rs:   []rune("//"),
+			i:    0,
+			want: false,
+		},
+		{
+			name: "runes extend beyond line",
+			l:    newLineFromString("/"),
+			rs:   []rune("//"),
+			i:    0,
+			want: false,
+		},
+		{
+			name: "negative index",
+			l:    newLineFromString("//"),
+			rs:   []rune("//"),
+			i:    -1,
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.l.hasRunesAt(tc.rs, tc.i); got != tc.want {
+				t.Errorf("expected %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Line_firstNonSpace(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		l    *Line
+		want int
+	}{
+		{
+			name: "empty",
+			l:    newLine(),
+			want: 0,
+		},
+		{
+			name: "no indent",
+			l:    newLineFromString("hello"),
+			want: 0,
+		},
+		{
+			name: "indented",
+			l:    newLineFromString("   hello"),
+			want: 3,
+		},
+		{
+			name: "all spaces",
+			l:    newLineFromString("    "),
+			want: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.l.firstNonSpace(); got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Line_IndentLevel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		l       *Line
+		tabStop int
+		want    int
+	}{
+		{
+			name:    "nil",
+			l:       nil,
+			tabStop: 4,
+			want:    0,
+		},
+		{
+			name:    "no indent",
+			l:       newLineFromString("hello"),
+			tabStop: 4,
+			want:    0,
+		},
+		{
+			name:    "partial tab stop",
+			l:       newLineFromString("   hello"),
+			tabStop: 4,
+			want:    0,
+		},
+		{
+			name:    "two full tab stops",
+			l:       newLineFromString("        hello"),
+			tabStop: 4,
+			want:    2,
+		},
+		{
+			name:    "expanded tab",
+			l:       newLineFromString("\thello"),
+			tabStop: 4,
+			want:    1,
+		},
+		{
+			name:    "mixed spaces and raw tabs",
+			l:       newLineFromRunes([]rune(" \t  \thello")),
+			tabStop: 4,
+			want:    2,
+		},
+		{
+			name:    "mixed Unicode whitespace",
+			l:       newLineFromRunes([]rune("\u00a0 \u2003 hello")),
+			tabStop: 2,
+			want:    2,
+		},
+		{
+			name:    "all whitespace",
+			l:       newLineFromString("      "),
+			tabStop: 2,
+			want:    3,
+		},
+		{
+			name:    "zero tab stop",
+			l:       newLineFromString("    hello"),
+			tabStop: 0,
+			want:    0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.l.IndentLevel(tc.tabStop); got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Line_TrimLeadingWhitespace(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		l    *Line
+		want string
+	}{
+		{
+			name: "empty",
+			l:    newLine(),
+			want: "",
+		},
+		{
+			name: "no indent",
+			l:    newLineFromString("hello"),
+			want: "hello",
+		},
+		{
+			name: "indented",
+			l:    newLineFromString("    hello  "),
+			want: "hello  ",
+		},
+		{
+			name: "mixed whitespace",
+			l:    newLineFromRunes([]rune(" \t hello world")),
+			want: "hello world",
+		},
+		{
+			name: "all whitespace",
+			l:    newLineFromRunes([]rune(" \t  ")),
+			want: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			before := tc.l.String()
+			got := tc.l.TrimLeadingWhitespace()
+			if got.String() != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got.String())
+			}
+			if tc.l.String() != before {
+				t.Errorf("expected receiver to be unchanged, got %q", tc.l.String())
+			}
+		})
+	}
+}
+
+func Test_Line_IndentBy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		l       *Line
+		n       int
+		tabStop int
+		want    string
+	}{
+		{
+			name:    "empty",
+			l:       newLine(),
+			n:       1,
+			tabStop: 4,
+			want:    "    ",
+		},
+		{
+			name:    "no indent",
+			l:       newLineFromString("hello"),
+			n:       2,
+			tabStop: 2,
+			want:    "    hello",
+		},
+		{
+			name:    "mixed whitespace",
+			l:       newLineFromRunes([]rune("\t hello")),
+			n:       1,
+			tabStop: 4,
+			want:    "    \t hello",
+		},
+		{
+			name:    "all whitespace",
+			l:       newLineFromString("  "),
+			n:       1,
+			tabStop: 3,
+			want:    "     ",
+		},
+		{
+			name:    "zero levels",
+			l:       newLineFromString("hello"),
+			n:       0,
+			tabStop: 4,
+			want:    "hello",
+		},
+		{
+			name:    "negative levels",
+			l:       newLineFromString("hello"),
+			n:       -1,
+			tabStop: 4,
+			want:    "hello",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			before := tc.l.String()
+			got := tc.l.IndentBy(tc.n, tc.tabStop)
+			if got.String() != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got.String())
+			}
+			if tc.l.String() != before {
+				t.Errorf("expected receiver to be unchanged, got %q", tc.l.String())
+			}
+		})
+	}
+}
+
+func Benchmark_Line_append(b *testing.B) {
+	// Merge many long lines into one, as when deleting the newlines of a large
+	// paste.
+	const nLines = 1000
+	other := newLineFromString(strings.Repeat("x", 80))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		l := newLine()
+		for i := 0; i < nLines; i++ {
+			l.append(other)
+		}
+	}
+}
+
+func Benchmark_Line_load(b *testing.B) {
+	// Load a large document, as when opening a file, without displaying or
+	// editing most of it.
+	lines := make([]string, 100000)
+	for i := range lines {
+		lines[i] = strings.Repeat("x", 79)
+	}
+
+	b.Run("eager", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			for _, s := range lines {
+				_ = newLineWithTabStop(s, 0)
+			}
+		}
+	})
+
+	b.Run("pending", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			for _, s := range lines {
+				_ = newPendingLine(s, 0)
+			}
+		}
+	})
+}
+
+func Test_Line_release(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		l    *Line
+	}{
+		{
+			name: "when the line holds runes it empties the line",
+			l:    newLineFromString("hello"),
+		},
+		{
+			name: "when the line is pending it empties the line",
+			l:    newPendingLine("hello", 0),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.l.tabStop = 8
+			tc.l.release()
+			if got := tc.l.String(); got != "" {
+				t.Errorf("expected empty line, got %q", got)
+			}
+			if tc.l.tabStop != 8 {
+				t.Errorf("expected tabStop 8, got %d", tc.l.tabStop)
+			}
+		})
+	}
+}
+
+func Benchmark_Line_pool(b *testing.B) {
+	// Open many small files in turn, displaying each, as when switching between
+	// files in the same editor.
+	doc := strings.Repeat("func main() {}\n", 50)
+	e := New(nil, nil)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if err := e.read(strings.NewReader(doc)); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		for _, line := range e.lines {
+			_ = line.Runes()
+		}
+	}
+}
+
+func Test_Line_Replace(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		l           *Line
+		search      string
+		replacement string
+		want        string
+		wantChanged bool
+	}{
+		{
+			name: "when search is empty " +
+				"it does nothing",
+			l:           newLineFromString("hello"),
+			search:      "",
+			replacement: "x",
+			want:        "hello",
+		},
+		{
+			name: "when search doesn't occur " +
+				"it does nothing",
+			l:           newLineFromString("hello"),
+			search:      "world",
+			replacement: "x",
+			want:        "hello",
+		},
+		{
+			name: "when search occurs more than once " +
+				"it replaces only the first occurrence",
+			l:           newLineFromString("foo foo"),
+			search:      "foo",
+			replacement: "bar",
+			want:        "bar foo",
+			wantChanged: true,
+		},
+		{
+			name: "when the replacement is longer than search " +
+				"it shifts the rest of the line right",
+			l:           newLineFromString("a-b"),
+			search:      "-",
+			replacement: " => ",
+			want:        "a => b",
+			wantChanged: true,
+		},
+		{
+			name: "when the replacement is shorter than search " +
+				"it shifts the rest of the line left",
+			l:           newLineFromString("a => b"),
+			search:      " => ",
+			replacement: "-",
+			want:        "a-b",
+			wantChanged: true,
+		},
+		{
+			name: "when the replacement is empty " +
+				"it deletes the occurrence",
+			l:           newLineFromString("hello world"),
+			search:      " world",
+			replacement: "",
+			want:        "hello",
+			wantChanged: true,
+		},
+		{
+			name: "when the line is pending " +
+				"it replaces the occurrence",
+			l:           newPendingLine("h\u00e9llo", 0),
+			search:      "\u00e9",
+			replacement: "e",
+			want:        "hello",
+			wantChanged: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			changed := tc.l.Replace([]rune(tc.search), []rune(tc.replacement))
+			if changed != tc.wantChanged {
+				t.Errorf("expected changed %t, got %t", tc.wantChanged, changed)
+			}
+			if got := tc.l.String(); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Line_ReplaceAll(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		l           *Line
+		search      string
+		replacement string
+		want        string
+		wantCount   int
+	}{
+		{
+			name: "when search is empty " +
+				"it does nothing",
+			l:           newLineFromString("hello"),
+			search:      "",
+			replacement: "x",
+			want:        "hello",
+		},
+		{
+			name: "when search occurs more than once " +
+				"it replaces every occurrence",
+			l:           newLineFromString("foo foo foo"),
+			search:      "foo",
+			replacement: "bar",
+			want:        "bar bar bar",
+			wantCount:   3,
+		},
+		{
+			name: "when occurrences overlap " +
+				"it replaces them left to right without overlap",
+			l:           newLineFromString("aaaaa"),
+			search:      "aa",
+			replacement: "b",
+			want:        "bba",
+			wantCount:   2,
+		},
+		{
+			name: "when the replacement contains search " +
+				"it doesn't search the replacement again",
+			l:           newLineFromString("a.a"),
+			search:      "a",
+			replacement: "aa",
+			want:        "aa.aa",
+			wantCount:   2,
+		},
+		{
+			name: "when the replacement is longer than search " +
+				"it grows the line",
+			l:           newLineFromString("x,y,z"),
+			search:      ",",
+			replacement: ", ",
+			want:        "x, y, z",
+			wantCount:   2,
+		},
+		{
+			name: "when the replacement is shorter than search " +
+				"it shrinks the line",
+			l:           newLineFromString("x, y, z"),
+			search:      ", ",
+			replacement: "",
+			want:        "xyz",
+			wantCount:   2,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			count := tc.l.ReplaceAll([]rune(tc.search), []rune(tc.replacement))
+			if count != tc.wantCount {
+				t.Errorf("expected count %d, got %d", tc.wantCount, count)
+			}
+			if got := tc.l.String(); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Line_SearchFirst(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("abcd", 500) + "needle" + strings.Repeat("x", 10)
+
+	testCases := []struct {
+		name      string
+		l         *Line
+		pattern   string
+		wantCol   int
+		wantFound bool
+	}{
+		{
+			name: "when the line is nil " +
+				"it finds nothing",
+			l:       nil,
+			pattern: "a",
+		},
+		{
+			name: "when the pattern is empty " +
+				"it finds nothing",
+			l:       newLineFromString("hello"),
+			pattern: "",
+		},
+		{
+			name: "when the pattern is longer than the line " +
+				"it finds nothing",
+			l:       newLineFromString("hi"),
+			pattern: "hello",
+		},
+		{
+			name: "when the pattern occurs more than once " +
+				"it returns the first occurrence",
+			l:         newLineFromString("foo bar foo"),
+			pattern:   "foo",
+			wantCol:   0,
+			wantFound: true,
+		},
+		{
+			name: "when the pattern ends the line " +
+				"it finds it",
+			l:         newLineFromString("hello world"),
+			pattern:   "world",
+			wantCol:   6,
+			wantFound: true,
+		},
+		{
+			name: "when the case differs " +
+				"it finds nothing",
+			l:       newLineFromString("Hello"),
+			pattern: "hello",
+		},
+		{
+			name: "when the line contains multi-byte runes " +
+				"it returns a rune position",
+			l:         newLineFromString("héllo wörld"),
+			pattern:   "wörld",
+			wantCol:   6,
+			wantFound: true,
+		},
+		{
+			name: "when the line is pending " +
+				"it searches its text",
+			l:         newPendingLine("pending text", 0),
+			pattern:   "text",
+			wantCol:   8,
+			wantFound: true,
+		},
+		{
+			name: "when the line is longer than the search threshold " +
+				"it finds the pattern",
+			l:         newLineFromString(long),
+			pattern:   "needle",
+			wantCol:   2000,
+			wantFound: true,
+		},
+		{
+			name: "when the line is longer than the search threshold and the pattern is absent " +
+				"it finds nothing",
+			l:       newLineFromString(long),
+			pattern: "needles",
+		},
+		{
+			name: "when the line is longer than the search threshold and the pattern is one rune " +
+				"it finds the pattern",
+			l:         newLineFromString(long),
+			pattern:   "n",
+			wantCol:   2000,
+			wantFound: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			col, found := tc.l.SearchFirst([]rune(tc.pattern))
+			if col != tc.wantCol || found != tc.wantFound {
+				t.Errorf("expected (%d, %t), got (%d, %t)", tc.wantCol, tc.wantFound, col, found)
+			}
+		})
+	}
+}
+
+func Test_Line_SearchFirstFold(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("abcd", 500) + "NeEdLe"
+
+	testCases := []struct {
+		name      string
+		l         *Line
+		pattern   string
+		wantCol   int
+		wantFound bool
+	}{
+		{
+			name: "when the line is nil " +
+				"it finds nothing",
+			l:       nil,
+			pattern: "a",
+		},
+		{
+			name: "when the pattern is empty " +
+				"it finds nothing",
+			l:       newLineFromString("hello"),
+			pattern: "",
+		},
+		{
+			name: "when the case differs " +
+				"it finds the pattern",
+			l:         newLineFromString("say Hello"),
+			pattern:   "hELLO",
+			wantCol:   4,
+			wantFound: true,
+		},
+		{
+			name: "when the line contains non-ASCII letters of a different case " +
+				"it finds the pattern",
+			l:         newLineFromString("ÜBER"),
+			pattern:   "über",
+			wantCol:   0,
+			wantFound: true,
+		},
+		{
+			name: "when the pattern is absent " +
+				"it finds nothing",
+			l:       newLineFromString("hello"),
+			pattern: "world",
+		},
+		{
+			name: "when the line is longer than the search threshold " +
+				"it finds the pattern",
+			l:         newLineFromString(long),
+			pattern:   "needle",
+			wantCol:   2000,
+			wantFound: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			col, found := tc.l.SearchFirstFold([]rune(tc.pattern))
+			if col != tc.wantCol || found != tc.wantFound {
+				t.Errorf("expected (%d, %t), got (%d, %t)", tc.wantCol, tc.wantFound, col, found)
+			}
+		})
+	}
+}
+
+func Test_Line_SearchAll(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("ab", 600)
+	var longWant []int
+	for i := 0; i < 600; i++ {
+		longWant = append(longWant, i*2)
+	}
+
+	testCases := []struct {
+		name    string
+		l       *Line
+		pattern string
+		want    []int
+	}{
+		{
+			name: "when the line is nil " +
+				"it finds nothing",
+			l:       nil,
+			pattern: "a",
+			want:    nil,
+		},
+		{
+			name: "when the pattern is empty " +
+				"it finds nothing",
+			l:       newLineFromString("hello"),
+			pattern: "",
+			want:    nil,
+		},
+		{
+			name: "when the pattern is absent " +
+				"it finds nothing",
+			l:       newLineFromString("hello"),
+			pattern: "x",
+			want:    nil,
+		},
+		{
+			name: "when the pattern occurs more than once " +
+				"it returns every position",
+			l:       newLineFromString("foo bar foo"),
+			pattern: "foo",
+			want:    []int{0, 8},
+		},
+		{
+			name: "when occurrences overlap " +
+				"it returns them left to right without overlap",
+			l:       newLineFromString("aaaaa"),
+			pattern: "aa",
+			want:    []int{0, 2},
+		},
+		{
+			name: "when the case differs " +
+				"it skips the occurrence",
+			l:       newLineFromString("Foo foo"),
+			pattern: "foo",
+			want:    []int{4},
+		},
+		{
+			name: "when the line is longer than the search threshold " +
+				"it returns every position",
+			l:       newLineFromString(long),
+			pattern: "ab",
+			want:    longWant,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.l.SearchAll([]rune(tc.pattern)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Line_SplitAt(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		l         *Line
+		col       int
+		wantLeft  string
+		wantRight string
+	}{
+		{
+			name: "when col is in the middle of the line " +
+				"it splits the line at col",
+			l:         newLineFromString("hello world"),
+			col:       5,
+			wantLeft:  "hello",
+			wantRight: " world",
+		},
+		{
+			name: "when col is 0 " +
+				"it returns an empty line and the original",
+			l:         newLineFromString("hello"),
+			col:       0,
+			wantLeft:  "",
+			wantRight: "hello",
+		},
+		{
+			name: "when col is the length of the line " +
+				"it returns the original and an empty line",
+			l:         newLineFromString("hello"),
+			col:       5,
+			wantLeft:  "hello",
+			wantRight: "",
+		},
+		{
+			name: "when col is negative " +
+				"it is clamped to 0",
+			l:         newLineFromString("hello"),
+			col:       -3,
+			wantLeft:  "",
+			wantRight: "hello",
+		},
+		{
+			name: "when col is beyond the end of the line " +
+				"it is clamped to the length of the line",
+			l:         newLineFromString("hello"),
+			col:       10,
+			wantLeft:  "hello",
+			wantRight: "",
+		},
+		{
+			name: "when the line contains multi-byte runes " +
+				"col is a rune index",
+			l:         newLineFromString("héllo"),
+			col:       2,
+			wantLeft:  "hé",
+			wantRight: "llo",
+		},
+		{
+			name: "when the line is pending " +
+				"it splits its text",
+			l:         newPendingLine("pending", 0),
+			col:       3,
+			wantLeft:  "pen",
+			wantRight: "ding",
+		},
+		{
+			name: "when the line is nil " +
+				"it returns two empty lines",
+			l:         nil,
+			col:       1,
+			wantLeft:  "",
+			wantRight: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			original := tc.l.String()
+			left, right := tc.l.SplitAt(tc.col)
+			if got := left.String(); got != tc.wantLeft {
+				t.Errorf("expected left %q, got %q", tc.wantLeft, got)
+			}
+			if got := right.String(); got != tc.wantRight {
+				t.Errorf("expected right %q, got %q", tc.wantRight, got)
+			}
+			if got := tc.l.String(); got != original {
+				t.Errorf("expected the line to be unchanged as %q, got %q", original, got)
+			}
+			if got := left.Join(right).String(); got != original {
+				t.Errorf("expected the joined halves to be %q, got %q", original, got)
+			}
+		})
+	}
+}
+
+func Test_Line_SplitAt_independent(t *testing.T) {
+	t.Parallel()
+
+	l := newLineWithTabStop("hello world", 8)
+	left, right := l.SplitAt(5)
+	left.appendRune('!')
+	right.insertRuneAt('?', 0)
+	if got, want := l.String(), "hello world"; got != want {
+		t.Errorf("expected editing the halves to leave the line as %q, got %q", want, got)
+	}
+	if left.tabStop != 8 || right.tabStop != 8 {
+		t.Errorf("expected both halves to keep tab stop 8, got %d and %d", left.tabStop, right.tabStop)
+	}
+}
+
+func Test_Line_Join(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		l     *Line
+		other *Line
+		want  string
+	}{
+		{
+			name: "when both lines have text " +
+				"it concatenates them",
+			l:     newLineFromString("hello"),
+			other: newLineFromString(" world"),
+			want:  "hello world",
+		},
+		{
+			name: "when the other line is empty " +
+				"it returns a copy of the line",
+			l:     newLineFromString("hello"),
+			other: newLine(),
+			want:  "hello",
+		},
+		{
+			name: "when the line is empty " +
+				"it returns a copy of the other line",
+			l:     newLine(),
+			other: newLineFromString("world"),
+			want:  "world",
+		},
+		{
+			name: "when either line is nil " +
+				"it is treated as empty",
+			l:     nil,
+			other: newPendingLine("pending", 0),
+			want:  "pending",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lBefore, otherBefore := tc.l.String(), tc.other.String()
+			if got := tc.l.Join(tc.other).String(); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+			if tc.l.String() != lBefore || tc.other.String() != otherBefore {
+				t.Errorf("expected the lines to be unchanged")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// lineIndex records the byte offset at which each line of a document starts,
+// so that ranges of lines can be read on demand instead of loading the whole
+// document into memory. Files larger than Config.LazyLoadSize are displayed
+// through an index.
+type lineIndex struct {
+	r       io.ReaderAt
+	size    int64
+	tabStop int
+	// offsets[i] is the byte offset of the start of line i. A final offset
+	// marks the end of the document.
+	offsets []int64
+}
+
+// newLineIndex indexes the lines of the size bytes of r in a single pass.
+// Lines read from the index display tabs with tabStop, or the default if it is
+// zero.
+func newLineIndex(r io.ReaderAt, size int64, tabStop int) (*lineIndex, error) {
+	ix := &lineIndex{
+		r:       r,
+		size:    size,
+		tabStop: tabStop,
+		offsets: []int64{0},
+	}
+	reader := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	var offset int64
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		offset += int64(len(chunk))
+		if err == nil {
+			ix.offsets = append(ix.offsets, offset)
+			continue
+		}
+		if err == bufio.ErrBufferFull {
+			continue // a line longer than the buffer
+		}
+		if err != io.EOF {
+			return nil, fmt.Errorf("index lines: %w", err)
+		}
+		break
+	}
+	// A final line without a terminator is a line like any other.
+	if offset > ix.offsets[len(ix.offsets)-1] {
+		ix.offsets = append(ix.offsets, offset)
+	}
+	return ix, nil
+}
+
+// len returns the number of lines in the document.
+func (ix *lineIndex) len() int {
+	return len(ix.offsets) - 1
+}
+
+// lines reads the lines in the half-open range [start, end), clamped to the
+// bounds of the document. Line endings, LF or CRLF, are removed.
+func (ix *lineIndex) lines(start, end int) ([]*Line, error) {
+	start, end = max(0, start), min(end, ix.len())
+	if start >= end {
+		return nil, nil
+	}
+	from, to := ix.offsets[start], ix.offsets[end]
+	buf := make([]byte, to-from)
+	if _, err := ix.r.ReadAt(buf, from); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read lines %d to %d: %w", start+1, end, err)
+	}
+
+	lines := make([]*Line, 0, end-start)
+	for i := start; i < end; i++ {
+		text := buf[ix.offsets[i]-from : ix.offsets[i+1]-from]
+		text = bytes.TrimSuffix(text, []byte("\n"))
+		text = bytes.TrimSuffix(text, []byte("\r"))
+		lines = append(lines, newLineWithTabStop(string(text), ix.tabStop))
+	}
+	return lines, nil
+}
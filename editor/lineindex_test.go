@@ -0,0 +1,116 @@
+package editor
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_lineIndex(t *testing.T) {
+	t.Parallel()
+
+	const fixture = "../testdata/lorem_ipsum.txt"
+	f, err := os.Open(fixture)
+	if err != nil {
+		t.Fatalf("open %s: %v", fixture, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("stat %s: %v", fixture, err)
+	}
+	ix, err := newLineIndex(f, info.Size(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The index must agree with opening the file normally.
+	e := New(nil, nil)
+	if err := e.open(fixture, nil); err != nil {
+		t.Fatalf("open %s: %v", fixture, err)
+	}
+	want := lineStrings(e.lines)
+	if ix.len() != len(want) {
+		t.Fatalf("expected %d lines, got %d", len(want), ix.len())
+	}
+
+	testCases := []struct {
+		name       string
+		start, end int
+		want       []string
+	}{
+		{name: "when the range is the whole document it reads every line", start: 0, end: len(want), want: want},
+		{name: "when the range is a viewport it reads only those lines", start: 3, end: 10, want: want[3:10]},
+		{name: "when the range overruns the document it is clamped", start: len(want) - 2, end: len(want) + 5, want: want[len(want)-2:]},
+		{name: "when the range is empty it reads nothing", start: 5, end: 5, want: nil},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lines, err := ix.lines(tc.start, tc.end)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			if lines != nil {
+				got = lineStrings(lines)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected lines %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_lineIndex_lineEndings(t *testing.T) {
+	t.Parallel()
+
+	doc := "one\r\ntwo\n\nlast"
+	ix, err := newLineIndex(strings.NewReader(doc), int64(len(doc)), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines, err := ix.lines(0, ix.len())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"one", "two", "", "last"}
+	if got := lineStrings(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected lines %q, got %q", want, got)
+	}
+}
+
+func Benchmark_lineIndex(b *testing.B) {
+	// A document of 100k lines, of which one screenful is displayed.
+	doc := strings.Repeat(strings.Repeat("x", 79)+"\n", 100000)
+	r := strings.NewReader(doc)
+
+	b.Run("read all lines", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			e := New(nil, nil)
+			if err := e.read(strings.NewReader(doc)); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	b.Run("index and read a viewport", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			ix, err := newLineIndex(r, int64(len(doc)), 0)
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := ix.lines(50000, 50024); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package editor
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+)
+
+// lspTimeout is how long the editor waits for a language server to find a
+// definition.
+const lspTimeout = 5 * time.Second
+
+// Location is a position in a file. Line and Col are 1-indexed, and Col counts
+// runes.
+type Location struct {
+	File      string
+	Line, Col int
+}
+
+// LSPClient queries a language server about the files open in the editor.
+type LSPClient interface {
+	// Definition returns the location of the definition of the symbol at the
+	// 1-indexed line and rune column of file.
+	Definition(ctx context.Context, file string, line, col int) (Location, error)
+}
+
+// SetLSPClient sets the language server client used to find definitions. A
+// nil client disables lookups.
+func (e *Editor) SetLSPClient(c LSPClient) {
+	e.lspClient = c
+}
+
+// GoToDefinition moves the cursor to the definition of the symbol under it,
+// as found by the editor's LSPClient. If the definition is in another file,
+// the file becomes the active buffer, and is opened first if necessary. If the
+// lookup fails or the file can't be opened, GoToDefinition returns an error
+// and the cursor doesn't move.
+func (e *Editor) GoToDefinition(ctx context.Context) error {
+	if e.lspClient == nil {
+		return errors.New("no language server")
+	}
+	if e.filepath == "" {
+		return errors.New("the document has no file")
+	}
+	loc, err := e.lspClient.Definition(ctx, e.filepath, e.cursor.Line(), e.cursor.Col())
+	if err != nil {
+		return err
+	}
+
+	b := e.bufferFor(loc.File)
+	if b == nil {
+		if err := e.OpenBuffer(loc.File); err != nil {
+			return err
+		}
+		b = e.buffers[len(e.buffers)-1]
+	}
+	e.buffer = b
+	e.applyEditorConfig()
+	e.JumpTo(Position{Line: loc.Line, Col: loc.Col})
+	return nil
+}
+
+// bufferFor returns the open buffer of the file at path, or nil if there is
+// none.
+func (e *Editor) bufferFor(path string) *buffer {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	for _, b := range e.buffers {
+		if b.filepath == "" {
+			continue
+		}
+		if bAbs, err := filepath.Abs(b.filepath); err == nil && bAbs == abs {
+			return b
+		}
+	}
+	return nil
+}
+
+// goToDefinition jumps to the definition of the symbol under the cursor,
+// reporting failures in the status message.
+func (e *Editor) goToDefinition() {
+	ctx, cancel := context.WithTimeout(context.Background(), lspTimeout)
+	defer cancel()
+	if err := e.GoToDefinition(ctx); err != nil {
+		e.setStatus("Definition not found: %s", err)
+		return
+	}
+	e.setStatus("%s:%d", e.filename, e.cursor.Line())
+}
@@ -0,0 +1,131 @@
+package editor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeLSPClient returns loc and err from Definition, recording the position
+// it was asked about.
+type fakeLSPClient struct {
+	loc       Location
+	err       error
+	file      string
+	line, col int
+}
+
+func (c *fakeLSPClient) Definition(_ context.Context, file string, line, col int) (Location, error) {
+	c.file, c.line, c.col = file, line, col
+	return c.loc, c.err
+}
+
+func Test_Editor_goToDefinition(t *testing.T) {
+	t.Parallel()
+
+	const f12 = "\x1b[24~"
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.go")
+	other := filepath.Join(dir, "other.go")
+	files := map[string]string{
+		main:  "package main\n\nfunc main() {\n\thelper()\n}\n\nfunc helper() {}\n",
+		other: "package main\n\nvar x = 1\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		client       *fakeLSPClient
+		wantFilename string
+		wantLine     int
+		wantCol      int
+		wantBuffers  int
+		wantStatus   string
+	}{
+		{
+			name:         "when the definition is in the same file the cursor moves to it",
+			client:       &fakeLSPClient{loc: Location{File: main, Line: 7, Col: 6}},
+			wantFilename: "main.go",
+			wantLine:     7,
+			wantCol:      6,
+			wantBuffers:  1,
+			wantStatus:   "main.go:7",
+		},
+		{
+			name:         "when the definition is in another file it is opened and made active",
+			client:       &fakeLSPClient{loc: Location{File: other, Line: 3, Col: 5}},
+			wantFilename: "other.go",
+			wantLine:     3,
+			wantCol:      5,
+			wantBuffers:  2,
+			wantStatus:   "other.go:3",
+		},
+		{
+			name:         "when the lookup fails it reports the error and the cursor stays put",
+			client:       &fakeLSPClient{err: errors.New("no identifier found")},
+			wantFilename: "main.go",
+			wantLine:     4,
+			wantCol:      2,
+			wantBuffers:  1,
+			wantStatus:   "Definition not found: no identifier found",
+		},
+		{
+			name:         "when the file can't be opened it reports the error",
+			client:       &fakeLSPClient{loc: Location{File: filepath.Join(dir, "missing.go"), Line: 1, Col: 1}},
+			wantFilename: "main.go",
+			wantLine:     4,
+			wantCol:      2,
+			wantBuffers:  1,
+			wantStatus:   "Definition not found: open ",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(keys(f12), nil, WithConfig(Config{Width: 80, Height: 24}))
+			if err := e.OpenBuffer(main); err != nil {
+				t.Fatal(err)
+			}
+			e.SetLSPClient(tc.client)
+			e.JumpTo(Position{Line: 4, Col: 2})
+			for e.processKeypress() {
+			}
+
+			if tc.client.file != main || tc.client.line != 4 || tc.client.col != 2 {
+				t.Errorf("expected a lookup at %s:4:2, got %s:%d:%d", main, tc.client.file, tc.client.line, tc.client.col)
+			}
+			if e.filename != tc.wantFilename {
+				t.Errorf("expected %s to be active, got %s", tc.wantFilename, e.filename)
+			}
+			if e.cursor.line != tc.wantLine || e.cursor.col != tc.wantCol {
+				t.Errorf("expected cursor at %d:%d, got %d:%d", tc.wantLine, tc.wantCol, e.cursor.line, e.cursor.col)
+			}
+			if len(e.buffers) != tc.wantBuffers {
+				t.Errorf("expected %d buffers, got %d", tc.wantBuffers, len(e.buffers))
+			}
+			if !strings.HasPrefix(e.statusMsg, tc.wantStatus) {
+				t.Errorf("expected status starting %q, got %q", tc.wantStatus, e.statusMsg)
+			}
+		})
+	}
+}
+
+func Test_Editor_GoToDefinition_noClient(t *testing.T) {
+	t.Parallel()
+
+	e := NewHeadless("text\n")
+	if err := e.GoToDefinition(context.Background()); err == nil {
+		t.Error("expected an error without a language server")
+	}
+}
@@ -0,0 +1,146 @@
+package editor
+
+import "fmt"
+
+// maxMacros is the maximum number of macros a MacroRegistry can hold.
+const maxMacros = 10
+
+var errMacroRegistryFull = fmt.Errorf("macro registry is full (max %d)", maxMacros)
+
+// Macro is a recorded sequence of raw keystrokes.
+type Macro struct {
+	keystrokes [][]byte
+}
+
+// record appends a copy of key to the macro. The copy is required because
+// KeyReaders may reuse the memory backing the keys they return.
+func (m *Macro) record(key []byte) {
+	keyCopy := make([]byte, len(key))
+	copy(keyCopy, key)
+	m.keystrokes = append(m.keystrokes, keyCopy)
+}
+
+// MacroRegistry stores up to maxMacros macros, each named by a lowercase
+// letter.
+type MacroRegistry struct {
+	macros map[rune]*Macro
+}
+
+func newMacroRegistry() *MacroRegistry {
+	return &MacroRegistry{
+		macros: make(map[rune]*Macro, maxMacros),
+	}
+}
+
+// get returns the macro with the given name, if it exists.
+func (mr *MacroRegistry) get(name rune) (*Macro, bool) {
+	m, ok := mr.macros[name]
+	return m, ok
+}
+
+// set stores m under name, replacing any existing macro of the same name. It
+// returns an error if name is not a lowercase letter, or if storing a new name
+// would exceed the registry's capacity.
+func (mr *MacroRegistry) set(name rune, m *Macro) error {
+	if !isMacroName(name) {
+		return fmt.Errorf("invalid macro name %q", name)
+	}
+	if _, ok := mr.macros[name]; !ok && len(mr.macros) >= maxMacros {
+		return errMacroRegistryFull
+	}
+	mr.macros[name] = m
+	return nil
+}
+
+func isMacroName(r rune) bool {
+	return r >= 'a' && r <= 'z'
+}
+
+// toggleMacroRecording starts recording keystrokes into a macro named by the
+// next keypress, or stops recording and saves the macro if recording is
+// already in progress. It returns false if an IO error occurs.
+func (e *Editor) toggleMacroRecording() bool {
+	if e.recording != nil {
+		// Discard the keystroke that stopped the recording.
+		e.recording.keystrokes = e.recording.keystrokes[:len(e.recording.keystrokes)-1]
+		if err := e.macros.set(e.recordingName, e.recording); err != nil {
+			e.setStatus("Macro not saved: %s", err)
+		} else {
+			e.setStatus("Recorded macro %c", e.recordingName)
+		}
+		e.recording = nil
+		return true
+	}
+
+	name, ok := e.promptMacroName("Record macro: press a-z")
+	if !ok {
+		return e.readErr == nil
+	}
+	if _, exists := e.macros.get(name); !exists && len(e.macros.macros) >= maxMacros {
+		e.setStatus("Macro not recorded: %s", errMacroRegistryFull)
+		return true
+	}
+	e.recording = &Macro{}
+	e.recordingName = name
+	e.setStatus("Recording macro %c. %s to stop.", name, e.config.KeyMap.keyFor(CmdMacroRecord))
+	return true
+}
+
+// playMacro replays the macro named by the next keypress. It returns false if
+// a replayed keystroke causes the editor to quit or an IO error occurs.
+//
+// Macros can't play other macros. Playing a macro while recording is refused
+// once its name has been read, so that both keystrokes are recorded, and they
+// are skipped together when the recording is replayed.
+func (e *Editor) playMacro() bool {
+	if e.replaying {
+		if len(e.replayQueue) > 0 {
+			e.replayQueue = e.replayQueue[1:] // the name of the macro
+		}
+		return true
+	}
+
+	name, ok := e.promptMacroName("Play macro: press a-z")
+	if !ok {
+		return e.readErr == nil
+	}
+	if e.recording != nil {
+		e.setStatus("Can't play a macro while recording")
+		return true
+	}
+	m, exists := e.macros.get(name)
+	if !exists {
+		e.setStatus("No macro named %c", name)
+		return true
+	}
+
+	e.replaying = true
+	defer func() { e.replaying = false }()
+	e.replayQueue = append(e.replayQueue[:0], m.keystrokes...)
+	for len(e.replayQueue) > 0 {
+		if !e.processKeypress() {
+			return false
+		}
+	}
+	return true
+}
+
+// promptMacroName displays msg and reads a single keypress naming a macro. It
+// returns false if the keypress is not a valid name or an IO error occurs.
+func (e *Editor) promptMacroName(msg string) (rune, bool) {
+	e.setStatus(msg)
+	if !e.render() {
+		return 0, false
+	}
+	rawKey, err := e.readKey()
+	if err != nil {
+		e.readErr = err
+		return 0, false
+	}
+	name := rune(transliterateKeypress(rawKey).key)
+	if !isMacroName(name) {
+		e.setStatus("Cancelled")
+		return 0, false
+	}
+	return name, true
+}
@@ -0,0 +1,161 @@
+package editor
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// fakeKeyReader returns each of its keys in turn, then empty keys, which the
+// editor interprets as EOF.
+type fakeKeyReader struct {
+	keys [][]byte
+}
+
+func (kr *fakeKeyReader) ReadKey() ([]byte, error) {
+	if len(kr.keys) == 0 {
+		return nil, nil
+	}
+	key := kr.keys[0]
+	kr.keys = kr.keys[1:]
+	return key, nil
+}
+
+// fakeRenderer counts the frames it renders and captures the most recent, as
+// well as the progress reported.
+type fakeRenderer struct {
+	frames   int
+	last     Frame
+	progress []string
+}
+
+func (r *fakeRenderer) Render(frame Frame) error {
+	r.frames++
+	r.last = frame
+	return nil
+}
+
+func (r *fakeRenderer) RenderProgress(done, total int, label string) error {
+	r.progress = append(r.progress, fmt.Sprintf("%s %d/%d", label, done, total))
+	return nil
+}
+
+func (r *fakeRenderer) Clear() error {
+	return nil
+}
+
+func keys(ks ...string) *fakeKeyReader {
+	kr := &fakeKeyReader{}
+	for _, k := range ks {
+		kr.keys = append(kr.keys, []byte(k))
+	}
+	return kr
+}
+
+func Test_Editor_macros(t *testing.T) {
+	t.Parallel()
+
+	const (
+		ctrlR = "\x12"
+		ctrlE = "\x05"
+	)
+
+	testCases := []struct {
+		name      string
+		keys      *fakeKeyReader
+		wantLines []string
+		wantMacro [][]byte
+	}{
+		{
+			name:      "record and replay",
+			keys:      keys(ctrlR, "a", "x", "y", ctrlR, ctrlE, "a", ctrlE, "a"),
+			wantLines: []string{"xyxyxy"},
+			wantMacro: [][]byte{[]byte("x"), []byte("y")},
+		},
+		{
+			name:      "replay with cursor movement",
+			keys:      keys(ctrlR, "b", "-", "\x1b[D", ctrlR, ctrlE, "b"),
+			wantLines: []string{"--"},
+			wantMacro: [][]byte{[]byte("-"), []byte("\x1b[D")},
+		},
+		{
+			name:      "invalid macro name cancels recording",
+			keys:      keys(ctrlR, "1", "x"),
+			wantLines: []string{"x"},
+		},
+		{
+			name:      "playing a macro while recording is refused and skipped on replay",
+			keys:      keys(ctrlR, "a", "x", ctrlE, "b", "y", ctrlR, ctrlE, "a"),
+			wantLines: []string{"xyxy"},
+			wantMacro: [][]byte{[]byte("x"), []byte(ctrlE), []byte("b"), []byte("y")},
+		},
+		{
+			name:      "playing an unknown macro does nothing",
+			keys:      keys(ctrlE, "z", "x"),
+			wantLines: []string{"x"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(tc.keys, &fakeRenderer{}, WithConfig(Config{Width: 80, Height: 24}))
+			for e.processKeypress() {
+			}
+			if e.readErr != nil {
+				t.Fatalf("unexpected read error: %v", e.readErr)
+			}
+
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+			if tc.wantMacro != nil {
+				var got [][]byte
+				for _, m := range e.macros.macros {
+					got = m.keystrokes
+				}
+				if !reflect.DeepEqual(got, tc.wantMacro) {
+					t.Errorf("expected macro %q, got %q", tc.wantMacro, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_Editor_frame_recording(t *testing.T) {
+	t.Parallel()
+
+	e := New(keys("\x12", "a"), &fakeRenderer{}, WithConfig(Config{Width: 80, Height: 24}))
+	e.processKeypress()
+	if !e.frame().Recording {
+		t.Error("expected frame to indicate recording")
+	}
+	e.recording.record([]byte("\x12")) // simulate the stopping keystroke
+	e.toggleMacroRecording()
+	if e.frame().Recording {
+		t.Error("expected frame not to indicate recording")
+	}
+}
+
+func Test_MacroRegistry_set(t *testing.T) {
+	t.Parallel()
+
+	mr := newMacroRegistry()
+	for r := 'a'; r < 'a'+maxMacros; r++ {
+		if err := mr.set(r, &Macro{}); err != nil {
+			t.Fatalf("set %c: unexpected error: %v", r, err)
+		}
+	}
+	if err := mr.set('z', &Macro{}); err != errMacroRegistryFull {
+		t.Errorf("expected errMacroRegistryFull, got %v", err)
+	}
+	if err := mr.set('a', &Macro{}); err != nil {
+		t.Errorf("expected replacing an existing macro to succeed, got %v", err)
+	}
+	if err := mr.set('A', &Macro{}); err == nil {
+		t.Error("expected error for invalid macro name")
+	}
+}
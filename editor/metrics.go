@@ -0,0 +1,40 @@
+package editor
+
+import "time"
+
+// EditorMetrics is a snapshot of runtime statistics for diagnosing the
+// editor's behaviour and performance.
+type EditorMetrics struct {
+	// The number of lines and runes in the active document.
+	LinesCount, TotalRunes int
+	// The number of frames drawn and keypresses processed since the editor
+	// was created.
+	RenderFrames, KeypressesProcessed int
+	// How long the most recent frame took to draw.
+	LastRenderDuration time.Duration
+	// How long the most recent keypress took to process, excluding the time
+	// spent waiting for it.
+	LastKeypressDuration time.Duration
+}
+
+// Metrics returns a snapshot of the editor's runtime statistics.
+func (e *Editor) Metrics() EditorMetrics {
+	m := e.metrics
+	m.LinesCount = len(e.lines)
+	for _, l := range e.lines {
+		m.TotalRunes += l.RuneLen()
+	}
+	return m
+}
+
+// recordKeypress counts a keypress whose processing began at start.
+func (e *Editor) recordKeypress(start time.Time) {
+	e.metrics.KeypressesProcessed++
+	e.metrics.LastKeypressDuration = e.now().Sub(start)
+}
+
+// recordRender counts a frame whose drawing began at start.
+func (e *Editor) recordRender(start time.Time) {
+	e.metrics.RenderFrames++
+	e.metrics.LastRenderDuration = e.now().Sub(start)
+}
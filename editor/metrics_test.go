@@ -0,0 +1,50 @@
+package editor
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Editor_Metrics(t *testing.T) {
+	t.Parallel()
+
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("\r"), []byte("c")}
+	e := NewHeadless("xyz\n", WithKeyReader(Keys(keys...)))
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := e.Metrics()
+	if m.KeypressesProcessed != len(keys) {
+		t.Errorf("expected %d keypresses processed, got %d", len(keys), m.KeypressesProcessed)
+	}
+	// A frame is drawn before each keypress and before reading EOF.
+	if want := len(keys) + 1; m.RenderFrames != want {
+		t.Errorf("expected %d frames rendered, got %d", want, m.RenderFrames)
+	}
+	// "ab" and "cxyz"
+	if m.LinesCount != 2 {
+		t.Errorf("expected 2 lines, got %d", m.LinesCount)
+	}
+	if m.TotalRunes != 6 {
+		t.Errorf("expected 6 runes, got %d", m.TotalRunes)
+	}
+}
+
+func Test_Editor_Metrics_clock(t *testing.T) {
+	t.Parallel()
+
+	// Durations are measured by the editor's clock, which doesn't advance.
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := NewHeadless("xyz\n", WithKeyReader(Keys([]byte("a"))))
+	e.clock = func() time.Time { return now }
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := e.Metrics()
+	if m.LastKeypressDuration != 0 || m.LastRenderDuration != 0 {
+		t.Errorf("expected zero durations from a stopped clock, got keypress %v and render %v",
+			m.LastKeypressDuration, m.LastRenderDuration)
+	}
+}
@@ -0,0 +1,63 @@
+package editor
+
+// EditorOption configures an Editor created by New. Options are applied in
+// order, so later options override earlier ones.
+type EditorOption func(*Editor)
+
+// WithConfig replaces the editor's configuration with config.
+func WithConfig(config Config) EditorOption {
+	return func(e *Editor) {
+		e.config = config
+	}
+}
+
+// WithConfigLoader sets the function that ReloadConfig calls to read the
+// editor's configuration afresh, such as from a config file.
+func WithConfigLoader(load func() (Config, error)) EditorOption {
+	return func(e *Editor) {
+		e.loadConfig = load
+	}
+}
+
+// WithLogger sets the logger that the editor writes debug output to if
+// Config.Debug is set. A nil logger discards output.
+func WithLogger(logger Logger) EditorOption {
+	return func(e *Editor) {
+		e.SetLogger(logger)
+	}
+}
+
+// SetLogger replaces the logger that the editor writes debug output to, such
+// as to capture the output of part of a session. A nil logger discards output.
+// Nothing is logged unless Config.Debug is set. It must not be called while
+// Run is in progress on another goroutine.
+func (e *Editor) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger{}
+	}
+	e.logger = logger
+}
+
+// debugf writes a debug message to the editor's logger if Config.Debug is set,
+// without formatting the message otherwise.
+func (e *Editor) debugf(format string, args ...any) {
+	if e.config.Debug {
+		e.logger.Printf(format, args...)
+	}
+}
+
+// WithKeyReader sets the source of the editor's keypresses, overriding the
+// KeyReader passed to New.
+func WithKeyReader(kr KeyReader) EditorOption {
+	return func(e *Editor) {
+		e.r = kr
+	}
+}
+
+// WithTabStop sets the display width of tab stops, overriding
+// Config.TabStop.
+func WithTabStop(n int) EditorOption {
+	return func(e *Editor) {
+		e.config.TabStop = n
+	}
+}
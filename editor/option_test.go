@@ -0,0 +1,106 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func Test_New_options(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		opts        []EditorOption
+		wantTabStop int
+	}{
+		{
+			name:        "when no options are given it uses the default tab stop",
+			wantTabStop: defaultTabStop,
+		},
+		{
+			name:        "when WithTabStop follows WithConfig it overrides the config",
+			opts:        []EditorOption{WithConfig(Config{TabStop: 2}), WithTabStop(8)},
+			wantTabStop: 8,
+		},
+		{
+			name:        "when WithConfig follows WithTabStop it replaces the tab stop",
+			opts:        []EditorOption{WithTabStop(8), WithConfig(Config{TabStop: 2})},
+			wantTabStop: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(nil, nil, tc.opts...)
+			if e.config.TabStop != tc.wantTabStop {
+				t.Errorf("expected tab stop %d, got %d", tc.wantTabStop, e.config.TabStop)
+			}
+			if e.logger == nil {
+				t.Error("expected a default logger")
+			}
+		})
+	}
+}
+
+func Test_Editor_SetLogger(t *testing.T) {
+	t.Parallel()
+
+	e := NewHeadless("text\n", WithConfig(Config{Debug: true}), WithLogger(nil))
+	// The default logger discards output without panicking.
+	e.resize(40, 10)
+
+	var buf bytes.Buffer
+	e.SetLogger(log.New(&buf, "", 0))
+	e.resize(80, 24)
+	if out := buf.String(); !strings.Contains(out, "resized to 80x24") || strings.Contains(out, "40x10") {
+		t.Errorf("expected only the second resize to be logged, got %q", out)
+	}
+
+	e.SetLogger(nil)
+	if _, ok := e.logger.(NopLogger); !ok {
+		t.Errorf("expected a nil logger to be replaced by NopLogger, got %T", e.logger)
+	}
+}
+
+func Test_NopLogger(t *testing.T) {
+	t.Parallel()
+
+	var logger Logger = NopLogger{}
+	logger.Println("discarded", 1)
+	logger.Printf("discarded %d", 2)
+
+	e := New(nil, nil, WithConfig(Config{Debug: true}))
+	// Debug output goes to the default logger without panicking.
+	e.resize(80, 24)
+	if _, ok := e.logger.(NopLogger); !ok {
+		t.Errorf("expected NopLogger by default, got %T", e.logger)
+	}
+}
+
+func Test_Editor_debugf(t *testing.T) {
+	t.Parallel()
+
+	for _, debug := range []bool{false, true} {
+		debug := debug
+
+		t.Run(fmt.Sprintf("debug=%t", debug), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			// The logger is set before the config to check that the order of
+			// the options doesn't matter.
+			e := New(nil, nil, WithLogger(log.New(&buf, "", 0)), WithConfig(Config{Debug: debug}))
+			e.resize(80, 24)
+			if got := buf.Len() > 0; got != debug {
+				t.Errorf("expected logging to be %t, got output %q", debug, buf.String())
+			}
+		})
+	}
+}
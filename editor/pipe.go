@@ -0,0 +1,83 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Pipe replaces the document with the output of command, run by sh with the
+// document as its standard input, like vi's :%!command. The document is written
+// as it would be saved, and the output is read as if it were opened from a file.
+// If command fails, Pipe returns an error that includes its standard error, and
+// the document is unchanged.
+func (e *Editor) Pipe(command string) error {
+	in, err := os.CreateTemp("", "gila-pipe-*")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(in.Name())
+	defer in.Close()
+	if _, err := in.WriteString(e.String()); err != nil {
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	if _, err := in.Seek(0, 0); err != nil {
+		return fmt.Errorf("rewind temporary file: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = in
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+
+	return e.replaceText(&stdout)
+}
+
+// replaceText replaces the document with the text read from r, as if it were
+// opened from a file, and keeps the cursor as close to its position as the new
+// text allows. The document is marked as modified only if its text changes.
+func (e *Editor) replaceText(r io.Reader) error {
+	// The document survives text that can't be read, such as binary data.
+	doc, err := readDocument(r, e.config.TabStop)
+	if err != nil {
+		return fmt.Errorf("read output: %w", err)
+	}
+
+	before := e.String()
+	doc.bom = e.bom
+	e.setDocument(doc)
+	if e.String() != before {
+		e.dirty = true
+	}
+	e.JumpTo(Position{Line: e.cursor.line, Col: e.cursor.col})
+	return nil
+}
+
+// pipe prompts for a command and pipes the document through it. It returns
+// false if the prompt fails.
+func (e *Editor) pipe() bool {
+	if !e.prompt("Pipe through command: %s") { // IO error
+		return false
+	}
+	command := e.promptBuf.String()
+	e.promptBuf.clear()
+	if command == "" {
+		return true
+	}
+	if err := e.Pipe(command); err != nil {
+		e.setStatus("Pipe failed: %s", err)
+		return true
+	}
+	e.setStatus("Piped through %s", command)
+	return true
+}
@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Editor_Pipe(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	testCases := []struct {
+		name      string
+		content   string
+		command   string
+		wantLines []string
+		wantDirty bool
+	}{
+		{
+			name:      "when the command copies its input the document is unchanged",
+			content:   "banana\napple\ncherry\n",
+			command:   "cat",
+			wantLines: []string{"banana", "apple", "cherry"},
+		},
+		{
+			name:      "when the command sorts its input the document is sorted",
+			content:   "banana\napple\ncherry\n",
+			command:   "sort",
+			wantLines: []string{"apple", "banana", "cherry"},
+			wantDirty: true,
+		},
+		{
+			name:      "when the command produces no output the document is emptied",
+			content:   "banana\n",
+			command:   "true",
+			wantLines: []string{},
+			wantDirty: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := NewHeadless(tc.content)
+			e.cursor.line = 3
+			if err := e.Pipe(tc.command); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := lineStrings(e.lines); !reflect.DeepEqual(got, tc.wantLines) {
+				t.Errorf("expected lines %q, got %q", tc.wantLines, got)
+			}
+			if e.dirty != tc.wantDirty {
+				t.Errorf("expected dirty %t, got %t", tc.wantDirty, e.dirty)
+			}
+			if e.cursor.line > len(e.lines)+1 {
+				t.Errorf("expected cursor within the document, got line %d", e.cursor.line)
+			}
+		})
+	}
+}
+
+func Test_Editor_Pipe_failure(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	e := NewHeadless("keep\n")
+	err := e.Pipe("echo oops >&2; exit 1")
+	if err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("expected an error containing the command's stderr, got %v", err)
+	}
+	if got, want := e.String(), "keep\n"; got != want {
+		t.Errorf("expected document %q, got %q", want, got)
+	}
+	if e.dirty {
+		t.Error("expected document to be unmodified")
+	}
+}
+
+func Test_Editor_processKeypress_pipe(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	kr := keys("\x1b|", "s", "o", "r", "t", "\r")
+	e := NewHeadless("b\na\n", WithKeyReader(kr))
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := e.String(), "a\nb\n"; got != want {
+		t.Errorf("expected document %q, got %q", want, got)
+	}
+	if want := "Piped through sort"; e.statusMsg != want {
+		t.Errorf("expected status %q, got %q", want, e.statusMsg)
+	}
+}
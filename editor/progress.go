@@ -0,0 +1,47 @@
+package editor
+
+import (
+	"io"
+	"time"
+)
+
+// progressInterval is the minimum time between progress updates, so that
+// rendering doesn't slow the operation being reported on.
+const progressInterval = time.Second / 10
+
+// progressReader reads from r, calling progress with the number of bytes read
+// so far after each read.
+type progressReader struct {
+	r        io.Reader
+	read     int64
+	total    int64
+	progress func(bytesRead, totalBytes int64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.read += int64(n)
+	pr.progress(pr.read, pr.total)
+	return n, err
+}
+
+// loadProgress returns a progress callback for open that renders a progress
+// bar described by label at most once per progressInterval. Operations that
+// finish within the first interval show no progress at all. stop must be
+// called once the operation is complete.
+func (e *Editor) loadProgress(label string) (progress func(bytesRead, totalBytes int64), stop func()) {
+	if e.renderer == nil {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(progressInterval)
+	progress = func(bytesRead, totalBytes int64) {
+		select {
+		case <-ticker.C:
+			if err := e.renderer.RenderProgress(int(bytesRead), int(totalBytes), label); err != nil {
+				e.debugf("render progress: %v\n", err)
+			}
+		default:
+		}
+	}
+	return progress, ticker.Stop
+}
@@ -0,0 +1,67 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Editor_open_progress(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Repeat("some text\n", 10000)
+	path := filepath.Join(t.TempDir(), "large.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var last, total int64
+	calls := 0
+	progress := func(bytesRead, totalBytes int64) {
+		if bytesRead < last {
+			t.Errorf("expected progress to increase, got %d after %d", bytesRead, last)
+		}
+		last, total = bytesRead, totalBytes
+		calls++
+	}
+	e := New(nil, nil)
+	if err := e.open(path, progress); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls == 0 {
+		t.Fatal("expected progress to be reported")
+	}
+	if want := int64(len(content)); last != want || total != want {
+		t.Errorf("expected final progress %d/%d, got %d/%d", want, want, last, total)
+	}
+}
+
+func Test_Editor_loadProgress(t *testing.T) {
+	t.Parallel()
+
+	t.Run("when the editor has no renderer it reports nothing", func(t *testing.T) {
+		t.Parallel()
+
+		e := New(nil, nil)
+		progress, stop := e.loadProgress("Loading")
+		defer stop()
+		if progress != nil {
+			t.Error("expected no progress callback")
+		}
+	})
+
+	t.Run("when the first interval hasn't elapsed it renders nothing", func(t *testing.T) {
+		t.Parallel()
+
+		r := &fakeRenderer{}
+		e := New(nil, r)
+		progress, stop := e.loadProgress("Loading")
+		defer stop()
+		progress(1, 2)
+		progress(2, 2)
+		if len(r.progress) != 0 {
+			t.Errorf("expected no progress to be rendered, got %q", r.progress)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package editor
+
+import (
+	"errors"
+	"strings"
+)
+
+// errNoConfigLoader is returned by ReloadConfig if the editor was created
+// without WithConfigLoader.
+var errNoConfigLoader = errors.New("no config to reload")
+
+// ReloadConfig reads the configuration afresh with the function set by
+// WithConfigLoader and applies it to every open buffer, keeping the screen size
+// and .editorconfig overrides. Changes to settings that only take effect at
+// startup aren't applied; their names are returned instead: Debug, since the
+// log file is opened at startup, and ReadOnly, which sets the initial mode.
+func (e *Editor) ReloadConfig() (needRestart []string, err error) {
+	if e.loadConfig == nil {
+		return nil, errNoConfigLoader
+	}
+	c, err := e.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	c.Width, c.Height = e.baseConfig.Width, e.baseConfig.Height
+	c = c.withDefaults()
+	if c.Debug != e.baseConfig.Debug {
+		needRestart = append(needRestart, "Debug")
+		c.Debug = e.baseConfig.Debug
+	}
+	if c.ReadOnly != e.baseConfig.ReadOnly {
+		needRestart = append(needRestart, "ReadOnly")
+		c.ReadOnly = e.baseConfig.ReadOnly
+	}
+
+	e.baseConfig, e.config = c, c
+	e.setKeyMap(c.KeyMap)
+	// Lines take their tab stop from the config they were read with.
+	for _, b := range e.buffers {
+		tabStop := e.configWith(b.editorConfig).TabStop
+		for _, line := range b.lines {
+			if line != nil { // not loaded by a lazily loaded buffer
+				line.tabStop = tabStop
+			}
+		}
+		if b.lazy != nil {
+			b.lazy.index.tabStop = tabStop
+		}
+	}
+	e.applyEditorConfig()
+	if inv, ok := e.renderer.(Invalidator); ok {
+		inv.Invalidate()
+	}
+	return needRestart, nil
+}
+
+// reloadConfig reloads the configuration, reporting the outcome in the status
+// bar.
+func (e *Editor) reloadConfig() {
+	needRestart, err := e.ReloadConfig()
+	switch {
+	case err != nil:
+		e.setStatus("Config reload failed: %s", err)
+	case len(needRestart) > 0:
+		e.setStatus("Config reloaded. Restart to apply: %s", strings.Join(needRestart, ", "))
+	default:
+		e.setStatus("Config reloaded")
+	}
+}
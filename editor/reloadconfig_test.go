@@ -0,0 +1,136 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_Editor_ReloadConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		".editorconfig": "root = true\n\n[*.go]\ntab_width = 8\n",
+		"main.go":       "\tpackage main\n",
+		"notes.txt":     "\tnotes\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded := Config{
+		TabStop:  2,
+		SoftWrap: true,
+		Debug:    true,
+		KeyMap:   KeyMap{"Ctrl-S": CmdQuit},
+	}
+	r := &invalidatingRenderer{}
+	e := New(nil, r,
+		WithConfig(Config{Width: 80, Height: 24, TabStop: 4}),
+		WithConfigLoader(func() (Config, error) { return loaded, nil }),
+	)
+	for _, name := range []string{"main.go", "notes.txt"} {
+		if err := e.OpenBuffer(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	needRestart, err := e.ReloadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{"Debug"}; !reflect.DeepEqual(needRestart, want) {
+		t.Errorf("expected settings needing a restart %q, got %q", want, needRestart)
+	}
+	if e.config.Debug {
+		t.Error("expected Debug not to change until restart")
+	}
+	if !e.config.SoftWrap {
+		t.Error("expected SoftWrap to be applied")
+	}
+	if e.config.Width != 80 || e.config.Height != 22 {
+		t.Errorf("expected the screen size to be kept, got %dx%d", e.config.Width, e.config.Height)
+	}
+	if e.config.IndentSize != defaultIndentSize {
+		t.Errorf("expected the default indent size, got %d", e.config.IndentSize)
+	}
+	if got := e.keyMap[keyEvent{key: 's' & ctrlMask}]; got != CmdQuit {
+		t.Errorf("expected Ctrl-S to be rebound to %q, got %q", CmdQuit, got)
+	}
+	if r.invalidated != 1 {
+		t.Errorf("expected the renderer to be invalidated once, got %d", r.invalidated)
+	}
+
+	// main.go is active, and its .editorconfig tab width still applies.
+	if e.config.TabStop != 8 || e.lines[0].tabWidth() != 8 {
+		t.Errorf("expected main.go to keep a tab width of 8, got config %d and line %d",
+			e.config.TabStop, e.lines[0].tabWidth())
+	}
+	e.switchBuffer(1)
+	if e.config.TabStop != 2 || e.lines[0].tabWidth() != 2 {
+		t.Errorf("expected notes.txt to take the reloaded tab width of 2, got config %d and line %d",
+			e.config.TabStop, e.lines[0].tabWidth())
+	}
+}
+
+func Test_Editor_reloadConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		opts       []EditorOption
+		wantStatus string
+	}{
+		{
+			name: "when the config loads it reports success",
+			opts: []EditorOption{
+				WithConfigLoader(func() (Config, error) { return Config{}, nil }),
+			},
+			wantStatus: "Config reloaded",
+		},
+		{
+			name: "when a startup-only setting changes it reports that a restart is needed",
+			opts: []EditorOption{
+				WithConfigLoader(func() (Config, error) { return Config{ReadOnly: true, Debug: true}, nil }),
+			},
+			wantStatus: "Config reloaded. Restart to apply: Debug, ReadOnly",
+		},
+		{
+			name: "when the config fails to load it reports the error",
+			opts: []EditorOption{
+				WithConfigLoader(func() (Config, error) { return Config{}, errors.New("line 1: bad") }),
+			},
+			wantStatus: "Config reload failed: line 1: bad",
+		},
+		{
+			name:       "when there is no config loader it reports the error",
+			wantStatus: "Config reload failed: " + errNoConfigLoader.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]EditorOption{WithConfig(Config{Width: 80, Height: 24})}, tc.opts...)
+			e := New(keys("\x1bc"), &fakeRenderer{}, opts...)
+			for e.processKeypress() {
+			}
+
+			if e.statusMsg != tc.wantStatus {
+				t.Errorf("expected status %q, got %q", tc.wantStatus, e.statusMsg)
+			}
+			if e.readOnly {
+				t.Error("expected read-only mode not to change until restart")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package editor
+
+// keyRead is the result of a call to KeyReader.ReadKey.
+type keyRead struct {
+	key []byte
+	err error
+}
+
+// waitForKey returns the next keystroke from the editor's KeyReader. If the
+// renderer is a Resizer, the screen is redrawn each time it is resized while
+// waiting. If the redraw fails, waitForKey returns EOF, leaving the error in
+// e.writeErr.
+func (e *Editor) waitForKey() ([]byte, error) {
+	resizer, ok := e.renderer.(Resizer)
+	if !ok {
+		return e.r.ReadKey()
+	}
+	if e.keyReads == nil {
+		e.keyReads = make(chan keyRead)
+		go readKeys(e.r, e.keyReads)
+	}
+	for {
+		select {
+		case read := <-e.keyReads:
+			return read.key, read.err
+		case <-resizer.ResizeNotifier():
+			e.resize(resizer.Size())
+			if !e.render() {
+				return nil, nil
+			}
+		}
+	}
+}
+
+// readKeys sends each keystroke read from kr to reads until an error occurs.
+// Keys are copied before they are sent, since a KeyReader may reuse the memory
+// of a key in its next read, which happens while the editor decodes it.
+func readKeys(kr KeyReader, reads chan<- keyRead) {
+	for {
+		key, err := kr.ReadKey()
+		reads <- keyRead{key: append([]byte(nil), key...), err: err}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// resize sets the size of the screen that the editor scrolls within.
+func (e *Editor) resize(width, height int) {
+	e.debugf("resized to %dx%d\n", width, height)
+	e.config.Width = width
+	e.config.Height = height - 2 // reserve the last two lines of the screen for the status bar and status message
+}
@@ -0,0 +1,68 @@
+package editor
+
+import "testing"
+
+// resizingRenderer is a Renderer and Resizer whose screen size is fixed, and
+// which reports each frame rendered on rendered.
+type resizingRenderer struct {
+	fakeRenderer
+	width, height int
+	resized       chan struct{}
+	rendered      chan struct{}
+}
+
+func (r *resizingRenderer) Render(frame Frame) error {
+	r.rendered <- struct{}{}
+	return r.fakeRenderer.Render(frame)
+}
+
+func (r *resizingRenderer) ResizeNotifier() <-chan struct{} {
+	return r.resized
+}
+
+func (r *resizingRenderer) Size() (int, int) {
+	return r.width, r.height
+}
+
+// chanKeyReader is a KeyReader that blocks until a key is sent on keys.
+type chanKeyReader struct {
+	keys chan []byte
+}
+
+func (kr *chanKeyReader) ReadKey() ([]byte, error) {
+	return <-kr.keys, nil
+}
+
+func Test_Editor_readKey_resize(t *testing.T) {
+	t.Parallel()
+
+	r := &resizingRenderer{
+		width:    100,
+		height:   50,
+		resized:  make(chan struct{}, 1),
+		rendered: make(chan struct{}, 1),
+	}
+	kr := &chanKeyReader{keys: make(chan []byte)}
+	e := New(kr, r, WithConfig(Config{Width: 80, Height: 24}))
+
+	// Press a key only once the resized screen has been redrawn.
+	r.resized <- struct{}{}
+	go func() {
+		<-r.rendered
+		kr.keys <- []byte("x")
+	}()
+
+	key, err := e.readKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(key) != "x" {
+		t.Errorf("expected key %q, got %q", "x", key)
+	}
+	if e.config.Width != 100 || e.config.Height != 48 {
+		t.Errorf("expected screen 100x48, got %dx%d", e.config.Width, e.config.Height)
+	}
+	if r.frames != 1 {
+		t.Errorf("expected 1 frame to be rendered, got %d", r.frames)
+	}
+}
@@ -0,0 +1,93 @@
+package editor
+
+import "github.com/angusgmorrison/gila/intutil"
+
+// Position is a location in the document, given by its 1-indexed line and
+// 1-indexed rune column.
+type Position struct {
+	Line, Col int
+}
+
+// Search returns the position of the first match of query after the cursor.
+// If there is no match between the cursor and the end of the document, the
+// search wraps around to the start of the document. Search reports false if
+// query is empty or doesn't occur in the document.
+func (e *Editor) Search(query string) (Position, bool) {
+	q := []rune(query)
+	if len(q) == 0 || len(e.lines) == 0 {
+		return Position{}, false
+	}
+	// Begin with the rest of the current line, just after the cursor, and
+	// finish with the part before it.
+	startLine, startIdx := e.cursor.line-1, e.cursor.col
+	if e.cursor.line > len(e.lines) {
+		startLine, startIdx = 0, 0
+	}
+	for n := 0; n <= len(e.lines); n++ {
+		lineIdx := (startLine + n) % len(e.lines)
+		line := e.lineAt(lineIdx)
+		from := 0
+		if n == 0 {
+			from = startIdx
+		}
+		i := line.index(q, from)
+		if i < 0 {
+			continue
+		}
+		if n == len(e.lines) && i >= startIdx {
+			return Position{}, false // back at the cursor
+		}
+		return Position{Line: lineIdx + 1, Col: i + 1}, true
+	}
+	return Position{}, false
+}
+
+// SearchBackward returns the position of the last match of query before the
+// cursor. If there is no match between the start of the document and the
+// cursor, the search wraps around to the end of the document. SearchBackward
+// reports false if query is empty or doesn't occur in the document.
+func (e *Editor) SearchBackward(query string) (Position, bool) {
+	q := []rune(query)
+	if len(q) == 0 || len(e.lines) == 0 {
+		return Position{}, false
+	}
+	startLine := min(e.cursor.line-1, len(e.lines)-1)
+	startIdx := e.cursor.col - 2 // the rune before the cursor
+	if e.cursor.line > len(e.lines) {
+		startIdx = e.lineAt(startLine).RuneLen()
+	}
+	for n := 0; n <= len(e.lines); n++ {
+		lineIdx := ((startLine-n)%len(e.lines) + len(e.lines)) % len(e.lines)
+		line := e.lineAt(lineIdx)
+		from := line.RuneLen() - len(q)
+		if n == 0 {
+			from = min(from, startIdx)
+		}
+		i := line.lastIndex(q, from)
+		if i < 0 {
+			continue
+		}
+		if n == len(e.lines) && i <= startIdx {
+			return Position{}, false // back at the cursor
+		}
+		return Position{Line: lineIdx + 1, Col: i + 1}, true
+	}
+	return Position{}, false
+}
+
+// JumpTo moves the cursor to pos, clamped to the bounds of the document.
+func (e *Editor) JumpTo(pos Position) {
+	e.cursor.line = intutil.Clamp(pos.Line, 1, len(e.lines)+1)
+	e.cursor.col = max(1, pos.Col)
+	e.cursor.snap(e.currentLine().RuneLen())
+}
+
+// SearchAndJump moves the cursor to the next match of query, as found by
+// Search, reporting whether there was one.
+func (e *Editor) SearchAndJump(query string) bool {
+	pos, ok := e.Search(query)
+	if ok {
+		e.JumpTo(pos)
+	}
+	return ok
+}
@@ -0,0 +1,192 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Editor_Search(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{"foo bar foo", "baz", "bar foo"}
+	testCases := []struct {
+		name     string
+		lines    []string
+		cursor   Position
+		query    string
+		backward bool
+		want     Position
+		wantOK   bool
+	}{
+		{
+			name:   "when the match is on the current line after the cursor it is found",
+			cursor: Position{Line: 1, Col: 1},
+			query:  "foo",
+			want:   Position{Line: 1, Col: 9},
+			wantOK: true,
+		},
+		{
+			name:   "when the match is on a later line it is found",
+			cursor: Position{Line: 1, Col: 9},
+			query:  "foo",
+			want:   Position{Line: 3, Col: 5},
+			wantOK: true,
+		},
+		{
+			name:   "when there is no match before the end it wraps around",
+			cursor: Position{Line: 3, Col: 5},
+			query:  "foo",
+			want:   Position{Line: 1, Col: 1},
+			wantOK: true,
+		},
+		{
+			name:   "when the only match is at the cursor it is found after wrapping",
+			cursor: Position{Line: 2, Col: 1},
+			query:  "baz",
+			want:   Position{Line: 2, Col: 1},
+			wantOK: true,
+		},
+		{
+			name:   "when the only match is before the cursor on its line it is found after wrapping",
+			cursor: Position{Line: 1, Col: 2},
+			query:  "foo bar",
+			want:   Position{Line: 1, Col: 1},
+			wantOK: true,
+		},
+		{
+			name:   "when the line is long the match after the cursor is found",
+			lines:  []string{strings.Repeat("ab", 600) + "abc" + strings.Repeat("ab", 600) + "abc"},
+			cursor: Position{Line: 1, Col: 2},
+			query:  "abc",
+			want:   Position{Line: 1, Col: 1201},
+			wantOK: true,
+		},
+		{
+			name:   "when there is no match it reports false",
+			cursor: Position{Line: 1, Col: 1},
+			query:  "qux",
+		},
+		{
+			name:   "when the query is empty it reports false",
+			cursor: Position{Line: 1, Col: 1},
+		},
+		{
+			name:     "when searching backward the match before the cursor is found",
+			cursor:   Position{Line: 3, Col: 5},
+			query:    "foo",
+			backward: true,
+			want:     Position{Line: 1, Col: 9},
+			wantOK:   true,
+		},
+		{
+			name:     "when searching backward with no match before the cursor it wraps around",
+			cursor:   Position{Line: 1, Col: 1},
+			query:    "foo",
+			backward: true,
+			want:     Position{Line: 3, Col: 5},
+			wantOK:   true,
+		},
+		{
+			name:     "when searching backward the nearest of several matches is found",
+			cursor:   Position{Line: 3, Col: 1},
+			query:    "bar",
+			backward: true,
+			want:     Position{Line: 1, Col: 5},
+			wantOK:   true,
+		},
+		{
+			name:     "when searching backward the only match after the cursor on its line is found after wrapping",
+			cursor:   Position{Line: 2, Col: 1},
+			query:    "az",
+			backward: true,
+			want:     Position{Line: 2, Col: 2},
+			wantOK:   true,
+		},
+		{
+			name:     "when searching backward with no match it reports false",
+			cursor:   Position{Line: 3, Col: 1},
+			query:    "qux",
+			backward: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := New(nil, nil)
+			lines := lines
+			if tc.lines != nil {
+				lines = tc.lines
+			}
+			for _, l := range lines {
+				e.lines = append(e.lines, NewLine(l))
+			}
+			e.JumpTo(tc.cursor)
+
+			search := e.Search
+			if tc.backward {
+				search = e.SearchBackward
+			}
+			got, ok := search(tc.query)
+			if ok != tc.wantOK {
+				t.Fatalf("expected ok %t, got %t", tc.wantOK, ok)
+			}
+			if got != tc.want {
+				t.Errorf("expected position %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Editor_SearchAndJump(t *testing.T) {
+	t.Parallel()
+
+	e := New(nil, nil)
+	e.lines = []*Line{NewLine("one"), NewLine("two")}
+	if !e.SearchAndJump("wo") {
+		t.Fatal("expected a match")
+	}
+	if e.cursor.line != 2 || e.cursor.col != 2 {
+		t.Errorf("expected cursor at 2:2, got %d:%d", e.cursor.line, e.cursor.col)
+	}
+}
+
+func Test_Editor_JumpTo(t *testing.T) {
+	t.Parallel()
+
+	newEditor := func(r Renderer) *Editor {
+		e := New(nil, r, WithConfig(Config{Width: 80, Height: 24}))
+		for i := 0; i < 100; i++ {
+			e.lines = append(e.lines, NewLine("line"))
+		}
+		return e
+	}
+
+	t.Run("when the line is off screen the next render scrolls to it", func(t *testing.T) {
+		t.Parallel()
+
+		r := &fakeRenderer{}
+		e := newEditor(r)
+		e.JumpTo(Position{Line: 42, Col: 1})
+		e.render()
+		if got := r.last.Cursor.Line(); got != 42 {
+			t.Errorf("expected cursor on line 42, got %d", got)
+		}
+		if offset := r.last.Cursor.LineOffset(); offset >= 42 || offset+e.config.Height < 42 {
+			t.Errorf("expected line 42 to be on screen, got line offset %d", offset)
+		}
+	})
+
+	t.Run("when the line is beyond the end of the document it is clamped", func(t *testing.T) {
+		t.Parallel()
+
+		e := newEditor(nil)
+		e.JumpTo(Position{Line: 1000, Col: 1})
+		if e.cursor.line != 101 {
+			t.Errorf("expected cursor on line 101, got %d", e.cursor.line)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/angusgmorrison/gila/find"
+)
+
+// searchResultsFilename names the buffer that lists the hits of SearchAll.
+const searchResultsFilename = "[Search results]"
+
+// SearchAll returns every occurrence of query in the open buffers, other than
+// the search results buffer and files too large to read into memory. The hits'
+// BufferIndex fields index the open buffers in the order they were opened.
+func (e *Editor) SearchAll(query string) []find.SearchHit {
+	f := find.Finder[*Line]{Buffers: make([][]*Line, len(e.buffers))}
+	for i, b := range e.buffers {
+		if b.hits == nil && b.lazy == nil {
+			f.Buffers[i] = b.lines
+		}
+	}
+	return f.SearchAll(query)
+}
+
+// searchAll prompts for a query and lists its occurrences in every open buffer
+// in the search results buffer. It returns false if the prompt fails.
+func (e *Editor) searchAll() bool {
+	if !e.prompt("Search all files: %s") { // IO error
+		return false
+	}
+	query := e.promptBuf.String()
+	e.promptBuf.clear()
+	if query == "" {
+		return true
+	}
+
+	hits := e.SearchAll(query)
+	if len(hits) == 0 {
+		e.setStatus("No matches for %q", query)
+		return true
+	}
+	e.showSearchResults(hits)
+	e.setStatus("%d matches for %q. Enter to jump to a match.", len(hits), query)
+	return true
+}
+
+// showSearchResults lists hits in the search results buffer, one per line in
+// the form "filename:line:col: context", and makes it active. The buffer is
+// created the first time it is needed, and reused after that.
+func (e *Editor) showSearchResults(hits []find.SearchHit) {
+	var results *buffer
+	for _, b := range e.buffers {
+		if b.hits != nil {
+			results = b
+			break
+		}
+	}
+	if results == nil {
+		results = newBuffer()
+		results.filename = searchResultsFilename
+		e.buffers = append(e.buffers, results)
+	}
+
+	for _, line := range results.lines {
+		line.release()
+	}
+	results.lines = make([]*Line, len(hits))
+	for i, h := range hits {
+		target := e.buffers[h.BufferIndex]
+		name := target.filepath
+		if name == "" {
+			name = target.filename
+		}
+		results.lines[i] = NewLine(fmt.Sprintf("%s:%d:%d: %s", name, h.Line, h.Col, h.Context))
+	}
+	results.hits = hits
+	results.cursor = newCursor()
+	e.buffer = results
+	e.applyEditorConfig()
+}
+
+// jumpToHit makes the buffer containing the hit listed on the cursor's line of
+// the search results buffer active, and moves the cursor to the hit.
+func (e *Editor) jumpToHit() {
+	i := e.cursor.line - 1
+	if i < 0 || i >= len(e.hits) {
+		return
+	}
+	h := e.hits[i]
+	e.buffer = e.buffers[h.BufferIndex]
+	e.applyEditorConfig()
+	e.JumpTo(Position{Line: h.Line, Col: h.Col})
+	e.setStatus("%s:%d:%d", e.filename, h.Line, h.Col)
+}
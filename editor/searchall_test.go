@@ -0,0 +1,103 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/angusgmorrison/gila/find"
+)
+
+func Test_Editor_SearchAll(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	for i, content := range []string{"foo bar foo\nbaz\n", "qux\nbar foo\n"} {
+		if err := os.WriteFile(paths[i], []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Alt-S, the query, Enter, then Down and Enter to jump to the second hit.
+	e := New(keys("\x1bs", "f", "o", "o", "\r", "\x1b[B", "\r"), &fakeRenderer{},
+		WithConfig(Config{Width: 80, Height: 24}))
+	for _, path := range paths {
+		if err := e.OpenBuffer(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	wantHits := []find.SearchHit{
+		{BufferIndex: 0, Line: 1, Col: 1, Context: "foo bar foo"},
+		{BufferIndex: 0, Line: 1, Col: 9, Context: "foo bar foo"},
+		{BufferIndex: 1, Line: 2, Col: 5, Context: "bar foo"},
+	}
+	if got := e.SearchAll("foo"); !reflect.DeepEqual(got, wantHits) {
+		t.Fatalf("expected hits %+v, got %+v", wantHits, got)
+	}
+
+	// Alt-S prompts for the query.
+	e.processKeypress()
+	if e.filename != searchResultsFilename {
+		t.Fatalf("expected the search results to be active, got %q", e.filename)
+	}
+	wantLines := []string{
+		paths[0] + ":1:1: foo bar foo",
+		paths[0] + ":1:9: foo bar foo",
+		paths[1] + ":2:5: bar foo",
+	}
+	if got := lineStrings(e.lines); !reflect.DeepEqual(got, wantLines) {
+		t.Errorf("expected results %q, got %q", wantLines, got)
+	}
+	if got := e.SearchAll("foo"); !reflect.DeepEqual(got, wantHits) {
+		t.Errorf("expected the search results not to be searched, got %+v", got)
+	}
+
+	// Down and Enter.
+	for e.processKeypress() {
+	}
+	if e.buffer != e.buffers[0] {
+		t.Fatalf("expected a.txt to be active, got %q", e.filename)
+	}
+	if e.cursor.line != 1 || e.cursor.col != 9 {
+		t.Errorf("expected the cursor at 1:9, got %d:%d", e.cursor.line, e.cursor.col)
+	}
+	if got := lineStrings(e.buffers[2].lines); !reflect.DeepEqual(got, wantLines) {
+		t.Errorf("expected Enter not to edit the search results, got %q", got)
+	}
+}
+
+func Test_Editor_searchAll_noMatches(t *testing.T) {
+	t.Parallel()
+
+	e := New(keys("\x1bs", "x", "\r"), &fakeRenderer{}, WithConfig(Config{Width: 80, Height: 24}))
+	e.lines = []*Line{NewLine("foo")}
+	for e.processKeypress() {
+	}
+
+	if want := `No matches for "x"`; e.statusMsg != want {
+		t.Errorf("expected status %q, got %q", want, e.statusMsg)
+	}
+	if len(e.buffers) != 1 {
+		t.Errorf("expected no search results buffer, got %d buffers", len(e.buffers))
+	}
+}
+
+func Test_Editor_processKeypress_searchResultsReadOnly(t *testing.T) {
+	t.Parallel()
+
+	e := New(keys("x", "\x7f"), &fakeRenderer{}, WithConfig(Config{Width: 80, Height: 24}))
+	e.lines = []*Line{NewLine("foo")}
+	e.showSearchResults([]find.SearchHit{{Line: 1, Col: 1, Context: "foo"}})
+	for e.processKeypress() {
+	}
+
+	if got, want := lineStrings(e.lines), []string{"[Untitled]:1:1: foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the search results to be unchanged, got %q", got)
+	}
+	if want := "Search results are read-only. Enter to jump to a match."; e.statusMsg != want {
+		t.Errorf("expected status %q, got %q", want, e.statusMsg)
+	}
+}
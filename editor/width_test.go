@@ -0,0 +1,92 @@
+package editor
+
+import "testing"
+
+func Test_RuneWidth(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		r    rune
+		want int
+	}{
+		{name: "ASCII", r: 'a', want: 1},
+		{name: "accented Latin", r: 'é', want: 1},
+		{name: "Han ideograph", r: '世', want: 2},
+		{name: "Hiragana", r: 'か', want: 2},
+		{name: "Hangul syllable", r: '한', want: 2},
+		{name: "fullwidth Latin", r: 'Ａ', want: 2},
+		{name: "emoji", r: '😀', want: 2},
+		{name: "combining accent", r: '\u0301', want: 0},
+		{name: "zero width joiner", r: '\u200d', want: 0},
+		{name: "variation selector", r: '\ufe0f', want: 0},
+		{name: "first rune of a range", r: 0x1100, want: 2},
+		{name: "last rune of a range", r: 0x115f, want: 2},
+		{name: "rune after a range", r: 0x1160, want: 1},
+		{name: "last range", r: 0x3fffd, want: 2},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := RuneWidth(tc.r); got != tc.want {
+				t.Errorf("expected width %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_wideRanges_sorted(t *testing.T) {
+	t.Parallel()
+
+	for i, rng := range wideRanges {
+		if rng[0] > rng[1] {
+			t.Errorf("range %d: start %#x is after end %#x", i, rng[0], rng[1])
+		}
+		if i > 0 && rng[0] <= wideRanges[i-1][1] {
+			t.Errorf("range %d: start %#x overlaps previous range", i, rng[0])
+		}
+	}
+}
+
+func Test_Cursor_scroll_wide(t *testing.T) {
+	t.Parallel()
+
+	line := newLineFromString("ab世界cd")
+	testCases := []struct {
+		name          string
+		col           int
+		wantRenderCol int
+		wantColOffset int
+		wantX         int
+	}{
+		{name: "before the wide characters", col: 2, wantRenderCol: 2, wantColOffset: 0, wantX: 2},
+		{name: "on the first wide character", col: 3, wantRenderCol: 3, wantColOffset: 0, wantX: 3},
+		{name: "after one wide character", col: 4, wantRenderCol: 5, wantColOffset: 0, wantX: 5},
+		{name: "after both wide characters", col: 5, wantRenderCol: 7, wantColOffset: 0, wantX: 7},
+		{name: "end of the line scrolls right by cells", col: 7, wantRenderCol: 9, wantColOffset: 2, wantX: 7},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Cursor{line: 1, col: tc.col}
+			c.scroll(line, 7, 10)
+			if c.renderCol != tc.wantRenderCol {
+				t.Errorf("expected renderCol %d, got %d", tc.wantRenderCol, c.renderCol)
+			}
+			if c.colOffset != tc.wantColOffset {
+				t.Errorf("expected colOffset %d, got %d", tc.wantColOffset, c.colOffset)
+			}
+			if c.X() != tc.wantX {
+				t.Errorf("expected X %d, got %d", tc.wantX, c.X())
+			}
+		})
+	}
+}
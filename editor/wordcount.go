@@ -0,0 +1,33 @@
+package editor
+
+import "unicode"
+
+// WordCount returns the number of words, lines and bytes in the document. A
+// word is a maximal sequence of letters and digits. The byte count is the
+// length of the document saved with a '\n' after every line.
+func (e *Editor) WordCount() (words, lines, bytes int) {
+	for i := range e.lines {
+		l := e.lineAt(i)
+		inWord := false
+		for _, r := range l.Runes() {
+			isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+			if isWordRune && !inWord {
+				words++
+			}
+			inWord = isWordRune
+		}
+		bytes += len(l.String()) + 1
+	}
+	return words, len(e.lines), bytes
+}
+
+// toggleWordCount shows or hides the word count in the status bar, reporting
+// the full count in the status message when shown.
+func (e *Editor) toggleWordCount() {
+	e.showWordCount = !e.showWordCount
+	if !e.showWordCount {
+		return
+	}
+	words, lines, bytes := e.WordCount()
+	e.setStatus("%d words, %d lines, %d bytes", words, lines, bytes)
+}
@@ -0,0 +1,93 @@
+package editor
+
+import (
+	"testing"
+)
+
+func Test_Editor_WordCount(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		lines     []string
+		wantWords int
+		wantLines int
+		wantBytes int
+	}{
+		{
+			name: "empty document",
+		},
+		{
+			name:      "empty line",
+			lines:     []string{""},
+			wantLines: 1,
+			wantBytes: 1,
+		},
+		{
+			name:      "words separated by whitespace and punctuation",
+			lines:     []string{"Hello, world!", "  one-two three  "},
+			wantWords: 5,
+			wantLines: 2,
+			wantBytes: 14 + 18,
+		},
+		{
+			name:      "digits and non-ASCII letters",
+			lines:     []string{"año 2023—über"},
+			wantWords: 3,
+			wantLines: 1,
+			wantBytes: 18,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := &Editor{buffer: newBuffer()}
+			for _, l := range tc.lines {
+				e.lines = append(e.lines, newLineFromString(l))
+			}
+			words, lines, bytes := e.WordCount()
+			if words != tc.wantWords || lines != tc.wantLines || bytes != tc.wantBytes {
+				t.Errorf("expected (%d, %d, %d), got (%d, %d, %d)",
+					tc.wantWords, tc.wantLines, tc.wantBytes, words, lines, bytes)
+			}
+		})
+	}
+}
+
+func Test_Editor_WordCount_file(t *testing.T) {
+	t.Parallel()
+
+	e := New(nil, nil)
+	if err := e.open("../testdata/short_lines.txt", nil); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	words, lines, bytes := e.WordCount()
+	if words != 173 || lines != 22 || bytes != 927 {
+		t.Errorf("expected (173, 22, 927), got (%d, %d, %d)", words, lines, bytes)
+	}
+}
+
+func Test_Editor_toggleWordCount(t *testing.T) {
+	t.Parallel()
+
+	e := New(keys("\x17"), nil)
+	e.lines = []*Line{newLineFromString("two words")}
+	e.processKeypress()
+
+	frame := e.frame()
+	if !frame.ShowWordCount || frame.Words != 2 {
+		t.Errorf("expected frame to show 2 words, got %t, %d", frame.ShowWordCount, frame.Words)
+	}
+	if want := "2 words, 1 lines, 10 bytes"; e.statusMsg != want {
+		t.Errorf("expected status %q, got %q", want, e.statusMsg)
+	}
+
+	e.toggleWordCount()
+	if e.frame().ShowWordCount {
+		t.Error("expected word count to be hidden")
+	}
+}
@@ -0,0 +1,212 @@
+// Package editorconfig reads the formatting settings that .editorconfig files
+// give for a file, as described at https://editorconfig.org.
+//
+// An .editorconfig file consists of sections headed by a glob in brackets,
+// each followed by lines of the form
+//
+//	property = value
+//
+// Lines starting with # or ; are comments. Globs without a "/" match files in
+// any directory below the .editorconfig file, while globs containing a "/" are
+// relative to its directory. The property root = true, given before the first
+// section, stops the search for .editorconfig files in parent directories.
+package editorconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Filename is the name of the files that Load reads.
+const Filename = ".editorconfig"
+
+// EC holds the settings that .editorconfig files give for a file. Fields that
+// no file sets are left as their zero values.
+type EC struct {
+	// IndentStyle is "tab" or "space".
+	IndentStyle string
+	// IndentSize is the number of columns used for each indentation level.
+	IndentSize int
+	// TabWidth is the display width of tab characters. It defaults to
+	// IndentSize.
+	TabWidth int
+	// EndOfLine is "lf", "crlf" or "cr".
+	EndOfLine string
+	// Charset is "latin1", "utf-8", "utf-8-bom", "utf-16be" or "utf-16le".
+	Charset string
+	// TrimTrailingWhitespace is "true" or "false", so that a file can turn off
+	// trimming that would otherwise apply.
+	TrimTrailingWhitespace string
+}
+
+// section is a glob and the properties that apply to the files it matches.
+type section struct {
+	glob       *glob
+	properties map[string]string
+}
+
+// file is a parsed .editorconfig file.
+type file struct {
+	root     bool
+	sections []section
+}
+
+// Load returns the settings for the file at filePath given by the
+// .editorconfig files in its directory and each parent directory, up to the
+// root of the file system or the first file that sets root = true. Properties
+// in files closer to filePath take precedence, and later sections in a file
+// take precedence over earlier ones. Missing .editorconfig files are ignored.
+func Load(filePath string) (EC, error) {
+	path, err := filepath.Abs(filePath)
+	if err != nil {
+		return EC{}, err
+	}
+
+	var files []*file
+	var dirs []string
+	for dir := filepath.Dir(path); ; {
+		f, err := parseFile(filepath.Join(dir, Filename))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return EC{}, err
+		}
+		if f != nil {
+			files = append(files, f)
+			dirs = append(dirs, dir)
+			if f.root {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	properties := make(map[string]string)
+	for i := len(files) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(dirs[i], path)
+		if err != nil {
+			return EC{}, err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, s := range files[i].sections {
+			if !s.glob.match(rel) {
+				continue
+			}
+			for name, value := range s.properties {
+				properties[name] = value
+			}
+		}
+	}
+	return newEC(properties), nil
+}
+
+func parseFile(path string) (*file, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parsed, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return parsed, nil
+}
+
+// parse reads an .editorconfig file from r. Errors give the line number they
+// occurred on.
+func parse(r io.Reader) (*file, error) {
+	f := &file{}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			g, err := compileGlob(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			f.sections = append(f.sections, section{glob: g, properties: make(map[string]string)})
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"property = value\", got %q", lineNum, line)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.ToLower(strings.TrimSpace(value))
+		if len(f.sections) == 0 {
+			// Only root may precede the first section.
+			if name == "root" {
+				f.root = value == "true"
+			}
+			continue
+		}
+		f.sections[len(f.sections)-1].properties[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read .editorconfig: %w", err)
+	}
+	return f, nil
+}
+
+// newEC returns the settings given by properties. Properties set to "unset"
+// and invalid values are ignored, and unknown properties are skipped.
+func newEC(properties map[string]string) EC {
+	var ec EC
+	switch v := properties["indent_style"]; v {
+	case "tab", "space":
+		ec.IndentStyle = v
+	}
+	switch v := properties["end_of_line"]; v {
+	case "lf", "crlf", "cr":
+		ec.EndOfLine = v
+	}
+	switch v := properties["charset"]; v {
+	case "latin1", "utf-8", "utf-8-bom", "utf-16be", "utf-16le":
+		ec.Charset = v
+	}
+	switch v := properties["trim_trailing_whitespace"]; v {
+	case "true", "false":
+		ec.TrimTrailingWhitespace = v
+	}
+	ec.TabWidth = positive(properties["tab_width"])
+
+	indentSize := properties["indent_size"]
+	if indentSize == "" && ec.IndentStyle == "tab" {
+		indentSize = "tab"
+	}
+	if indentSize == "tab" {
+		ec.IndentSize = ec.TabWidth
+	} else {
+		ec.IndentSize = positive(indentSize)
+	}
+	if ec.TabWidth == 0 {
+		ec.TabWidth = ec.IndentSize
+	}
+	return ec
+}
+
+// positive returns the positive integer value represents, or 0 if it doesn't
+// represent one.
+func positive(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
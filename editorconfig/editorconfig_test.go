@@ -0,0 +1,174 @@
+package editorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Load(t *testing.T) {
+	t.Parallel()
+
+	// The top-level file sets root so that files outside the temporary
+	// directory are ignored.
+	dir := t.TempDir()
+	files := map[string]string{
+		".editorconfig": `root = true
+
+[*]
+indent_style = space
+indent_size = 2
+end_of_line = lf
+
+# Later sections take precedence.
+[*.go]
+indent_style = tab
+
+[sub/*.md]
+end_of_line = crlf
+`,
+		"sub/.editorconfig": `; Closer files take precedence.
+[*.go]
+indent_size = 8
+
+[*]
+trim_trailing_whitespace = true
+`,
+		"sub/unset/.editorconfig": `[*]
+indent_size = unset
+`,
+		"sub/keep/.editorconfig": `[*]
+trim_trailing_whitespace = false
+`,
+		"sub/root/.editorconfig": `root = true
+
+[*]
+charset = utf-8
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name string
+		path string
+		want EC
+	}{
+		{
+			name: "when the file is beside the root file it uses its settings",
+			path: "a.txt",
+			want: EC{IndentStyle: "space", IndentSize: 2, TabWidth: 2, EndOfLine: "lf"},
+		},
+		{
+			name: "when sections of several files match it merges them",
+			path: "sub/main.go",
+			want: EC{IndentStyle: "tab", IndentSize: 8, TabWidth: 8, EndOfLine: "lf", TrimTrailingWhitespace: "true"},
+		},
+		{
+			name: "when a glob contains a slash it matches relative to its file",
+			path: "sub/README.md",
+			want: EC{IndentStyle: "space", IndentSize: 2, TabWidth: 2, EndOfLine: "crlf", TrimTrailingWhitespace: "true"},
+		},
+		{
+			name: "when a property is unset it takes its default",
+			path: "sub/unset/a.txt",
+			want: EC{IndentStyle: "space", EndOfLine: "lf", TrimTrailingWhitespace: "true"},
+		},
+		{
+			name: "when a closer file turns a property off it overrides files above it",
+			path: "sub/keep/a.txt",
+			want: EC{IndentStyle: "space", IndentSize: 2, TabWidth: 2, EndOfLine: "lf", TrimTrailingWhitespace: "false"},
+		},
+		{
+			name: "when a closer file sets root it ignores files above it",
+			path: "sub/root/a.txt",
+			want: EC{Charset: "utf-8"},
+		},
+		{
+			name: "when the file doesn't exist it still finds its settings",
+			path: "sub/new/main.go",
+			want: EC{IndentStyle: "tab", IndentSize: 8, TabWidth: 8, EndOfLine: "lf", TrimTrailingWhitespace: "true"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Load(filepath.Join(dir, tc.path))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Load_invalidFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, Filename)
+	if err := os.WriteFile(path, []byte("root = true\n[*]\nindent_size\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(filepath.Join(dir, "a.txt"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error on line 3, got %v", err)
+	}
+}
+
+func Test_newEC(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		properties map[string]string
+		want       EC
+	}{
+		{
+			name:       "when indent_size is tab it uses tab_width",
+			properties: map[string]string{"indent_size": "tab", "tab_width": "3"},
+			want:       EC{IndentSize: 3, TabWidth: 3},
+		},
+		{
+			name:       "when indent_style is tab indent_size defaults to tab_width",
+			properties: map[string]string{"indent_style": "tab", "tab_width": "8"},
+			want:       EC{IndentStyle: "tab", IndentSize: 8, TabWidth: 8},
+		},
+		{
+			name:       "when tab_width is set it overrides indent_size",
+			properties: map[string]string{"indent_size": "2", "tab_width": "4"},
+			want:       EC{IndentSize: 2, TabWidth: 4},
+		},
+		{
+			name:       "when values are invalid they are ignored",
+			properties: map[string]string{"indent_style": "both", "indent_size": "-1", "end_of_line": "nl"},
+			want:       EC{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := newEC(tc.properties); got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package editorconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// glob matches file paths against an .editorconfig section name. Supported
+// wildcards are:
+//
+//   - "*" matches any characters except "/".
+//   - "**" matches any characters.
+//   - "?" matches any single character except "/".
+//   - "[abc]" and "[a-z]" match any single character in the set.
+//   - "[!abc]" matches any single character not in the set.
+//   - "{s1,s2}" matches any of the comma-separated patterns.
+//   - "{n1..n2}" matches any integer between n1 and n2 inclusive.
+//
+// A backslash escapes the character that follows it.
+type glob struct {
+	re *regexp.Regexp
+	// The bounds of each {n1..n2} range, in the order of their capturing
+	// groups in re.
+	ranges [][2]int
+}
+
+// compileGlob returns a glob for the section name pattern. If pattern contains
+// a "/", it matches paths relative to the directory of the .editorconfig file;
+// otherwise, it matches file names in any directory below it.
+func compileGlob(pattern string) (*glob, error) {
+	g := &glob{}
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(pattern, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	b.WriteString(g.translate(strings.TrimPrefix(pattern, "/")))
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	g.re = re
+	return g, nil
+}
+
+// match reports whether the slash-separated path matches the glob.
+func (g *glob) match(path string) bool {
+	m := g.re.FindStringSubmatch(path)
+	if m == nil {
+		return false
+	}
+	for i, r := range g.ranges {
+		if m[i+1] == "" { // in an alternative that didn't match
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil || n < r[0] || n > r[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// translate returns the regular expression equivalent to pattern, recording
+// the bounds of any numeric ranges.
+func (g *glob) translate(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end <= 0 || strings.Contains(pattern[i+1:i+1+end], "/") {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(charClass(pattern[i+1 : i+1+end]))
+			i += end + 1
+		case '{':
+			end := closingBrace(pattern, i)
+			if end < 0 {
+				b.WriteString(`\{`)
+				continue
+			}
+			b.WriteString(g.translateBraces(pattern[i+1 : end]))
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// translateBraces returns the regular expression equivalent to the contents of
+// a pair of braces.
+func (g *glob) translateBraces(inner string) string {
+	if lo, hi, ok := numericRange(inner); ok {
+		g.ranges = append(g.ranges, [2]int{lo, hi})
+		return `([+-]?\d+)`
+	}
+	alts := splitAlternatives(inner)
+	if len(alts) < 2 { // not a list, so the braces are literal
+		return `\{` + g.translate(inner) + `\}`
+	}
+	for i, alt := range alts {
+		alts[i] = g.translate(alt)
+	}
+	return "(?:" + strings.Join(alts, "|") + ")"
+}
+
+// charClass returns the regular expression character class equivalent to the
+// contents of a pair of square brackets.
+func charClass(class string) string {
+	var b strings.Builder
+	b.WriteString("[")
+	if rest, ok := strings.CutPrefix(class, "!"); ok {
+		b.WriteString("^")
+		class = rest
+	}
+	for _, r := range class {
+		if strings.ContainsRune(`\[]^`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// closingBrace returns the index of the brace that closes the one at
+// pattern[open], or -1 if it isn't closed.
+func closingBrace(pattern string, open int) int {
+	depth := 0
+	for i := open; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the contents of a pair of braces at the commas that
+// aren't nested in further braces or escaped.
+func splitAlternatives(inner string) []string {
+	var alts []string
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		switch inner[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alts = append(alts, inner[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alts, inner[start:])
+}
+
+// numericRange parses the contents of a pair of braces of the form n1..n2.
+func numericRange(inner string) (lo, hi int, ok bool) {
+	first, second, found := strings.Cut(inner, "..")
+	if !found {
+		return 0, 0, false
+	}
+	lo, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, false
+	}
+	hi, err = strconv.Atoi(second)
+	if err != nil {
+		return 0, 0, false
+	}
+	return lo, hi, true
+}
@@ -0,0 +1,54 @@
+package editorconfig
+
+import "testing"
+
+func Test_glob_match(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*", path: "main.go", want: true},
+		{pattern: "*", path: "cmd/main.go", want: true},
+		{pattern: "*.go", path: "cmd/main.go", want: true},
+		{pattern: "*.go", path: "main.go.txt", want: false},
+		{pattern: "main.?o", path: "main.go", want: true},
+		{pattern: "Makefile", path: "sub/Makefile", want: true},
+		{pattern: "cmd/*.go", path: "cmd/main.go", want: true},
+		{pattern: "cmd/*.go", path: "sub/cmd/main.go", want: false},
+		{pattern: "/cmd/*.go", path: "cmd/main.go", want: true},
+		{pattern: "cmd/*.go", path: "cmd/sub/main.go", want: false},
+		{pattern: "cmd/**.go", path: "cmd/sub/main.go", want: true},
+		{pattern: "[abc].txt", path: "b.txt", want: true},
+		{pattern: "[a-c].txt", path: "d.txt", want: false},
+		{pattern: "[!abc].txt", path: "d.txt", want: true},
+		{pattern: "[!abc].txt", path: "a.txt", want: false},
+		{pattern: "*.{js,ts}", path: "index.ts", want: true},
+		{pattern: "*.{js,ts}", path: "index.go", want: false},
+		{pattern: "{package.json,.travis.yml}", path: ".travis.yml", want: true},
+		{pattern: "{single}", path: "{single}", want: true},
+		{pattern: "file{1..10}.txt", path: "file7.txt", want: true},
+		{pattern: "file{1..10}.txt", path: "file11.txt", want: false},
+		{pattern: `\*.txt`, path: "*.txt", want: true},
+		{pattern: `\*.txt`, path: "a.txt", want: false},
+		{pattern: "a+b(c).txt", path: "a+b(c).txt", want: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.pattern+" "+tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			g, err := compileGlob(tc.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := g.match(tc.path); got != tc.want {
+				t.Errorf("expected match to be %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
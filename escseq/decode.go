@@ -0,0 +1,123 @@
+package escseq
+
+import (
+	"strconv"
+	"strings"
+)
+
+// decodable lists the sequences recognized by Decode in the order they are
+// tried. Sequences without parameters come first, so that they take
+// precedence over parameterized sequences that also match, like
+// EscGRendRestore over EscGRendSet. Likewise, modified keys precede
+// EscCursorPosition, which has the same form as EscKeyHomeMod.
+var decodable = []EscSeq{
+	EscCursorHide,
+	EscCursorShow,
+	EscCursorTopLeft,
+	EscCursorBlinkBlock,
+	EscCursorSteadyBlock,
+	EscCursorBlinkUnderline,
+	EscCursorSteadyUnderline,
+	EscCursorBlinkBar,
+	EscCursorSteadyBar,
+	EscGRendInvertColors,
+	EscGRendRestore,
+	EscLineClearFromCursor,
+	EscScreenClear,
+	EscScrollRegionReset,
+	EscKeyUp,
+	EscKeyDown,
+	EscKeyRight,
+	EscKeyLeft,
+	EscKeyHome,
+	EscKeyEnd,
+	EscKeyShiftTab,
+	EscKeyHomeSS3,
+	EscKeyEndSS3,
+	EscKeyF1SS3,
+	EscKeyUpMod,
+	EscKeyDownMod,
+	EscKeyRightMod,
+	EscKeyLeftMod,
+	EscKeyHomeMod,
+	EscKeyEndMod,
+	EscCursorPosition,
+	EscGRendSet,
+	EscScrollUp,
+	EscScrollDown,
+	EscScrollRegion,
+	EscKeyVT,
+	EscKeyVTMod,
+}
+
+// Decode matches seq against the known escape sequences, returning the
+// template of the first that matches in full, and the integer parameters that
+// fill its verbs. For example, "\x1b[12;34H" decodes to EscCursorPosition with
+// parameters 12 and 34. A %d verb matches one or more digits; a %s verb
+// matches a possibly empty list of semicolon-separated numbers, each of which
+// is a parameter. ok is false if no sequence matches.
+//
+// Sequences with the same bytes, like EscCursorTopLeft and EscKeyHome, are
+// equal, so either may be compared with the result.
+func Decode(seq []byte) (template EscSeq, args []int, ok bool) {
+	for _, t := range decodable {
+		if args, ok := match(string(t), string(seq)); ok {
+			return t, args, true
+		}
+	}
+	return "", nil, false
+}
+
+// match reports whether s matches template in full, returning the parameters
+// that fill the template's verbs.
+func match(template, s string) ([]int, bool) {
+	args := []int{}
+	for len(template) > 0 {
+		switch {
+		case strings.HasPrefix(template, "%d"):
+			n := digitsPrefixLen(s)
+			if n == 0 {
+				return nil, false
+			}
+			arg, err := strconv.Atoi(s[:n])
+			if err != nil {
+				return nil, false
+			}
+			args = append(args, arg)
+			template, s = template[2:], s[n:]
+		case strings.HasPrefix(template, "%s"):
+			n := 0
+			for n < len(s) && (isDigit(s[n]) || s[n] == ';') {
+				n++
+			}
+			if n > 0 {
+				for _, field := range strings.Split(s[:n], ";") {
+					arg, err := strconv.Atoi(field)
+					if err != nil {
+						return nil, false
+					}
+					args = append(args, arg)
+				}
+			}
+			template, s = template[2:], s[n:]
+		default:
+			if len(s) == 0 || s[0] != template[0] {
+				return nil, false
+			}
+			template, s = template[1:], s[1:]
+		}
+	}
+	return args, len(s) == 0
+}
+
+func digitsPrefixLen(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
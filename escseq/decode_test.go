@@ -0,0 +1,125 @@
+package escseq
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Decode(t *testing.T) {
+	t.Parallel()
+
+	t.Run("every known sequence decodes to itself", func(t *testing.T) {
+		t.Parallel()
+
+		for _, template := range decodable {
+			// Fill each verb with a distinct parameter.
+			var (
+				args []any
+				want = []int{}
+			)
+			for i := 0; i < strings.Count(string(template), "%"); i++ {
+				if strings.Contains(string(template), "%s") {
+					args = append(args, fmt.Sprintf("%d;%d", 10+i, 20+i))
+					want = append(want, 10+i, 20+i)
+				} else {
+					args = append(args, 10+i)
+					want = append(want, 10+i)
+				}
+			}
+			seq := fmt.Sprintf(string(template), args...)
+
+			gotTemplate, gotArgs, ok := Decode([]byte(seq))
+			if !ok {
+				t.Errorf("%q: expected a match", seq)
+				continue
+			}
+			if gotTemplate != template {
+				t.Errorf("%q: expected template %q, got %q", seq, template, gotTemplate)
+			}
+			if !reflect.DeepEqual(gotArgs, want) {
+				t.Errorf("%q: expected args %v, got %v", seq, want, gotArgs)
+			}
+		}
+	})
+
+	testCases := []struct {
+		name         string
+		seq          string
+		wantTemplate EscSeq
+		wantArgs     []int
+		wantOK       bool
+	}{
+		{
+			name:         "cursor position",
+			seq:          "\x1b[12;34H",
+			wantTemplate: EscCursorPosition,
+			wantArgs:     []int{12, 34},
+			wantOK:       true,
+		},
+		{
+			name:         "VT-style key",
+			seq:          "\x1b[15~",
+			wantTemplate: EscKeyVT,
+			wantArgs:     []int{15},
+			wantOK:       true,
+		},
+		{
+			name:         "modified key",
+			seq:          "\x1b[1;5C",
+			wantTemplate: EscKeyRightMod,
+			wantArgs:     []int{5},
+			wantOK:       true,
+		},
+		{
+			name:         "modified key preferred over cursor position",
+			seq:          "\x1b[1;2H",
+			wantTemplate: EscKeyHomeMod,
+			wantArgs:     []int{2},
+			wantOK:       true,
+		},
+		{
+			name:         "modified VT-style key",
+			seq:          "\x1b[3;5~",
+			wantTemplate: EscKeyVTMod,
+			wantArgs:     []int{3, 5},
+			wantOK:       true,
+		},
+		{
+			name:         "literal sequence preferred over parameterized",
+			seq:          "\x1b[m",
+			wantTemplate: EscGRendRestore,
+			wantArgs:     []int{},
+			wantOK:       true,
+		},
+		{name: "empty", seq: ""},
+		{name: "plain text", seq: "abc"},
+		{name: "lone escape", seq: "\x1b"},
+		{name: "unterminated CSI", seq: "\x1b["},
+		{name: "missing parameter", seq: "\x1b[12;H"},
+		{name: "non-numeric parameter", seq: "\x1b[ab~"},
+		{name: "too many parameters", seq: "\x1b[1;2;3H"},
+		{name: "trailing bytes", seq: "\x1b[Ax"},
+		{name: "unknown final byte", seq: "\x1b[12Y"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotTemplate, gotArgs, ok := Decode([]byte(tc.seq))
+			if ok != tc.wantOK {
+				t.Fatalf("expected ok %t, got %t", tc.wantOK, ok)
+			}
+			if gotTemplate != tc.wantTemplate {
+				t.Errorf("expected template %q, got %q", tc.wantTemplate, gotTemplate)
+			}
+			if tc.wantOK && !reflect.DeepEqual(gotArgs, tc.wantArgs) {
+				t.Errorf("expected args %v, got %v", tc.wantArgs, gotArgs)
+			}
+		})
+	}
+}
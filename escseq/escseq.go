@@ -2,6 +2,11 @@
 // terminals.
 package escseq
 
+import (
+	"fmt"
+	"strconv"
+)
+
 type EscSeq string
 
 const (
@@ -10,16 +15,184 @@ const (
 	EscCursorShow     EscSeq = "\x1b[?25h"
 	EscCursorPosition EscSeq = "\x1b[%d;%dH"
 	EscCursorTopLeft  EscSeq = "\x1b[H"
+	EscCursorSave     EscSeq = "\x1b[s"
+	EscCursorRestore  EscSeq = "\x1b[u"
+	// Cursor style (DECSCUSR)
+	EscCursorDefaultStyle    EscSeq = "\x1b[0 q"
+	EscCursorBlinkBlock      EscSeq = "\x1b[1 q"
+	EscCursorSteadyBlock     EscSeq = "\x1b[2 q"
+	EscCursorBlinkUnderline  EscSeq = "\x1b[3 q"
+	EscCursorSteadyUnderline EscSeq = "\x1b[4 q"
+	EscCursorBlinkBar        EscSeq = "\x1b[5 q"
+	EscCursorSteadyBar       EscSeq = "\x1b[6 q"
 	// Graphic rendition
 	EscGRendInvertColors EscSeq = "\x1b[7m"
 	EscGRendRestore      EscSeq = "\x1b[m"
+	EscGRendSet          EscSeq = "\x1b[%sm"
 	// Line
 	EscLineClearFromCursor EscSeq = "\x1b[K"
-	// Screen
-	EscScreenClear EscSeq = "\x1b[2J"
+	// Screen. The alternate screen has no scrollback, and leaving it restores
+	// the contents of the primary screen.
+	EscScreenClear    EscSeq = "\x1b[2J"
+	EscAltScreenEnter EscSeq = "\x1b[?1049h"
+	EscAltScreenExit  EscSeq = "\x1b[?1049l"
+	// Scrolling. Scrolling shifts the content of the scroll region, which
+	// defaults to the whole screen, by the given number of lines.
+	EscScrollUp          EscSeq = "\x1b[%dS"
+	EscScrollDown        EscSeq = "\x1b[%dT"
+	EscScrollRegion      EscSeq = "\x1b[%d;%dr"
+	EscScrollRegionReset EscSeq = "\x1b[r"
+	// Keys. Terminals differ in the sequences they send for some keys, such as
+	// Home and End, which may be sent as CSI or SS3 sequences, or as VT-style
+	// "\x1b[n~" sequences, where n identifies the key.
+	EscKeyUp       EscSeq = "\x1b[A"
+	EscKeyDown     EscSeq = "\x1b[B"
+	EscKeyRight    EscSeq = "\x1b[C"
+	EscKeyLeft     EscSeq = "\x1b[D"
+	EscKeyHome     EscSeq = "\x1b[H"
+	EscKeyEnd      EscSeq = "\x1b[F"
+	EscKeyShiftTab EscSeq = "\x1b[Z"
+	EscKeyHomeSS3  EscSeq = "\x1bOH"
+	EscKeyEndSS3   EscSeq = "\x1bOF"
+	EscKeyF1SS3    EscSeq = "\x1bOP"
+	EscKeyVT       EscSeq = "\x1b[%d~"
+	// Modified keys. xterm reports keys pressed with Shift, Alt or Ctrl by
+	// adding a parameter m, where m-1 is a bitmask of the modifiers: 1 for
+	// Shift, 2 for Alt and 4 for Ctrl. For example, Ctrl-Right is "\x1b[1;5C".
+	EscKeyUpMod    EscSeq = "\x1b[1;%dA"
+	EscKeyDownMod  EscSeq = "\x1b[1;%dB"
+	EscKeyRightMod EscSeq = "\x1b[1;%dC"
+	EscKeyLeftMod  EscSeq = "\x1b[1;%dD"
+	EscKeyHomeMod  EscSeq = "\x1b[1;%dH"
+	EscKeyEndMod   EscSeq = "\x1b[1;%dF"
+	EscKeyVTMod    EscSeq = "\x1b[%d;%d~"
 )
 
+// Format returns esc with its verbs filled by args, as it would be written to a
+// terminal. For example, Format(EscCursorPosition, 3, 7) is "\x1b[3;7H".
+func Format(esc EscSeq, args ...any) string {
+	return string(AppendFormat(nil, esc, args...))
+}
+
+// AppendFormat appends esc with its verbs filled by args to dst, like
+// fmt.Appendf, and returns the extended buffer. The %d verbs with int
+// arguments and %s verbs with string arguments used by escape sequences are
+// formatted without fmt, so that writing a frame's worth of sequences doesn't
+// allocate. Anything else is left to fmt.
+func AppendFormat(dst []byte, esc EscSeq, args ...any) []byte {
+	start := len(dst)
+	argIdx := 0
+	for i := 0; i < len(esc); i++ {
+		c := esc[i]
+		if c != '%' {
+			dst = append(dst, c)
+			continue
+		}
+		if i+1 >= len(esc) || argIdx >= len(args) {
+			return fmt.Appendf(dst[:start], string(esc), args...)
+		}
+		i++
+		n, isInt := args[argIdx].(int)
+		s, isString := args[argIdx].(string)
+		switch {
+		case esc[i] == 'd' && isInt:
+			dst = strconv.AppendInt(dst, int64(n), 10)
+		case esc[i] == 's' && isString:
+			dst = append(dst, s...)
+		default:
+			return fmt.Appendf(dst[:start], string(esc), args...)
+		}
+		argIdx++
+	}
+	if argIdx != len(args) {
+		return fmt.Appendf(dst[:start], string(esc), args...)
+	}
+	return dst
+}
+
 // MaxLenBytes is the length in bytes of the longest escape sequence we intend
 // to handle. 8 bytes is longer than any kepress on a standard ~100-key QWERTY
 // keyboard.
 const MaxLenBytes = 8
+
+const (
+	esc = '\x1b'
+	bel = '\a'
+)
+
+// Strip removes ANSI escape sequences, such as SGR color codes, from rs,
+// returning the stripped runes. rs is modified in place.
+func Strip(rs []rune) []rune {
+	stripped := rs[:0]
+	for i := 0; i < len(rs); {
+		if n := sequenceLen(rs[i:]); n > 0 {
+			i += n
+			continue
+		}
+		stripped = append(stripped, rs[i])
+		i++
+	}
+	return stripped
+}
+
+// Sanitize returns the string representation of rs with each control
+// character replaced by '?', making it safe to write to a terminal without
+// affecting the terminal's state. Each rune of rs corresponds to exactly one
+// rune of the result.
+func Sanitize(rs []rune) string {
+	sanitized := make([]rune, len(rs))
+	for i, r := range rs {
+		sanitized[i] = SanitizeRune(r)
+	}
+	return string(sanitized)
+}
+
+// SanitizeRune returns '?' if r is a control character, and r otherwise. It is
+// the single-rune form of Sanitize, for writers that can't afford to allocate.
+func SanitizeRune(r rune) rune {
+	if isControl(r) {
+		return '?'
+	}
+	return r
+}
+
+// sequenceLen returns the length in runes of the escape sequence at the start
+// of rs, or 0 if rs doesn't start with an escape sequence. An unterminated
+// sequence extends to the end of rs.
+func sequenceLen(rs []rune) int {
+	if len(rs) < 2 || rs[0] != esc {
+		return 0
+	}
+	switch rs[1] {
+	case '[': // CSI: parameter and intermediate bytes followed by a final byte.
+		for i := 2; i < len(rs); i++ {
+			if rs[i] >= 0x40 && rs[i] <= 0x7e {
+				return i + 1
+			}
+			if rs[i] < 0x20 || rs[i] > 0x3f {
+				return i // malformed; strip the introducer and parameters only
+			}
+		}
+		return len(rs)
+	case ']': // OSC: terminated by BEL or ST (ESC \).
+		for i := 2; i < len(rs); i++ {
+			if rs[i] == bel {
+				return i + 1
+			}
+			if rs[i] == esc && i+1 < len(rs) && rs[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(rs)
+	default:
+		if rs[1] >= 0x40 && rs[1] <= 0x5f { // two-rune Fe sequence
+			return 2
+		}
+		return 0
+	}
+}
+
+// isControl reports whether r is a C0 or C1 control character or DEL.
+func isControl(r rune) bool {
+	return r < 0x20 || (r >= 0x7f && r < 0xa0)
+}
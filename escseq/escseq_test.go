@@ -0,0 +1,114 @@
+package escseq
+
+import "testing"
+
+func Test_Strip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "plain text",
+			s:    "hello, world",
+			want: "hello, world",
+		},
+		{
+			name: "SGR colors",
+			s:    "\x1b[1;31merror\x1b[0m: file not found",
+			want: "error: file not found",
+		},
+		{
+			name: "SGR reset without parameters",
+			s:    "\x1b[32mok\x1b[m",
+			want: "ok",
+		},
+		{
+			name: "256-color and truecolor SGR",
+			s:    "\x1b[38;5;208morange\x1b[38;2;0;255;0m green\x1b[0m",
+			want: "orange green",
+		},
+		{
+			name: "cursor movement CSI",
+			s:    "a\x1b[2Kb\x1b[10;20Hc",
+			want: "abc",
+		},
+		{
+			name: "OSC terminated by BEL",
+			s:    "\x1b]0;title\atext",
+			want: "text",
+		},
+		{
+			name: "OSC terminated by ST",
+			s:    "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\",
+			want: "link",
+		},
+		{
+			name: "unterminated CSI",
+			s:    "text\x1b[31",
+			want: "text",
+		},
+		{
+			name: "lone escape",
+			s:    "a\x1b",
+			want: "a\x1b",
+		},
+		{
+			name: "multibyte content",
+			s:    "\x1b[34mこんにちは\x1b[0m",
+			want: "こんにちは",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := string(Strip([]rune(tc.s))); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Sanitize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "plain text",
+			s:    "hello",
+			want: "hello",
+		},
+		{
+			name: "escape sequence",
+			s:    "\x1b[31mred",
+			want: "?[31mred",
+		},
+		{
+			name: "C0 and C1 controls",
+			s:    "a\x00b\x7fc\u009bd",
+			want: "a?b?c?d",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Sanitize([]rune(tc.s)); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package escseq
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const goldenFile = "../testdata/escseq_golden.txt"
+
+var update = flag.Bool("update", false, "rewrite "+goldenFile)
+
+// escSeqConsts returns the names and values of the EscSeq constants declared
+// in the package, in declaration order. Constants can't be enumerated at run
+// time, so they are read from the source.
+func escSeqConsts(t *testing.T) (names []string, values []EscSeq) {
+	t.Helper()
+
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		n, v := fileEscSeqConsts(t, f)
+		names, values = append(names, n...), append(values, v...)
+	}
+	return names, values
+}
+
+// fileEscSeqConsts returns the names and values of the EscSeq constants
+// declared in f.
+func fileEscSeqConsts(t *testing.T, f *ast.File) (names []string, values []EscSeq) {
+	t.Helper()
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if ident, ok := vs.Type.(*ast.Ident); !ok || ident.Name != "EscSeq" {
+				continue
+			}
+			for i, name := range vs.Names {
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					t.Fatalf("%s: expected a string literal", name.Name)
+				}
+				value, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Fatalf("%s: %v", name.Name, err)
+				}
+				names = append(names, name.Name)
+				values = append(values, EscSeq(value))
+			}
+		}
+	}
+	return names, values
+}
+
+// zeroArgs returns the zero value for each verb in template.
+func zeroArgs(template EscSeq) []any {
+	var args []any
+	for i := 0; i < len(template)-1; i++ {
+		if template[i] != '%' {
+			continue
+		}
+		i++
+		switch template[i] {
+		case 'd':
+			args = append(args, 0)
+		case 's':
+			args = append(args, "")
+		}
+	}
+	return args
+}
+
+// Test_golden checks every EscSeq constant against the golden file, which
+// records each sequence's name, template bytes in hex, and output formatted
+// with zero-value arguments. Run with -update after adding a sequence.
+func Test_golden(t *testing.T) {
+	t.Parallel()
+
+	names, values := escSeqConsts(t)
+	if len(names) == 0 {
+		t.Fatal("expected EscSeq constants in escseq.go")
+	}
+
+	var b strings.Builder
+	for i, name := range names {
+		formatted := Format(values[i], zeroArgs(values[i])...)
+		fmt.Fprintf(&b, "%s %s %q\n", name, hex.EncodeToString([]byte(values[i])), formatted)
+	}
+	got := b.String()
+
+	if *update {
+		if err := os.WriteFile(goldenFile, []byte(got), 0644); err != nil {
+			t.Fatalf("write %s: %v", goldenFile, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("read %s: %v", goldenFile, err)
+	}
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < max(len(gotLines), len(wantLines)); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Errorf("%s:%d: expected %q, got %q", goldenFile, i+1, w, g)
+		}
+	}
+}
+
+func Test_Format(t *testing.T) {
+	t.Parallel()
+
+	if got, want := Format(EscCursorPosition, 3, 7), "\x1b[3;7H"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := Format(EscGRendSet, "1;31"), "\x1b[1;31m"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func Test_AppendFormat(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		esc  EscSeq
+		args []any
+	}{
+		{name: "when there are no verbs it appends the sequence", esc: EscScreenClear},
+		{name: "when the args are ints it formats them", esc: EscCursorPosition, args: []any{12, -3}},
+		{name: "when the arg is a string it formats it", esc: EscGRendSet, args: []any{"1;31"}},
+		{name: "when a verb is unsupported it falls back to fmt", esc: EscSeq("\x1b[%x"), args: []any{255}},
+		{name: "when an arg has the wrong type it falls back to fmt", esc: EscCursorPosition, args: []any{"1", 2}},
+		{name: "when an arg is missing it falls back to fmt", esc: EscCursorPosition, args: []any{1}},
+		{name: "when there are extra args it falls back to fmt", esc: EscScreenClear, args: []any{1}},
+		{name: "when the sequence contains %% it falls back to fmt", esc: EscSeq("100%%")},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want := "prefix" + fmt.Sprintf(string(tc.esc), tc.args...)
+			if got := string(AppendFormat([]byte("prefix"), tc.esc, tc.args...)); got != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package escseq
+
+import (
+	"io"
+	"regexp"
+	"time"
+)
+
+// MaxLenBytesKitty is the length in bytes of the longest keypress sequence sent
+// by terminals that support the Kitty keyboard protocol.
+const MaxLenBytesKitty = 20
+
+const (
+	// queryKittyFlags asks the terminal for its current Kitty keyboard protocol
+	// flags. Terminals without Kitty support ignore it.
+	queryKittyFlags = "\x1b[?u"
+	// queryDeviceAttributes asks for the terminal's primary device attributes.
+	// Almost every terminal responds, so its response marks the end of the
+	// replies to any queries sent before it.
+	queryDeviceAttributes = "\x1b[c"
+)
+
+var (
+	kittyFlagsResponse       = regexp.MustCompile(`\x1b\[\?\d*u`)
+	deviceAttributesResponse = regexp.MustCompile(`\x1b\[\?[\d;]*c`)
+)
+
+// deadlineReader is an io.Reader whose reads can be interrupted by a deadline,
+// such as a net.Conn or an *os.File in non-blocking mode.
+type deadlineReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+// QueryMaxKeyBytes asks the terminal that reads from w and writes to r whether
+// it supports the Kitty keyboard protocol, returning MaxLenBytesKitty if it
+// does and MaxLenBytes otherwise. The terminal must be in raw mode.
+//
+// The Kitty query is followed by a primary device attributes query, whose
+// response signals that the terminal has nothing more to say. If no response
+// arrives within timeout, or an error occurs, MaxLenBytes is returned.
+//
+// The response is read with a deadline, so that no read is left waiting to
+// consume the user's input once the timeout elapses. If r doesn't support
+// read deadlines, like a terminal in blocking mode, the terminal isn't queried
+// and MaxLenBytes is returned.
+func QueryMaxKeyBytes(r io.Reader, w io.Writer, timeout time.Duration) int {
+	dr, ok := r.(deadlineReader)
+	if !ok {
+		return MaxLenBytes
+	}
+	if err := dr.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return MaxLenBytes // deadlines unsupported by this reader
+	}
+	defer dr.SetReadDeadline(time.Time{})
+
+	if _, err := io.WriteString(w, queryKittyFlags+queryDeviceAttributes); err != nil {
+		return MaxLenBytes
+	}
+	return readQueryResponse(dr)
+}
+
+// readQueryResponse reads from r until it sees a response to the device
+// attributes query, returning MaxLenBytesKitty if a Kitty flags response
+// preceded it.
+func readQueryResponse(r io.Reader) int {
+	var response []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		response = append(response, buf[:n]...)
+		if loc := deviceAttributesResponse.FindIndex(response); loc != nil {
+			if kittyFlagsResponse.Match(response[:loc[0]]) {
+				return MaxLenBytesKitty
+			}
+			return MaxLenBytes
+		}
+		if err != nil {
+			return MaxLenBytes
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package escseq
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// terminalPipe returns the ends of a pipe that stands in for a terminal in
+// non-blocking mode: in supports read deadlines, and out receives the
+// terminal's output.
+func terminalPipe(t *testing.T) (in, out *os.File) {
+	t.Helper()
+
+	in, out, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create pipe: %v", err)
+	}
+	t.Cleanup(func() {
+		in.Close()
+		out.Close()
+	})
+	return in, out
+}
+
+func Test_QueryMaxKeyBytes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		responses []string
+		// Whether the terminal closes its output after responding.
+		close bool
+		want  int
+	}{
+		{
+			name:      "when the terminal reports Kitty flags it returns MaxLenBytesKitty",
+			responses: []string{"\x1b[?0u\x1b[?62;22c"},
+			want:      MaxLenBytesKitty,
+		},
+		{
+			name:      "when the terminal only reports device attributes it returns MaxLenBytes",
+			responses: []string{"\x1b[?62;22c"},
+			want:      MaxLenBytes,
+		},
+		{
+			name:      "when the response arrives in pieces it is reassembled",
+			responses: []string{"\x1b[?1", "u\x1b[?6", "2c"},
+			want:      MaxLenBytesKitty,
+		},
+		{
+			name:      "when the input ends without a response it returns MaxLenBytes",
+			responses: []string{"\x1b[?0u"},
+			close:     true,
+			want:      MaxLenBytes,
+		},
+		{
+			name: "when the terminal doesn't respond it returns MaxLenBytes",
+			want: MaxLenBytes,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			in, out := terminalPipe(t)
+			go func() {
+				for _, response := range tc.responses {
+					out.WriteString(response)
+					time.Sleep(time.Millisecond)
+				}
+				if tc.close {
+					out.Close()
+				}
+			}()
+
+			var w bytes.Buffer
+			got := QueryMaxKeyBytes(in, &w, 50*time.Millisecond)
+			if got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+			if want := queryKittyFlags + queryDeviceAttributes; w.String() != want {
+				t.Errorf("expected query %q, got %q", want, w.String())
+			}
+		})
+	}
+}
+
+func Test_QueryMaxKeyBytes_timeout(t *testing.T) {
+	t.Parallel()
+
+	in, out := terminalPipe(t)
+	var w bytes.Buffer
+	if got := QueryMaxKeyBytes(in, &w, 10*time.Millisecond); got != MaxLenBytes {
+		t.Errorf("expected %d, got %d", MaxLenBytes, got)
+	}
+
+	// A key pressed after the timeout is left for the key reader.
+	if _, err := out.WriteString("x"); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	buf := make([]byte, 8)
+	n, err := in.Read(buf)
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
+	if got := string(buf[:n]); got != "x" {
+		t.Errorf("expected the key %q to be read, got %q", "x", got)
+	}
+}
+
+func Test_QueryMaxKeyBytes_noDeadlines(t *testing.T) {
+	t.Parallel()
+
+	var w bytes.Buffer
+	got := QueryMaxKeyBytes(strings.NewReader("\x1b[?0u\x1b[?62;22c"), &w, 50*time.Millisecond)
+	if got != MaxLenBytes {
+		t.Errorf("expected %d, got %d", MaxLenBytes, got)
+	}
+	if w.Len() != 0 {
+		t.Errorf("expected no query without read deadlines, got %q", w.String())
+	}
+}
@@ -0,0 +1,50 @@
+// Package find searches for text across several buffers at once.
+package find
+
+// Line is a line of text that can be searched, such as an *editor.Line.
+type Line interface {
+	// SearchAll returns the 0-indexed rune positions of every non-overlapping
+	// occurrence of pattern in the line.
+	SearchAll(pattern []rune) []int
+	String() string
+}
+
+// SearchHit is an occurrence of a query in one of the buffers searched.
+type SearchHit struct {
+	// BufferIndex is the index of the buffer in Finder.Buffers.
+	BufferIndex int
+	// Line and Col are the 1-indexed line and rune column of the start of the
+	// hit.
+	Line, Col int
+	// Context is the full text of the line.
+	Context string
+}
+
+// Finder searches each of its buffers, given as their lines.
+type Finder[L Line] struct {
+	Buffers [][]L
+}
+
+// SearchAll returns every non-overlapping occurrence of query in the buffers,
+// ordered by buffer, line and column. The search is case-sensitive, and an
+// empty query is never found.
+func (f Finder[L]) SearchAll(query string) []SearchHit {
+	pattern := []rune(query)
+	if len(pattern) == 0 {
+		return nil
+	}
+	var hits []SearchHit
+	for i, lines := range f.Buffers {
+		for j, line := range lines {
+			cols := line.SearchAll(pattern)
+			if len(cols) == 0 {
+				continue
+			}
+			context := line.String()
+			for _, col := range cols {
+				hits = append(hits, SearchHit{BufferIndex: i, Line: j + 1, Col: col + 1, Context: context})
+			}
+		}
+	}
+	return hits
+}
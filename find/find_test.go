@@ -0,0 +1,74 @@
+package find
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeLine is a Line backed by a string.
+type fakeLine string
+
+func (l fakeLine) SearchAll(pattern []rune) []int {
+	var cols []int
+	s, p := []rune(string(l)), string(pattern)
+	for i := 0; i+len(pattern) <= len(s); {
+		if string(s[i:i+len(pattern)]) == p {
+			cols = append(cols, i)
+			i += len(pattern)
+			continue
+		}
+		i++
+	}
+	return cols
+}
+
+func (l fakeLine) String() string {
+	return string(l)
+}
+
+func Test_Finder_SearchAll(t *testing.T) {
+	t.Parallel()
+
+	f := Finder[fakeLine]{
+		Buffers: [][]fakeLine{
+			{"foo bar foo", "baz"},
+			{},
+			{"qux", "bar foo"},
+		},
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		want  []SearchHit
+	}{
+		{
+			name:  "when the query occurs in several buffers it returns every hit in order",
+			query: "foo",
+			want: []SearchHit{
+				{BufferIndex: 0, Line: 1, Col: 1, Context: "foo bar foo"},
+				{BufferIndex: 0, Line: 1, Col: 9, Context: "foo bar foo"},
+				{BufferIndex: 2, Line: 2, Col: 5, Context: "bar foo"},
+			},
+		},
+		{
+			name:  "when the query doesn't occur it returns no hits",
+			query: "quux",
+		},
+		{
+			name: "when the query is empty it returns no hits",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := f.SearchAll(tc.query); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected hits %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -1,18 +1,28 @@
 // Package intutil provides integer utilities.
 package intutil
 
+import "cmp"
+
 // Min returns the minimum of a and b.
+//
+// Deprecated: Use the built-in min function.
 func Min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	return min(a, b)
 }
 
 // Max returns the maximum of a and b.
+//
+// Deprecated: Use the built-in max function.
 func Max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
+	return max(a, b)
+}
+
+// Clamp returns v limited to the range [lo, hi]. If lo > hi, hi is returned.
+func Clamp(v, lo, hi int) int {
+	return ClampOrdered(v, lo, hi)
+}
+
+// ClampOrdered is the generic form of Clamp, for any ordered type.
+func ClampOrdered[T cmp.Ordered](v, lo, hi T) T {
+	return min(max(v, lo), hi)
 }
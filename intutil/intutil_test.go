@@ -23,3 +23,44 @@ func Test_Max(t *testing.T) {
 		t.Error("Max(2, 1) != 2")
 	}
 }
+
+func Test_Clamp(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		v, lo, hi int
+		want      int
+	}{
+		{name: "when v is below the range it returns lo", v: -3, lo: 1, hi: 10, want: 1},
+		{name: "when v is in the range it returns v", v: 5, lo: 1, hi: 10, want: 5},
+		{name: "when v is lo it returns v", v: 1, lo: 1, hi: 10, want: 1},
+		{name: "when v is hi it returns v", v: 10, lo: 1, hi: 10, want: 10},
+		{name: "when v is above the range it returns hi", v: 42, lo: 1, hi: 10, want: 10},
+		{name: "when lo == hi it returns lo", v: 7, lo: 3, hi: 3, want: 3},
+		{name: "when lo > hi it returns hi", v: 7, lo: 5, hi: 2, want: 2},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Clamp(tc.v, tc.lo, tc.hi); got != tc.want {
+				t.Errorf("Clamp(%d, %d, %d): expected %d, got %d", tc.v, tc.lo, tc.hi, tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_ClampOrdered(t *testing.T) {
+	t.Parallel()
+
+	if got := ClampOrdered(1.5, 0, 1); got != 1 {
+		t.Errorf("ClampOrdered(1.5, 0, 1): expected 1, got %v", got)
+	}
+	if got := ClampOrdered("m", "a", "k"); got != "k" {
+		t.Errorf(`ClampOrdered("m", "a", "k"): expected "k", got %q`, got)
+	}
+}
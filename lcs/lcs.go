@@ -0,0 +1,114 @@
+// Package lcs computes the differences between two sequences of lines using
+// their longest common subsequence.
+package lcs
+
+// OpKind identifies the kind of edit represented by a DiffOp.
+type OpKind int
+
+const (
+	// Equal indicates that a line is common to both sequences.
+	Equal OpKind = iota
+	// Delete indicates that a line of the first sequence is absent from the
+	// second.
+	Delete
+	// Insert indicates that a line of the second sequence is absent from the
+	// first.
+	Insert
+)
+
+// maxTableCells bounds the size of the dynamic programming table used to
+// compute the LCS, which is quadratic in the length of the sequences. Beyond
+// this limit, the differing region is reported as wholly deleted and
+// reinserted.
+const maxTableCells = 1 << 22
+
+// DiffOp is a single step in an edit script that transforms sequence a into
+// sequence b.
+type DiffOp struct {
+	Kind OpKind
+	// A is the index of the line in a for Equal and Delete ops, and -1 for
+	// Insert ops.
+	A int
+	// B is the index of the line in b for Equal and Insert ops, and -1 for
+	// Delete ops.
+	B int
+}
+
+// LCS returns an edit script that transforms a into b, preserving their
+// longest common subsequence. Within a changed region, deletions precede
+// insertions.
+func LCS(a, b []string) []DiffOp {
+	ops := make([]DiffOp, 0, max(len(a), len(b)))
+
+	// Common prefixes and suffixes are trivially part of the LCS, and
+	// trimming them keeps the table small for typical, localized edits.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, DiffOp{Kind: Equal, A: prefix, B: prefix})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops = appendMiddle(ops, a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)
+
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, DiffOp{Kind: Equal, A: len(a) - suffix + i, B: len(b) - suffix + i})
+	}
+	return ops
+}
+
+// appendMiddle appends the edit script transforming a into b to ops, where a
+// and b start at index offset of their parent sequences.
+func appendMiddle(ops []DiffOp, a, b []string, offset int) []DiffOp {
+	if len(a)*len(b) > maxTableCells {
+		for i := range a {
+			ops = append(ops, DiffOp{Kind: Delete, A: offset + i, B: -1})
+		}
+		for j := range b {
+			ops = append(ops, DiffOp{Kind: Insert, A: -1, B: offset + j})
+		}
+		return ops
+	}
+
+	// table[i][j] holds the length of the LCS of a[i:] and b[j:].
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffOp{Kind: Equal, A: offset + i, B: offset + j})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = append(ops, DiffOp{Kind: Delete, A: offset + i, B: -1})
+			i++
+		default:
+			ops = append(ops, DiffOp{Kind: Insert, A: -1, B: offset + j})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, DiffOp{Kind: Delete, A: offset + i, B: -1})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, DiffOp{Kind: Insert, A: -1, B: offset + j})
+	}
+	return ops
+}
@@ -0,0 +1,99 @@
+package lcs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_LCS(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		a, b []string
+		want []DiffOp
+	}{
+		{
+			name: "both empty",
+			a:    nil,
+			b:    nil,
+			want: []DiffOp{},
+		},
+		{
+			name: "no change",
+			a:    []string{"a", "b"},
+			b:    []string{"a", "b"},
+			want: []DiffOp{
+				{Kind: Equal, A: 0, B: 0},
+				{Kind: Equal, A: 1, B: 1},
+			},
+		},
+		{
+			name: "pure insertion",
+			a:    []string{"a", "c"},
+			b:    []string{"a", "b", "c"},
+			want: []DiffOp{
+				{Kind: Equal, A: 0, B: 0},
+				{Kind: Insert, A: -1, B: 1},
+				{Kind: Equal, A: 1, B: 2},
+			},
+		},
+		{
+			name: "pure deletion",
+			a:    []string{"a", "b", "c"},
+			b:    []string{"a", "c"},
+			want: []DiffOp{
+				{Kind: Equal, A: 0, B: 0},
+				{Kind: Delete, A: 1, B: -1},
+				{Kind: Equal, A: 2, B: 1},
+			},
+		},
+		{
+			name: "modification",
+			a:    []string{"a", "b", "c"},
+			b:    []string{"a", "B", "c"},
+			want: []DiffOp{
+				{Kind: Equal, A: 0, B: 0},
+				{Kind: Delete, A: 1, B: -1},
+				{Kind: Insert, A: -1, B: 1},
+				{Kind: Equal, A: 2, B: 2},
+			},
+		},
+		{
+			name: "mixed changes around common lines",
+			a:    []string{"x", "a", "b", "y", "c"},
+			b:    []string{"a", "z", "b", "c", "w"},
+			want: []DiffOp{
+				{Kind: Delete, A: 0, B: -1},
+				{Kind: Equal, A: 1, B: 0},
+				{Kind: Insert, A: -1, B: 1},
+				{Kind: Equal, A: 2, B: 2},
+				{Kind: Delete, A: 3, B: -1},
+				{Kind: Equal, A: 4, B: 3},
+				{Kind: Insert, A: -1, B: 4},
+			},
+		},
+		{
+			name: "everything replaced",
+			a:    []string{"a", "b"},
+			b:    []string{"c"},
+			want: []DiffOp{
+				{Kind: Delete, A: 0, B: -1},
+				{Kind: Delete, A: 1, B: -1},
+				{Kind: Insert, A: -1, B: 0},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := LCS(tc.a, tc.b); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
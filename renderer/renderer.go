@@ -3,16 +3,25 @@
 package renderer
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/angusgmorrison/gila/editor"
 	"github.com/angusgmorrison/gila/escseq"
-	"github.com/angusgmorrison/gila/intutil"
 )
 
-const statusMsgMaxDuration = 3 * time.Second
+const (
+	defaultStatusMsgDuration = 3 * time.Second
+	helpTitle                = " Help "
+	helpFooter               = "Press any key to close"
+	// noFileType is shown in the status bar when the file type is unknown.
+	noFileType = "no ft"
+)
 
 // TerminalWriter writes output to a terminal-like device.
 type TerminalWriter interface {
@@ -30,43 +39,205 @@ type Screen struct {
 	Width, Height int
 }
 
+// CursorStyle is the shape of the terminal cursor.
+type CursorStyle int
+
+const (
+	// CursorDefault leaves the terminal's cursor style unchanged.
+	CursorDefault CursorStyle = iota
+	CursorBlinkBlock
+	CursorSteadyBlock
+	CursorBlinkUnderline
+	CursorSteadyUnderline
+	CursorBlinkBar
+	CursorSteadyBar
+)
+
+// cursorStyleSeqs maps each cursor style to the escape sequence that selects
+// it.
+var cursorStyleSeqs = map[CursorStyle]escseq.EscSeq{
+	CursorBlinkBlock:      escseq.EscCursorBlinkBlock,
+	CursorSteadyBlock:     escseq.EscCursorSteadyBlock,
+	CursorBlinkUnderline:  escseq.EscCursorBlinkUnderline,
+	CursorSteadyUnderline: escseq.EscCursorSteadyUnderline,
+	CursorBlinkBar:        escseq.EscCursorBlinkBar,
+	CursorSteadyBar:       escseq.EscCursorSteadyBar,
+}
+
+// Config contains renderer configuration data.
+type Config struct {
+	// StatusMsgDuration is how long a status message is displayed for, unless
+	// overridden by the frame being rendered. Defaults to 3 seconds.
+	StatusMsgDuration time.Duration
+	// CursorStyle is the shape of the cursor while the editor is running.
+	// When the screen is cleared on exit, the terminal's default style is
+	// restored.
+	CursorStyle CursorStyle
+	// Theme sets the colors of the text and status bar. The zero Theme uses
+	// the terminal's colors.
+	Theme Theme
+}
+
 // Renderer satisfies editor.Renderer, formatting content and writing to its
 // underlying TerminalWriter.
+//
+// Render and Clear must be called from a single goroutine, but Resize may be
+// called from any goroutine, such as one handling SIGWINCH.
 type Renderer struct {
-	about  string
-	w      TerminalWriter
+	about string
+	w     TerminalWriter
+	// mu guards screen and prevRows against concurrent calls to Resize.
+	mu     sync.RWMutex
 	screen Screen
+	// resized receives a value after each call to Resize that hasn't yet been
+	// noticed.
+	resized chan struct{}
+	config  Config
+	// clock returns the current time. If nil, time.Now is used.
+	clock func() time.Time
+	// The rows of content drawn by the previous frame and the line offset
+	// they were drawn at, used to scroll the screen instead of redrawing it.
+	// prevRows is nil if the screen's content is unknown.
+	prevRows       []string
+	prevLineOffset int
+	// rowBuf is reused to render the rows of each frame.
+	rowBuf rowBuffer
+	// The most recently rendered status bar and about message, and the
+	// inputs they were rendered from, reused while those are unchanged.
+	statusCache statusCache
+	aboutText   string
+	aboutWidth  int
+	// styles applies config.Theme.
+	styles themeStyles
 }
 
-var _ editor.Renderer = (*Renderer)(nil)
+// statusCache holds a rendered status bar along with the status and width it
+// was rendered for.
+type statusCache struct {
+	status status
+	width  int
+	text   string
+}
+
+var (
+	_ editor.Renderer = (*Renderer)(nil)
+	_ editor.Resizer  = (*Renderer)(nil)
+)
 
-func New(name, version string, tw TerminalWriter, screen Screen) *Renderer {
+func New(name, version string, tw TerminalWriter, screen Screen, config Config) *Renderer {
 	screen.Height -= 2 // reserve two lines for status and message bars
+	if config.StatusMsgDuration <= 0 {
+		config.StatusMsgDuration = defaultStatusMsgDuration
+	}
 	return &Renderer{
-		about:  fmt.Sprintf("%s -- version %s", name, version),
-		w:      tw,
-		screen: screen,
+		about:   fmt.Sprintf("%s -- version %s", name, version),
+		w:       tw,
+		screen:  screen,
+		resized: make(chan struct{}, 1),
+		config:  config,
+		clock:   time.Now,
+		styles:  config.Theme.styles(),
 	}
 }
 
+// Resize sets the size of the screen, including the status and message bars,
+// and forces the next frame to be drawn in full. It notifies ResizeNotifier so
+// that the editor can redraw the screen without waiting for a keypress.
+func (r *Renderer) Resize(w, h int) {
+	r.mu.Lock()
+	r.screen = Screen{Width: w, Height: h - 2} // reserve two lines for status and message bars
+	r.prevRows = nil
+	r.mu.Unlock()
+
+	select {
+	case r.resized <- struct{}{}:
+	default: // a notification is already pending
+	}
+}
+
+// ResizeNotifier returns a channel that receives a value after the screen is
+// resized. Resizes that occur before the value is received are coalesced.
+func (r *Renderer) ResizeNotifier() <-chan struct{} {
+	return r.resized
+}
+
+// Size returns the size of the screen, including the status and message bars.
+func (r *Renderer) Size() (w, h int) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.screen.Width, r.screen.Height + 2
+}
+
+// Reconfigure replaces the renderer's configuration, such as after the config
+// file is reloaded, and forces the next frame to be drawn in full. Like Render,
+// it must not be called concurrently with Render or Clear.
+func (r *Renderer) Reconfigure(config Config) {
+	if config.StatusMsgDuration <= 0 {
+		config.StatusMsgDuration = defaultStatusMsgDuration
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.config = config
+	r.styles = config.Theme.styles()
+	r.prevRows = nil
+	r.statusCache = statusCache{}
+}
+
 // Render a complete frame to the renderer's TerminalWriter.
 func (r *Renderer) Render(frame editor.Frame) error {
+	// Only Resize writes to the screen concurrently, so a read lock suffices.
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if seq, ok := cursorStyleSeqs[r.config.CursorStyle]; ok {
+		if _, err := r.w.WriteEscapeSequence(seq); err != nil {
+			return err
+		}
+	}
 	if _, err := r.w.WriteEscapeSequence(escseq.EscCursorHide); err != nil {
 		return err
 	}
 	if _, err := r.w.WriteEscapeSequence(escseq.EscCursorTopLeft); err != nil {
 		return err
 	}
-	if err := r.renderPage(frame.Cursor, frame.Lines); err != nil {
+	if r.styles.text != "" {
+		if _, err := r.w.WriteEscapeSequence(escseq.EscGRendSet, r.styles.text); err != nil {
+			return err
+		}
+	}
+	if err := r.renderPage(frame); err != nil {
 		return err
 	}
-	if err := r.renderStatusBar(frame.Filename, frame.Cursor.Line(), len(frame.Lines), frame.Dirty); err != nil {
+	if err := r.renderStatusBar(frame); err != nil {
 		return err
 	}
-	if err := r.renderMessageBar(frame.StatusMsg, frame.LastStatusTime); err != nil {
+	statusMsgDuration := frame.StatusMsgDuration
+	if statusMsgDuration <= 0 {
+		statusMsgDuration = r.config.StatusMsgDuration
+	}
+	msg, msgTime := frame.StatusMsg, frame.LastStatusTime
+	if r.now().Sub(msgTime) >= statusMsgDuration {
+		// Once the status message expires, show any message annotating the
+		// cursor's line.
+		if annMsg := annotationMessage(frame.Annotations, frame.Cursor.Line()); annMsg != "" {
+			msg, msgTime = annMsg, r.now()
+		}
+	}
+	if err := r.renderMessageBar(msg, msgTime, statusMsgDuration); err != nil {
 		return err
 	}
-	if _, err := r.w.WriteEscapeSequence(escseq.EscCursorPosition, frame.Cursor.Y(), frame.Cursor.X()); err != nil {
+	if len(frame.Help) > 0 {
+		if err := r.renderHelp(frame.Help); err != nil {
+			return err
+		}
+		r.prevRows = nil // the overlay hides the content
+	}
+	x := frame.Cursor.X()
+	if frame.Gutter {
+		x += editor.GutterWidth
+	}
+	if _, err := r.w.WriteEscapeSequence(escseq.EscCursorPosition, frame.Cursor.Y(), x); err != nil {
 		return err
 	}
 	if _, err := r.w.WriteEscapeSequence(escseq.EscCursorShow); err != nil {
@@ -75,68 +246,222 @@ func (r *Renderer) Render(frame editor.Frame) error {
 	return r.w.Flush()
 }
 
-// Clear wipes the terminal represented the renderer's TerminalWriter.
+// RenderProgress overwrites the message bar with label, a bar of ▓ and ░
+// proportional to done/total and the percentage complete. The rest of the
+// screen and the cursor are left as they are until the next frame is rendered.
+func (r *Renderer) RenderProgress(done, total int, label string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	err := r.withCursorSaved(func() error {
+		if _, err := r.w.WriteEscapeSequence(escseq.EscCursorPosition, r.screen.Height+2, 1); err != nil {
+			return err
+		}
+		if _, err := r.w.WriteString(progressBar(done, total, label, r.screen.Width)); err != nil {
+			return err
+		}
+		_, err := r.w.WriteEscapeSequence(escseq.EscLineClearFromCursor)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+// withCursorSaved calls write, restoring the cursor to its current position
+// afterwards, so that transient output can be drawn anywhere on screen without
+// disturbing the cursor. The cursor is restored even if write fails.
+func (r *Renderer) withCursorSaved(write func() error) error {
+	if _, err := r.w.WriteEscapeSequence(escseq.EscCursorSave); err != nil {
+		return err
+	}
+	writeErr := write()
+	if _, err := r.w.WriteEscapeSequence(escseq.EscCursorRestore); err != nil && writeErr == nil {
+		return err
+	}
+	return writeErr
+}
+
+// progressBar returns label followed by a bar showing the proportion done of
+// total and the percentage complete, filling width columns. If width is too
+// narrow for the bar, the label is truncated.
+func progressBar(done, total int, label string, width int) string {
+	percent := 0
+	if total > 0 {
+		percent = 100 * min(max(done, 0), total) / total
+	}
+	suffix := fmt.Sprintf(" %3d%%", percent)
+	barWidth := width - utf8.RuneCountInString(label) - 1 - len(suffix)
+	if barWidth < 1 {
+		runes := []rune(label)
+		return string(runes[:min(len(runes), width)])
+	}
+	filled := barWidth * percent / 100
+	return label + " " + strings.Repeat("▓", filled) + strings.Repeat("░", barWidth-filled) + suffix
+}
+
+// Clear wipes the terminal represented the renderer's TerminalWriter. If the
+// terminal is showing its alternate screen, Clear switches back to the primary
+// screen, restoring the user's previous terminal content.
 func (r *Renderer) Clear() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.prevRows = nil
+	if r.styles.text != "" {
+		if _, err := r.w.WriteEscapeSequence(escseq.EscGRendRestore); err != nil {
+			return err
+		}
+	}
+	if r.config.CursorStyle != CursorDefault {
+		if _, err := r.w.WriteEscapeSequence(escseq.EscCursorDefaultStyle); err != nil {
+			return err
+		}
+	}
 	if _, err := r.w.WriteEscapeSequence(escseq.EscScreenClear); err != nil {
 		return err
 	}
 	if _, err := r.w.WriteEscapeSequence(escseq.EscCursorTopLeft); err != nil {
 		return err
 	}
+	if _, err := r.w.WriteEscapeSequence(escseq.EscAltScreenExit); err != nil {
+		return err
+	}
 	return r.w.Flush()
 }
 
-// renderPage renders a full page of text to w. If lines is empty, it renders the homepage.
-func (r *Renderer) renderPage(cursor *editor.Cursor, lines []*editor.Line) error {
-	if len(lines) == 0 {
+// Invalidate causes the next frame to be drawn in full, rather than only the
+// rows that have changed since the previous frame.
+func (r *Renderer) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prevRows = nil
+}
+
+// renderPage renders a full page of text to w. If the frame has no lines, it
+// renders the homepage.
+func (r *Renderer) renderPage(frame editor.Frame) error {
+	if len(frame.Lines) == 0 {
+		r.prevRows = nil
 		return r.renderHomepage()
 	}
-	return r.renderContent(cursor, lines)
+	if frame.SoftWrap {
+		r.prevRows = nil
+		return r.renderWrappedContent(frame)
+	}
+	return r.renderContent(frame)
 }
 
 // renderStatusBar renders a status bar in the second-last row of the screen. It
-// renders the filename, current line number and total lines in inverted colors.
-func (r *Renderer) renderStatusBar(filename string, line, totalLines int, dirty bool) error {
-	if _, err := r.w.WriteEscapeSequence(escseq.EscGRendInvertColors); err != nil {
+// renders the filename, modification status and macro recording indicator on
+// the left and the file type, cursor position, total lines and scroll position
+// on the right, in the theme's status bar colors, or in inverted colors if the
+// theme doesn't set them. If the screen is too narrow to fit both, the
+// right-hand side is truncated.
+func (r *Renderer) renderStatusBar(frame editor.Frame) error {
+	if r.styles.statusBar != "" {
+		if _, err := r.w.WriteEscapeSequence(escseq.EscGRendSet, r.styles.statusBar); err != nil {
+			return err
+		}
+	} else if _, err := r.w.WriteEscapeSequence(escseq.EscGRendInvertColors); err != nil {
 		return err
 	}
-
-	var modified string
-	if dirty {
-		modified = "(modified)"
+	s := status{
+		filename:   frame.Filename,
+		fileType:   frame.FileType,
+		line:       frame.Cursor.Line(),
+		col:        frame.Cursor.Col(),
+		totalLines: len(frame.Lines),
+		scroll:     scrollPosition(frame.Cursor.LineOffset(), r.screen.Height, len(frame.Lines)),
+		dirty:      frame.Dirty,
+		recording:  frame.Recording,
+		readOnly:   frame.ReadOnly,
+		showWords:  frame.ShowWordCount,
+		words:      frame.Words,
+	}
+	if s != r.statusCache.status || r.screen.Width != r.statusCache.width || r.statusCache.text == "" {
+		r.statusCache = statusCache{status: s, width: r.screen.Width, text: statusBar(s, r.screen.Width)}
 	}
-	lhs := fmt.Sprintf(" %.20s - %d lines %s", filename, totalLines, modified)
-	maxLHSLen := intutil.Min(len(lhs), r.screen.Width-1) // leave room for at least one padding space on RHS
-	if _, err := r.w.WriteString(lhs[:maxLHSLen]); err != nil {
+	if _, err := r.w.WriteString(r.statusCache.text); err != nil {
 		return err
 	}
+	if err := r.restoreTextColors(); err != nil {
+		return err
+	}
+	return r.renderNewLine()
+}
 
-	rhs := fmt.Sprintf("%d/%d ", line, totalLines)
-	for i := maxLHSLen; i < r.screen.Width; {
-		if r.screen.Width-i == len(rhs) {
-			if _, err := r.w.WriteString(rhs); err != nil {
-				return err
-			}
-			break
-		} else {
-			if err := r.w.WriteByte(' '); err != nil {
-				return err
-			}
-			i++
-		}
+// scrollPosition describes how far through a document of totalLines the screen
+// is scrolled, like vi: "All" if the whole document is visible, "Top" or "Bot"
+// if its first or last line is, and otherwise the percentage of the lines not
+// shown that are above the screen. lineOffset is the number of lines above the
+// screen and height is the number of lines on it.
+func scrollPosition(lineOffset, height, totalLines int) string {
+	above := lineOffset
+	below := max(0, totalLines-lineOffset-height)
+	switch {
+	case above == 0 && below == 0:
+		return "All"
+	case above == 0:
+		return "Top"
+	case below == 0:
+		return "Bot"
 	}
+	return fmt.Sprintf("%d%%", above*100/(above+below))
+}
 
-	if _, err := r.w.WriteEscapeSequence(escseq.EscGRendRestore); err != nil {
-		return err
+// status holds the information displayed in the status bar.
+type status struct {
+	filename              string
+	fileType              string
+	scroll                string
+	line, col, totalLines int
+	dirty, recording      bool
+	readOnly              bool
+	showWords             bool
+	words                 int
+}
+
+// statusBar returns the text of the status bar padded or truncated to width.
+func statusBar(s status, width int) string {
+	lhs := fmt.Sprintf(" %.20s", s.filename)
+	if s.readOnly {
+		lhs += " [READ-ONLY]"
 	}
-	return r.renderNewLine()
+	if s.dirty {
+		lhs += " (modified)"
+	}
+	if s.recording {
+		lhs += " REC"
+	}
+	maxLHSLen := max(0, min(len(lhs), width-1)) // leave room for at least one padding space on RHS
+	lhs = lhs[:maxLHSLen]
+
+	fileType := s.fileType
+	if fileType == "" {
+		fileType = noFileType
+	}
+	rhs := fileType + " | "
+	if s.showWords {
+		rhs += fmt.Sprintf("%d words | ", s.words)
+	}
+	rhs += fmt.Sprintf("%d:%d | %d lines ", s.line, s.col, s.totalLines)
+	if s.scroll != "" {
+		rhs += "| " + s.scroll + " "
+	}
+	available := max(0, width-len(lhs))
+	rhs = rhs[:min(len(rhs), available)]
+	padding := strings.Repeat(" ", available-len(rhs))
+	return lhs + padding + rhs
 }
 
 // renderMessageBar renders a status message bar in the last row of the screen,
-// provided that the status message has not yet expired.
-func (r *Renderer) renderMessageBar(msg string, lastStatusTime time.Time) error {
-	maxLen := intutil.Min(len(msg), r.screen.Width)
-	if maxLen > 0 && time.Since(lastStatusTime) < statusMsgMaxDuration {
+// provided that the status message was set less than duration ago.
+func (r *Renderer) renderMessageBar(msg string, lastStatusTime time.Time, duration time.Duration) error {
+	maxLen := min(len(msg), r.screen.Width)
+	if maxLen > 0 && r.now().Sub(lastStatusTime) < duration {
 		if _, err := r.w.WriteString(msg[:maxLen]); err != nil {
 			return err
 		}
@@ -147,6 +472,14 @@ func (r *Renderer) renderMessageBar(msg string, lastStatusTime time.Time) error
 	return nil
 }
 
+// now returns the current time according to the renderer's clock.
+func (r *Renderer) now() time.Time {
+	if r.clock == nil {
+		return time.Now()
+	}
+	return r.clock()
+}
+
 func (r *Renderer) renderHomepage() error {
 	for y := 1; y <= r.screen.Height; y++ {
 		if y == r.screen.Height/3 {
@@ -162,31 +495,239 @@ func (r *Renderer) renderHomepage() error {
 	return nil
 }
 
-func (r *Renderer) renderContent(cursor *editor.Cursor, lines []*editor.Line) error {
-	for y := 1; y <= r.screen.Height; y++ {
-		lineIdx := y + cursor.LineOffset() - 1
+// renderContent renders the rows of lines visible from the cursor's offsets.
+// If the content has only scrolled vertically since the previous frame, the
+// terminal is instructed to shift the rows already on screen, and only the
+// newly exposed rows are drawn.
+func (r *Renderer) renderContent(frame editor.Frame) error {
+	rows, err := r.contentRows(frame)
+	if err != nil {
+		return err
+	}
+	return r.drawRows(rows, frame.Cursor.LineOffset())
+}
+
+// drawRows writes rows of content rendered at lineOffset to the screen,
+// scrolling the rows of the previous frame into place or rewriting only the
+// rows that have changed where possible.
+func (r *Renderer) drawRows(rows []string, lineOffset int) error {
+	delta := lineOffset - r.prevLineOffset
+	prevRows := r.prevRows
+	r.prevRows, r.prevLineOffset = rows, lineOffset
+	if canScroll(prevRows, rows, delta) {
+		return r.scrollContent(rows, delta)
+	}
+	if prevRows != nil && len(prevRows) == len(rows) && delta == 0 {
+		return r.redrawChangedRows(prevRows, rows)
+	}
+	for _, row := range rows {
+		if _, err := r.w.WriteString(row); err != nil {
+			return fmt.Errorf("write row %q: %w", row, err)
+		}
+		if _, err := r.w.WriteString("\r\n"); err != nil {
+			return fmt.Errorf("write CRLF: %w", err)
+		}
+	}
+	return nil
+}
+
+// contentRows returns the output for each row of content, excluding the
+// trailing CRLF.
+func (r *Renderer) contentRows(frame editor.Frame) ([]string, error) {
+	// Rows are rendered to a buffer so that they can be compared with the
+	// previous frame before anything is written to the terminal.
+	w := r.w
+	defer func() { r.w = w }()
+	buf := &r.rowBuf
+	r.w = buf
+
+	anns := annotationsByLine(frame.Annotations)
+	width := textWidth(frame, r.screen.Width)
+	rows := make([]string, r.screen.Height)
+	for y := range rows {
+		buf.Reset()
+		lineIdx := y + frame.Cursor.LineOffset()
 		// We leave an empty line at the bottom of the document for the user to
 		// insert new content which is not represented in lines. Hence, we must
 		// check the lineIdx against the number of "real" lines to avoid
 		// OutOfBounds errors.
-		if lineIdx < len(lines) {
-			if err := r.renderLine(cursor, lines[lineIdx]); err != nil {
-				return err
+		if lineIdx < len(frame.Lines) {
+			lineAnns := anns[lineIdx+1]
+			if frame.Gutter {
+				if err := r.renderGutter(gutterMark(lineAnns)); err != nil {
+					return nil, err
+				}
+			}
+			runes, indices := visibleRunes(frame.Cursor, frame.Lines[lineIdx], width)
+			if err := r.renderRow(runes, indices, lineAnns); err != nil {
+				return nil, err
 			}
 		} else {
 			if err := r.renderEmptyLine(); err != nil {
+				return nil, err
+			}
+		}
+		rows[y] = strings.TrimSuffix(buf.String(), "\r\n")
+	}
+	return rows, nil
+}
+
+// canScroll reports whether rows can be drawn by shifting prevRows by delta
+// rows, because every row that remains on screen is unchanged.
+func canScroll(prevRows, rows []string, delta int) bool {
+	if prevRows == nil || len(prevRows) != len(rows) || delta == 0 ||
+		abs(delta) >= len(rows) {
+		return false
+	}
+	for y := max(0, -delta); y < min(len(rows), len(rows)-delta); y++ {
+		if rows[y] != prevRows[y+delta] {
+			return false
+		}
+	}
+	return true
+}
+
+// scrollContent shifts the content rows on screen by delta rows, then draws
+// the rows exposed at the top or bottom of the screen, leaving the cursor at
+// the start of the row below the content.
+func (r *Renderer) scrollContent(rows []string, delta int) error {
+	// Restrict scrolling to the content so that the status and message bars
+	// stay put.
+	if _, err := r.w.WriteEscapeSequence(escseq.EscScrollRegion, 1, len(rows)); err != nil {
+		return err
+	}
+	var (
+		exposed      []string
+		firstExposed int // the 0-indexed row of the first exposed row
+	)
+	if delta > 0 {
+		if _, err := r.w.WriteEscapeSequence(escseq.EscScrollUp, delta); err != nil {
+			return err
+		}
+		exposed, firstExposed = rows[len(rows)-delta:], len(rows)-delta
+	} else {
+		if _, err := r.w.WriteEscapeSequence(escseq.EscScrollDown, -delta); err != nil {
+			return err
+		}
+		exposed, firstExposed = rows[:-delta], 0
+	}
+	if _, err := r.w.WriteEscapeSequence(escseq.EscScrollRegionReset); err != nil {
+		return err
+	}
+
+	for i, row := range exposed {
+		if _, err := r.w.WriteEscapeSequence(escseq.EscCursorPosition, firstExposed+i+1, 1); err != nil {
+			return err
+		}
+		if _, err := r.w.WriteString(row); err != nil {
+			return fmt.Errorf("write row %q: %w", row, err)
+		}
+	}
+	_, err := r.w.WriteEscapeSequence(escseq.EscCursorPosition, len(rows)+1, 1)
+	return err
+}
+
+// redrawChangedRows rewrites the rows that differ from prevRows, which were
+// drawn at the same line offset, leaving the cursor at the start of the row
+// below the content.
+func (r *Renderer) redrawChangedRows(prevRows, rows []string) error {
+	for y, row := range rows {
+		if row == prevRows[y] {
+			continue
+		}
+		if _, err := r.w.WriteEscapeSequence(escseq.EscCursorPosition, y+1, 1); err != nil {
+			return err
+		}
+		if _, err := r.w.WriteString(row); err != nil {
+			return fmt.Errorf("write row %q: %w", row, err)
+		}
+	}
+	_, err := r.w.WriteEscapeSequence(escseq.EscCursorPosition, len(rows)+1, 1)
+	return err
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// rowBuffer is a TerminalWriter that accumulates output in memory.
+type rowBuffer struct {
+	bytes.Buffer
+}
+
+func (b *rowBuffer) Flush() error {
+	return nil
+}
+
+func (b *rowBuffer) WriteEscapeSequence(esc escseq.EscSeq, args ...any) (int, error) {
+	return b.Write(escseq.AppendFormat(b.AvailableBuffer(), esc, args...))
+}
+
+// renderWrappedContent renders lines starting from the cursor's line offset,
+// wrapping each line across as many rows as it needs to fit the screen width.
+func (r *Renderer) renderWrappedContent(frame editor.Frame) error {
+	anns := annotationsByLine(frame.Annotations)
+	width := textWidth(frame, r.screen.Width)
+	y := 1
+	for lineIdx := frame.Cursor.LineOffset(); y <= r.screen.Height; lineIdx++ {
+		if lineIdx >= len(frame.Lines) {
+			if err := r.renderEmptyLine(); err != nil {
+				return err
+			}
+			y++
+			continue
+		}
+
+		lineAnns := anns[lineIdx+1]
+		runes := frame.Lines[lineIdx].Runes()
+		widths := frame.Lines[lineIdx].RuneWidths()
+		starts := frame.Lines[lineIdx].WrapPoints(width)
+		for i := 0; i < len(starts) && y <= r.screen.Height; i++ {
+			if frame.Gutter {
+				mark := ' ' // only the first row of a line is marked
+				if i == 0 {
+					mark = gutterMark(lineAnns)
+				}
+				if err := r.renderGutter(mark); err != nil {
+					return err
+				}
+			}
+			end := len(runes)
+			if i+1 < len(starts) {
+				end = starts[i+1]
+			}
+			row := make([]rune, 0, end-starts[i])
+			indices := make([]int, 0, end-starts[i])
+			for j := starts[i]; j < end; j++ {
+				if runes[j] == '\t' {
+					for k := 0; k < widths[j]; k++ {
+						row = append(row, ' ')
+						indices = append(indices, j)
+					}
+					continue
+				}
+				row = append(row, runes[j])
+				indices = append(indices, j)
+			}
+			if err := r.renderRow(row, indices, lineAnns); err != nil {
 				return err
 			}
+			y++
 		}
 	}
 	return nil
 }
 
 func (r *Renderer) renderAbout() error {
-	about := center(r.about, r.screen.Width)
-	maxLen := intutil.Min(len(about), r.screen.Width)
-	if _, err := r.w.WriteString(about[:maxLen]); err != nil {
-		return fmt.Errorf("render about message %q: %w", about[:maxLen], err)
+	if r.aboutText == "" || r.aboutWidth != r.screen.Width {
+		about := center(r.about, r.screen.Width)
+		r.aboutText, r.aboutWidth = about[:min(len(about), r.screen.Width)], r.screen.Width
+	}
+	if _, err := r.w.WriteString(r.aboutText); err != nil {
+		return fmt.Errorf("render about message %q: %w", r.aboutText, err)
 	}
 	return nil
 }
@@ -198,20 +739,167 @@ func (r *Renderer) renderEmptyLine() error {
 	return r.renderNewLine()
 }
 
-func (r *Renderer) renderLine(cursor *editor.Cursor, line *editor.Line) error {
-	str := r.truncateLineForScreen(cursor, line)
-	if _, err := r.w.WriteString(str); err != nil {
-		return fmt.Errorf("write %q: %w", line, err)
+// renderGutter renders mark followed by padding to fill the gutter.
+func (r *Renderer) renderGutter(mark rune) error {
+	gutter := escseq.Sanitize([]rune{mark}) + strings.Repeat(" ", editor.GutterWidth-1)
+	if _, err := r.w.WriteString(gutter); err != nil {
+		return fmt.Errorf("write gutter %q: %w", gutter, err)
+	}
+	return nil
+}
+
+// renderRow renders runes followed by a new line, coloring each rune according
+// to the color spans of anns that cover its index in the line, given by the
+// corresponding element of indices. An index of -1 marks padding that is never
+// colored.
+func (r *Renderer) renderRow(runes []rune, indices []int, anns []editor.Annotation) error {
+	var activeSGR string
+	for i, rn := range runes {
+		sgr := r.styles.spanSGR(spanSGR(anns, indices[i]))
+		if sgr != activeSGR {
+			if activeSGR != "" {
+				if err := r.restoreTextColors(); err != nil {
+					return err
+				}
+			}
+			if sgr != "" {
+				if _, err := r.w.WriteEscapeSequence(escseq.EscGRendSet, sgr); err != nil {
+					return err
+				}
+			}
+			activeSGR = sgr
+		}
+		if _, err := r.w.WriteRune(escseq.SanitizeRune(rn)); err != nil {
+			return fmt.Errorf("write %q: %w", rn, err)
+		}
+	}
+	if activeSGR != "" {
+		if err := r.restoreTextColors(); err != nil {
+			return err
+		}
 	}
 	return r.renderNewLine()
 }
 
-func (r *Renderer) truncateLineForScreen(cursor *editor.Cursor, line *editor.Line) string {
-	runes := line.Runes()
-	leftMargin := intutil.Min(cursor.ColOffset(), line.RuneLen())
-	runes = runes[leftMargin:]
-	rightMargin := intutil.Min(len(runes), r.screen.Width)
-	return string(runes[:rightMargin])
+// restoreTextColors resets the graphic rendition to the theme's text colors.
+func (r *Renderer) restoreTextColors() error {
+	if _, err := r.w.WriteEscapeSequence(escseq.EscGRendRestore); err != nil {
+		return err
+	}
+	if r.styles.text == "" {
+		return nil
+	}
+	_, err := r.w.WriteEscapeSequence(escseq.EscGRendSet, r.styles.text)
+	return err
+}
+
+// visibleRunes returns the runes of line that are visible between the cursor's
+// column offset and width columns to its right, along with the index of each
+// rune in the line. Tabs are expanded to spaces. Wide characters straddling
+// either edge are replaced by spaces for the visible cells, with index -1.
+func visibleRunes(cursor *editor.Cursor, line *editor.Line, width int) ([]rune, []int) {
+	left := cursor.ColOffset()
+	right := left + width
+	visible := make([]rune, 0, width)
+	indices := make([]int, 0, width)
+	widths := line.RuneWidths()
+	var (
+		start  int  // the cell at which the current rune starts
+		hidden bool // whether the current grapheme cluster is hidden
+	)
+	for i, rn := range line.Runes() {
+		end := start + widths[i]
+		switch {
+		case widths[i] == 0 && i > 0:
+			// The rest of a cluster is shown only if the start of it is.
+			if !hidden {
+				visible = append(visible, rn)
+				indices = append(indices, i)
+			}
+		case end <= left:
+			hidden = true
+		case start >= right:
+			return visible, indices
+		case rn == '\t':
+			// Tabs are expanded to spaces so that the terminal's own tab stops
+			// don't apply.
+			for j := max(start, left); j < min(end, right); j++ {
+				visible = append(visible, ' ')
+				indices = append(indices, i)
+			}
+		case start < left || end > right:
+			hidden = true
+			for j := max(start, left); j < min(end, right); j++ {
+				visible = append(visible, ' ')
+				indices = append(indices, -1)
+			}
+		default:
+			hidden = false
+			visible = append(visible, rn)
+			indices = append(indices, i)
+		}
+		start = end
+	}
+	return visible, indices
+}
+
+// textWidth returns the number of columns of the screen available for text in
+// the given frame.
+func textWidth(frame editor.Frame, screenWidth int) int {
+	if frame.Gutter {
+		return max(0, screenWidth-editor.GutterWidth)
+	}
+	return screenWidth
+}
+
+// annotationsByLine groups annotations by the line they decorate.
+func annotationsByLine(anns []editor.Annotation) map[int][]editor.Annotation {
+	byLine := make(map[int][]editor.Annotation, len(anns))
+	for _, ann := range anns {
+		byLine[ann.Line] = append(byLine[ann.Line], ann)
+	}
+	return byLine
+}
+
+// gutterMark returns the gutter mark of the last annotation to set one, or a
+// space if there is none.
+func gutterMark(anns []editor.Annotation) rune {
+	mark := ' '
+	for _, ann := range anns {
+		if ann.Gutter != 0 {
+			mark = ann.Gutter
+		}
+	}
+	return mark
+}
+
+// spanSGR returns the SGR of the last color span in anns covering index i of
+// the line, or "" if there is none.
+func spanSGR(anns []editor.Annotation, i int) string {
+	var sgr string
+	if i < 0 {
+		return sgr
+	}
+	for _, ann := range anns {
+		for _, span := range ann.Spans {
+			if i >= span.Start && i < span.End {
+				sgr = span.SGR
+			}
+		}
+	}
+	return sgr
+}
+
+// annotationMessage returns the message of the last annotation of the 1-indexed
+// line to set one, or "" if there is none.
+func annotationMessage(anns []editor.Annotation, line int) string {
+	var msg string
+	for _, ann := range anns {
+		if ann.Line == line && ann.Message != "" {
+			msg = ann.Message
+		}
+	}
+	return msg
 }
 
 // renderNewLine clears any text to the right of the cursor position remaining
@@ -226,10 +914,69 @@ func (r *Renderer) renderNewLine() error {
 	return nil
 }
 
+// renderHelp draws a box listing the given key bindings over the center of the
+// screen. Rows and columns that don't fit on the screen are clipped.
+func (r *Renderer) renderHelp(bindings []editor.Binding) error {
+	box := helpBox(bindings)
+	// If the box is too tall for the screen, drop the last bindings rather
+	// than the footer explaining how to close it.
+	if len(box) > r.screen.Height && r.screen.Height > helpBoxTrailingRows {
+		keep := r.screen.Height - helpBoxTrailingRows
+		box = append(box[:keep:keep], box[len(box)-helpBoxTrailingRows:]...)
+	}
+	height := min(len(box), r.screen.Height)
+	top := (r.screen.Height-height)/2 + 1
+	for i, row := range box[:height] {
+		width := min(len(row), r.screen.Width)
+		left := (r.screen.Width-width)/2 + 1
+		if _, err := r.w.WriteEscapeSequence(escseq.EscCursorPosition, top+i, left); err != nil {
+			return err
+		}
+		if _, err := r.w.WriteString(row[:width]); err != nil {
+			return fmt.Errorf("render help row %q: %w", row[:width], err)
+		}
+	}
+	return nil
+}
+
+// helpBoxTrailingRows is the number of rows that follow the bindings in a help
+// box: a blank row, the footer and the bottom border.
+const helpBoxTrailingRows = 3
+
+// helpBox returns the rows of a bordered box listing each binding's keys and
+// description in aligned columns.
+func helpBox(bindings []editor.Binding) []string {
+	keysWidth := 0
+	for _, b := range bindings {
+		keysWidth = max(keysWidth, len(b.Keys))
+	}
+	entries := make([]string, 0, len(bindings))
+	innerWidth := max(len(helpTitle), len(helpFooter))
+	for _, b := range bindings {
+		entry := fmt.Sprintf("%-*s  %s", keysWidth, b.Keys, b.Description)
+		entries = append(entries, entry)
+		innerWidth = max(innerWidth, len(entry))
+	}
+
+	border := "+" + strings.Repeat("-", innerWidth+2) + "+"
+	row := func(s string) string {
+		return fmt.Sprintf("| %-*s |", innerWidth, s)
+	}
+	box := make([]string, 0, len(entries)+5)
+	box = append(box, "+-"+helpTitle+strings.Repeat("-", innerWidth+1-len(helpTitle))+"+")
+	for _, entry := range entries {
+		box = append(box, row(entry))
+	}
+	box = append(box, row(""), row(helpFooter), border)
+	return box
+}
+
 func center(s string, width int) string {
-	leftPadding := (width + len(s)) / 2
-	rightPadding := -width // Go interprets negative values as padding from the right
-	// Bring the right margin all the way over to the left, then add half
-	// (screen width + string len) to push the text into the middle.
-	return fmt.Sprintf("%*s", rightPadding, fmt.Sprintf("%*s", leftPadding, s))
+	// Pad s on the left to half (screen width + string len) to push the text
+	// into the middle, then pad the result on the right to the full width.
+	// Padding is counted in runes.
+	n := utf8.RuneCountInString(s)
+	left := max(0, (width+len(s))/2-n)
+	right := max(0, width-left-n)
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", right)
 }
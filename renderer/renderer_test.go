@@ -0,0 +1,1155 @@
+package renderer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/angusgmorrison/gila/editor"
+	"github.com/angusgmorrison/gila/escseq"
+)
+
+// fakeTerminalWriter is a TerminalWriter that accumulates output in memory.
+type fakeTerminalWriter struct {
+	bytes.Buffer
+}
+
+var _ TerminalWriter = (*fakeTerminalWriter)(nil)
+
+func (w *fakeTerminalWriter) Flush() error {
+	return nil
+}
+
+func (w *fakeTerminalWriter) WriteEscapeSequence(esc escseq.EscSeq, args ...any) (int, error) {
+	return fmt.Fprintf(&w.Buffer, string(esc), args...)
+}
+
+func Test_Renderer_Render_help(t *testing.T) {
+	t.Parallel()
+
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 80, Height: 36}, Config{})
+	frame := editor.Frame{
+		Cursor: &editor.Cursor{},
+		Help:   editor.DefaultBindings(),
+	}
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error rendering frame: %v", err)
+	}
+
+	out := w.String()
+	wantRows := []string{
+		`\+- Help -+\+`,
+		`\| Ctrl-S +Save +\|`,
+		`\| Ctrl-Q +Quit +\|`,
+		`\| Arrows +Move the cursor +\|`,
+		`\| F1 +Show this help +\|`,
+		`\| Press any key to close +\|`,
+	}
+	for _, want := range wantRows {
+		if !regexp.MustCompile(want).MatchString(out) {
+			t.Errorf("expected help box to contain a row matching %q, got\n%q", want, out)
+		}
+	}
+}
+
+func Test_Renderer_Render_helpTruncated(t *testing.T) {
+	t.Parallel()
+
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 80, Height: 10}, Config{})
+	frame := editor.Frame{
+		Cursor: &editor.Cursor{},
+		Help:   editor.DefaultBindings(),
+	}
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error rendering frame: %v", err)
+	}
+
+	out := w.String()
+	if !regexp.MustCompile(`\| Press any key to close +\|`).MatchString(out) {
+		t.Errorf("expected the footer to be shown when the help box is too tall, got\n%q", out)
+	}
+	if regexp.MustCompile(`\| F1 +Show this help +\|`).MatchString(out) {
+		t.Errorf("expected the last bindings to be dropped when the help box is too tall, got\n%q", out)
+	}
+}
+
+func Test_Renderer_Render_noHelp(t *testing.T) {
+	t.Parallel()
+
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 80, Height: 24}, Config{})
+	frame := editor.Frame{
+		Cursor: &editor.Cursor{},
+	}
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error rendering frame: %v", err)
+	}
+
+	if out := w.String(); bytes.Contains([]byte(out), []byte(helpFooter)) {
+		t.Errorf("expected no help box when Frame.Help is nil, got\n%q", out)
+	}
+}
+
+func Test_Renderer_Render_fileType(t *testing.T) {
+	t.Parallel()
+
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 80, Height: 24}, Config{})
+	frame := editor.Frame{
+		Cursor:   &editor.Cursor{},
+		Lines:    []*editor.Line{editor.NewLine("package main")},
+		Filename: "main.go",
+		FileType: "go",
+	}
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error rendering frame: %v", err)
+	}
+
+	if out := w.String(); !strings.Contains(out, "go | 0:0 | 1 lines | All") {
+		t.Errorf("expected the status bar to show the file type, got\n%q", out)
+	}
+}
+
+func Test_helpBox(t *testing.T) {
+	t.Parallel()
+
+	bindings := []editor.Binding{
+		{Keys: "Ctrl-S", Description: "Save"},
+		{Keys: "F1", Description: "Help"},
+	}
+	want := []string{
+		"+- Help -----------------+",
+		"| Ctrl-S  Save           |",
+		"| F1      Help           |",
+		"|                        |",
+		"| Press any key to close |",
+		"+------------------------+",
+	}
+	got := helpBox(bindings)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d rows, got %d: %q", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func Test_statusBar(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		s     status
+		width int
+		want  string
+	}{
+		{
+			name: "when the screen is wide enough, the RHS is flush right",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       12,
+				col:        5,
+				totalLines: 42,
+			},
+			width: 32,
+			want:  " main.go   go | 12:5 | 42 lines ",
+		},
+		{
+			name: "when the document is modified, the LHS says so",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       1,
+				col:        1,
+				totalLines: 2,
+				dirty:      true,
+			},
+			width: 40,
+			want:  " main.go (modified)  go | 1:1 | 2 lines ",
+		},
+		{
+			name: "when the document is read-only, the LHS says so",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       1,
+				col:        1,
+				totalLines: 2,
+				readOnly:   true,
+			},
+			width: 41,
+			want:  " main.go [READ-ONLY]  go | 1:1 | 2 lines ",
+		},
+		{
+			name: "when a macro is being recorded, the LHS says so",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       1,
+				col:        1,
+				totalLines: 2,
+				recording:  true,
+			},
+			width: 33,
+			want:  " main.go REC  go | 1:1 | 2 lines ",
+		},
+		{
+			name: "when the word count is shown, the RHS includes it",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       1,
+				col:        1,
+				totalLines: 2,
+				showWords:  true,
+				words:      7,
+			},
+			width: 39,
+			want:  " main.go  go | 7 words | 1:1 | 2 lines ",
+		},
+		{
+			name: "when the scroll position is known, the RHS ends with it",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       12,
+				col:        5,
+				totalLines: 42,
+				scroll:     "Top",
+			},
+			width: 38,
+			want:  " main.go   go | 12:5 | 42 lines | Top ",
+		},
+		{
+			name: "when the file type is unknown, the RHS says so",
+			s: status{
+				filename:   "notes",
+				line:       1,
+				col:        1,
+				totalLines: 2,
+			},
+			width: 30,
+			want:  " notes  no ft | 1:1 | 2 lines ",
+		},
+		{
+			name: "when the RHS doesn't fit, it is truncated",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       12,
+				col:        5,
+				totalLines: 42,
+			},
+			width: 13,
+			want:  " main.gogo | ",
+		},
+		{
+			name: "when the LHS fills the screen, one column of the RHS remains",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       12,
+				col:        5,
+				totalLines: 42,
+			},
+			width: 6,
+			want:  " maing",
+		},
+		{
+			name: "when the screen has width 1, only the RHS is shown",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       12,
+				col:        5,
+				totalLines: 42,
+			},
+			width: 1,
+			want:  "g",
+		},
+		{
+			name: "when the screen has width 0, nothing is shown",
+			s: status{
+				filename:   "main.go",
+				fileType:   "go",
+				line:       12,
+				col:        5,
+				totalLines: 42,
+			},
+			width: 0,
+			want:  "",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := statusBar(tc.s, tc.width)
+			if got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+			if len(got) != tc.width {
+				t.Errorf("expected status bar of width %d, got %d", tc.width, len(got))
+			}
+		})
+	}
+}
+
+func Test_scrollPosition(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		lineOffset int
+		height     int
+		totalLines int
+		want       string
+	}{
+		{name: "when the document is empty it shows All", height: 22, want: "All"},
+		{name: "when the document fits on screen it shows All", height: 22, totalLines: 22, want: "All"},
+		{name: "when the first line is visible it shows Top", height: 22, totalLines: 23, want: "Top"},
+		{name: "when the last line is visible it shows Bot", lineOffset: 78, height: 22, totalLines: 100, want: "Bot"},
+		{name: "when scrolled past the end it shows Bot", lineOffset: 90, height: 22, totalLines: 100, want: "Bot"},
+		{name: "when halfway through it shows 50%", lineOffset: 39, height: 22, totalLines: 100, want: "50%"},
+		{name: "when one line from the top it shows the percentage", lineOffset: 1, height: 22, totalLines: 100, want: "1%"},
+		{name: "when one line from the bottom it shows the percentage", lineOffset: 77, height: 22, totalLines: 100, want: "98%"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := scrollPosition(tc.lineOffset, tc.height, tc.totalLines); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Renderer_renderMessageBar_expiry(t *testing.T) {
+	t.Parallel()
+
+	statusTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name          string
+		config        Config
+		frameDuration time.Duration
+		elapsed       time.Duration
+		wantShown     bool
+	}{
+		{
+			name:      "when no time has elapsed, the message is shown",
+			elapsed:   0,
+			wantShown: true,
+		},
+		{
+			name:      "just before the default threshold, the message is shown",
+			elapsed:   defaultStatusMsgDuration - time.Nanosecond,
+			wantShown: true,
+		},
+		{
+			name:    "at the default threshold, the message is hidden",
+			elapsed: defaultStatusMsgDuration,
+		},
+		{
+			name:    "after the default threshold, the message is hidden",
+			elapsed: defaultStatusMsgDuration + time.Second,
+		},
+		{
+			name:    "at a configured threshold, the message is hidden",
+			config:  Config{StatusMsgDuration: 200 * time.Millisecond},
+			elapsed: 200 * time.Millisecond,
+		},
+		{
+			name:      "before a configured threshold, the message is shown",
+			config:    Config{StatusMsgDuration: 30 * time.Second},
+			elapsed:   10 * time.Second,
+			wantShown: true,
+		},
+		{
+			name:          "when the frame sets a duration, it overrides the configured duration",
+			config:        Config{StatusMsgDuration: 30 * time.Second},
+			frameDuration: time.Second,
+			elapsed:       time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := &fakeTerminalWriter{}
+			r := New("gila", "test", w, Screen{Width: 80, Height: 24}, tc.config)
+			r.clock = func() time.Time { return statusTime.Add(tc.elapsed) }
+			frame := editor.Frame{
+				Cursor:            &editor.Cursor{},
+				StatusMsg:         "Saved",
+				LastStatusTime:    statusTime,
+				StatusMsgDuration: tc.frameDuration,
+			}
+			if err := r.Render(frame); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := strings.Contains(w.String(), "Saved"); got != tc.wantShown {
+				t.Errorf("expected message shown to be %t, got output\n%q", tc.wantShown, w.String())
+			}
+		})
+	}
+}
+
+func Test_visibleRunes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		line  string
+		width int
+		want  string
+	}{
+		{
+			name:  "when the line fits, it is unchanged",
+			line:  "ab世界cd",
+			width: 8,
+			want:  "ab世界cd",
+		},
+		{
+			name:  "when the line is too long, it is truncated by cells",
+			line:  "ab世界cd",
+			width: 6,
+			want:  "ab世界",
+		},
+		{
+			name:  "when the line contains a tab, it is expanded to the next tab stop",
+			line:  "ab\tc",
+			width: 8,
+			want:  "ab  c",
+		},
+		{
+			name:  "when a ZWJ sequence fits, it occupies two cells",
+			line:  "a\U0001f468\u200d\U0001f469b",
+			width: 4,
+			want:  "a\U0001f468\u200d\U0001f469b",
+		},
+		{
+			name:  "when an emoji with a modifier straddles the right edge, the whole cluster is replaced",
+			line:  "ab\U0001f44b\U0001f3fd",
+			width: 3,
+			want:  "ab ",
+		},
+		{
+			name:  "when a wide character straddles the right edge, it is replaced by a space",
+			line:  "ab世界cd",
+			width: 5,
+			want:  "ab世 ",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			line := editor.NewLine(tc.line)
+			runes, _ := visibleRunes(&editor.Cursor{}, line, tc.width)
+			if got := string(runes); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Renderer_renderWrappedContent(t *testing.T) {
+	t.Parallel()
+
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 4, Height: 7}, Config{})
+	lines := []*editor.Line{
+		editor.NewLine("abcdefghij"),
+		editor.NewLine("xy"),
+	}
+	if err := r.renderWrappedContent(editor.Frame{Cursor: &editor.Cursor{}, Lines: lines}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clearAndNewLine := string(escseq.EscLineClearFromCursor) + "\r\n"
+	want := strings.Join([]string{"abcd", "efgh", "ij", "xy", "~"}, clearAndNewLine) + clearAndNewLine
+	if got := w.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func Test_Renderer_renderContent_annotations(t *testing.T) {
+	t.Parallel()
+
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 8, Height: 5}, Config{})
+	frame := editor.Frame{
+		Cursor: &editor.Cursor{},
+		Lines: []*editor.Line{
+			editor.NewLine("plain"),
+			editor.NewLine("colored"),
+		},
+		Gutter: true,
+		Annotations: []editor.Annotation{
+			{Line: 2, Gutter: '~', Spans: []editor.ColorSpan{{Start: 1, End: 3, SGR: "31"}}},
+		},
+	}
+	if err := r.renderContent(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clearAndNewLine := string(escseq.EscLineClearFromCursor) + "\r\n"
+	want := strings.Join([]string{
+		"  plain",
+		"~ c\x1b[31mol" + string(escseq.EscGRendRestore) + "ore",
+		"~",
+	}, clearAndNewLine) + clearAndNewLine
+	if got := w.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func Test_Renderer_drawRows(t *testing.T) {
+	t.Parallel()
+
+	rows := func(offset int) []string {
+		var rs []string
+		for i := offset; i < offset+3; i++ {
+			rs = append(rs, fmt.Sprintf("line %d", i))
+		}
+		return rs
+	}
+
+	testCases := []struct {
+		name       string
+		prevRows   []string
+		prevOffset int
+		rows       []string
+		offset     int
+		want       string
+	}{
+		{
+			name:   "when there is no previous frame, it redraws every row",
+			rows:   rows(0),
+			offset: 0,
+			want:   "line 0\r\nline 1\r\nline 2\r\n",
+		},
+		{
+			name:       "when the content scrolls down, it scrolls up and draws the bottom row",
+			prevRows:   rows(0),
+			prevOffset: 0,
+			rows:       rows(1),
+			offset:     1,
+			want:       "\x1b[1;3r\x1b[1S\x1b[r\x1b[3;1Hline 3\x1b[4;1H",
+		},
+		{
+			name:       "when the content scrolls up, it scrolls down and draws the top row",
+			prevRows:   rows(1),
+			prevOffset: 1,
+			rows:       rows(0),
+			offset:     0,
+			want:       "\x1b[1;3r\x1b[1T\x1b[r\x1b[1;1Hline 0\x1b[4;1H",
+		},
+		{
+			name:       "when a row that remains on screen has changed, it redraws every row",
+			prevRows:   []string{"line 0", "edited", "line 2"},
+			prevOffset: 0,
+			rows:       rows(1),
+			offset:     1,
+			want:       "line 1\r\nline 2\r\nline 3\r\n",
+		},
+		{
+			name:       "when the content is unchanged, it draws nothing",
+			prevRows:   rows(0),
+			prevOffset: 0,
+			rows:       rows(0),
+			offset:     0,
+			want:       "\x1b[4;1H",
+		},
+		{
+			name:       "when a row has changed without scrolling, it redraws only that row",
+			prevRows:   rows(0),
+			prevOffset: 0,
+			rows:       []string{"line 0", "edited", "line 2"},
+			offset:     0,
+			want:       "\x1b[2;1Hedited\x1b[4;1H",
+		},
+		{
+			name:       "when the screen size has changed, it redraws every row",
+			prevRows:   rows(0)[:2],
+			prevOffset: 0,
+			rows:       rows(0),
+			offset:     0,
+			want:       "line 0\r\nline 1\r\nline 2\r\n",
+		},
+		{
+			name:       "when the content scrolls by a whole screen, it redraws every row",
+			prevRows:   rows(0),
+			prevOffset: 0,
+			rows:       rows(3),
+			offset:     3,
+			want:       "line 3\r\nline 4\r\nline 5\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := &fakeTerminalWriter{}
+			r := New("gila", "test", w, Screen{Width: 10, Height: 5}, Config{})
+			r.prevRows, r.prevLineOffset = tc.prevRows, tc.prevOffset
+			if err := r.drawRows(tc.rows, tc.offset); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := w.String(); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Benchmark_Renderer_drawRows(b *testing.B) {
+	const nLines = 100
+	lines := make([]*editor.Line, nLines)
+	for i := range lines {
+		lines[i] = editor.NewLine(fmt.Sprintf("line %d of a document scrolled one line at a time", i))
+	}
+	screen := Screen{Width: 80, Height: 24}
+
+	// The rows of the document scrolled to each line offset.
+	r := New("gila", "test", &fakeTerminalWriter{}, screen, Config{})
+	rowsAt := make([][]string, nLines)
+	for i := range rowsAt {
+		rows, err := r.contentRows(editor.Frame{Cursor: &editor.Cursor{}, Lines: lines[i:]})
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		rowsAt[i] = rows
+	}
+
+	for _, scroll := range []bool{false, true} {
+		scroll := scroll
+
+		b.Run(fmt.Sprintf("scroll=%t", scroll), func(b *testing.B) {
+			w := &fakeTerminalWriter{}
+			r := New("gila", "test", w, screen, Config{})
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				offset := n % nLines
+				if !scroll {
+					r.prevRows = nil
+				}
+				if err := r.drawRows(rowsAt[offset], offset); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+			b.ReportMetric(float64(w.Len())/float64(b.N), "bytes/frame")
+		})
+	}
+}
+
+// Benchmark_Renderer_drawRows_edit measures the bytes written to redraw the
+// screen after a character is typed on one line, with and without the rows
+// of the previous frame to compare against.
+func Benchmark_Renderer_drawRows_edit(b *testing.B) {
+	screen := Screen{Width: 80, Height: 24}
+	r := New("gila", "test", &fakeTerminalWriter{}, screen, Config{})
+	frameRows := func(edited string) []string {
+		lines := make([]*editor.Line, 100)
+		for i := range lines {
+			lines[i] = editor.NewLine(fmt.Sprintf("line %d of a document edited one character at a time", i))
+		}
+		lines[10] = editor.NewLine(edited)
+		rows, err := r.contentRows(editor.Frame{Cursor: &editor.Cursor{}, Lines: lines})
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		return rows
+	}
+	before, after := frameRows("hello"), frameRows("hello!")
+
+	for _, partial := range []bool{false, true} {
+		partial := partial
+
+		b.Run(fmt.Sprintf("partial=%t", partial), func(b *testing.B) {
+			w := &fakeTerminalWriter{}
+			r := New("gila", "test", w, screen, Config{})
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				rows := before
+				if n%2 == 1 {
+					rows = after
+				}
+				if !partial {
+					r.prevRows = nil
+				}
+				if err := r.drawRows(rows, 0); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+			b.ReportMetric(float64(w.Len())/float64(b.N), "bytes/frame")
+		})
+	}
+}
+
+func Test_Renderer_cursorStyle(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		style CursorStyle
+		want  string
+	}{
+		{name: "blinking block", style: CursorBlinkBlock, want: "\x1b[1 q"},
+		{name: "steady block", style: CursorSteadyBlock, want: "\x1b[2 q"},
+		{name: "blinking underline", style: CursorBlinkUnderline, want: "\x1b[3 q"},
+		{name: "steady underline", style: CursorSteadyUnderline, want: "\x1b[4 q"},
+		{name: "blinking bar", style: CursorBlinkBar, want: "\x1b[5 q"},
+		{name: "steady bar", style: CursorSteadyBar, want: "\x1b[6 q"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := &fakeTerminalWriter{}
+			r := New("gila", "test", w, Screen{Width: 80, Height: 24}, Config{CursorStyle: tc.style})
+			if err := r.Render(editor.Frame{Cursor: &editor.Cursor{}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := w.String(); !strings.HasPrefix(got, tc.want) {
+				t.Errorf("expected render to start with %q, got %q", tc.want, got)
+			}
+
+			w.Reset()
+			if err := r.Clear(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := w.String(), "\x1b[0 q"; !strings.HasPrefix(got, want) {
+				t.Errorf("expected clear to restore %q, got %q", want, got)
+			}
+		})
+	}
+
+	t.Run("default style", func(t *testing.T) {
+		t.Parallel()
+
+		w := &fakeTerminalWriter{}
+		r := New("gila", "test", w, Screen{Width: 80, Height: 24}, Config{})
+		if err := r.Render(editor.Frame{Cursor: &editor.Cursor{}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := r.Clear(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, seq := range cursorStyleSeqs {
+			if got := w.String(); strings.Contains(got, string(seq)) {
+				t.Errorf("expected no cursor style sequence, got %q", got)
+			}
+		}
+	})
+}
+
+func Test_Renderer_Clear(t *testing.T) {
+	t.Parallel()
+
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 80, Height: 24}, Config{})
+	if err := r.Clear(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The alternate screen is cleared before leaving it, so that its contents
+	// aren't shown if it's entered again.
+	want := string(escseq.EscScreenClear) + string(escseq.EscCursorTopLeft) + string(escseq.EscAltScreenExit)
+	if got := w.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func Test_Renderer_Invalidate(t *testing.T) {
+	t.Parallel()
+
+	frame := editor.Frame{
+		Cursor: &editor.Cursor{},
+		Lines:  []*editor.Line{editor.NewLine("one"), editor.NewLine("two")},
+	}
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 80, Height: 5}, Config{})
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w.Reset()
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(w.String(), "one") {
+		t.Errorf("expected unchanged rows not to be redrawn, got %q", w.String())
+	}
+
+	r.Invalidate()
+	w.Reset()
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := w.String(); !strings.Contains(got, "one") || !strings.Contains(got, "two") {
+		t.Errorf("expected every row to be redrawn after Invalidate, got %q", got)
+	}
+}
+
+func Test_Renderer_Reconfigure(t *testing.T) {
+	t.Parallel()
+
+	frame := editor.Frame{
+		Cursor: &editor.Cursor{},
+		Lines:  []*editor.Line{editor.NewLine("one"), editor.NewLine("two")},
+	}
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 80, Height: 5}, Config{})
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.Reconfigure(Config{CursorStyle: CursorSteadyBar, Theme: DarkTheme})
+	w.Reset()
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := w.String()
+	if want := string(cursorStyleSeqs[CursorSteadyBar]); !strings.HasPrefix(got, want) {
+		t.Errorf("expected render to start with %q, got %q", want, got)
+	}
+	if !strings.Contains(got, DarkTheme.styles().text) {
+		t.Errorf("expected the new theme's text style %q, got %q", DarkTheme.styles().text, got)
+	}
+	if !strings.Contains(got, "one") || !strings.Contains(got, "two") {
+		t.Errorf("expected every row to be redrawn after Reconfigure, got %q", got)
+	}
+	if r.config.StatusMsgDuration != defaultStatusMsgDuration {
+		t.Errorf("expected the default status message duration, got %v", r.config.StatusMsgDuration)
+	}
+}
+
+func Test_Renderer_Resize(t *testing.T) {
+	t.Parallel()
+
+	r := New("gila", "test", &fakeTerminalWriter{}, Screen{Width: 80, Height: 24}, Config{})
+	r.Resize(100, 40)
+	r.Resize(120, 50)
+
+	if w, h := r.Size(); w != 120 || h != 50 {
+		t.Errorf("expected size 120x50, got %dx%d", w, h)
+	}
+	select {
+	case <-r.ResizeNotifier():
+	default:
+		t.Fatal("expected a resize notification")
+	}
+	select {
+	case <-r.ResizeNotifier():
+		t.Error("expected consecutive resizes to be coalesced into one notification")
+	default:
+	}
+}
+
+// Test_Renderer_Resize_concurrent is intended to be run with -race.
+func Test_Renderer_Resize_concurrent(t *testing.T) {
+	t.Parallel()
+
+	lines := []*editor.Line{editor.NewLine("one"), editor.NewLine("two")}
+	r := New("gila", "test", &fakeTerminalWriter{}, Screen{Width: 80, Height: 24}, Config{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r.Resize(40+i+j, 10+j)
+			}
+		}(i)
+	}
+	for i := 0; i < 100; i++ {
+		if err := r.Render(editor.Frame{Cursor: &editor.Cursor{}, Lines: lines}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	wg.Wait()
+}
+
+func Test_Renderer_RenderProgress(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		done  int
+		total int
+		label string
+		width int
+		want  string
+	}{
+		{
+			name:  "when nothing is done the bar is empty",
+			done:  0,
+			total: 100,
+			label: "Loading",
+			width: 23,
+			want:  "Loading ░░░░░░░░░░   0%",
+		},
+		{
+			name:  "when some is done the bar is partly filled",
+			done:  1,
+			total: 3,
+			label: "Loading",
+			width: 23,
+			want:  "Loading ▓▓▓░░░░░░░  33%",
+		},
+		{
+			name:  "when everything is done the bar is full",
+			done:  50,
+			total: 50,
+			label: "Loading",
+			width: 23,
+			want:  "Loading ▓▓▓▓▓▓▓▓▓▓ 100%",
+		},
+		{
+			name:  "when more than the total is done the bar is full",
+			done:  60,
+			total: 50,
+			label: "Loading",
+			width: 23,
+			want:  "Loading ▓▓▓▓▓▓▓▓▓▓ 100%",
+		},
+		{
+			name:  "when the total is unknown the bar is empty",
+			done:  10,
+			total: 0,
+			label: "Loading",
+			width: 23,
+			want:  "Loading ░░░░░░░░░░   0%",
+		},
+		{
+			name:  "when the screen is too narrow for the bar the label is truncated",
+			done:  1,
+			total: 2,
+			label: "Loading file.txt",
+			width: 10,
+			want:  "Loading fi",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := &fakeTerminalWriter{}
+			r := New("gila", "test", w, Screen{Width: tc.width, Height: 24}, Config{})
+			if err := r.RenderProgress(tc.done, tc.total, tc.label); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := "\x1b[s\x1b[24;1H" + tc.want + "\x1b[K\x1b[u"
+			if got := w.String(); got != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+// frameRecorder is a Renderer that captures the last frame it renders.
+type frameRecorder struct {
+	*Renderer
+	last editor.Frame
+}
+
+func (r *frameRecorder) Render(frame editor.Frame) error {
+	r.last = frame
+	return r.Renderer.Render(frame)
+}
+
+func Test_Renderer_Render_scrollPastEnd(t *testing.T) {
+	t.Parallel()
+
+	// Page down well beyond the end of a ten-line document on a screen of
+	// five rows of text.
+	pgDn := []byte("\x1b[6~")
+	r := &frameRecorder{
+		Renderer: New("gila", "test", &fakeTerminalWriter{}, Screen{Width: 20, Height: 7}, Config{}),
+	}
+	e := editor.New(
+		editor.Keys(pgDn, pgDn, pgDn, pgDn),
+		r,
+		editor.WithConfig(editor.Config{Width: 20, Height: 7, ScrollPastEnd: true}),
+	)
+	if err := e.Load(strings.NewReader("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows, err := r.contentRows(r.last)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"10\x1b[K", "~\x1b[K", "~\x1b[K", "~\x1b[K", "~\x1b[K"}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("expected rows %q, got %q", want, rows)
+	}
+	if got := r.last.Cursor.Line(); got != 11 {
+		t.Errorf("expected the cursor to stop at line 11, got %d", got)
+	}
+}
+
+func Test_Renderer_withCursorSaved(t *testing.T) {
+	t.Parallel()
+
+	t.Run("when the write succeeds it is bracketed by save and restore", func(t *testing.T) {
+		t.Parallel()
+
+		w := &fakeTerminalWriter{}
+		r := New("gila", "test", w, Screen{Width: 80, Height: 24}, Config{})
+		err := r.withCursorSaved(func() error {
+			_, err := w.WriteString("popup")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := w.String(), "\x1b[spopup\x1b[u"; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("when the write fails the cursor is still restored", func(t *testing.T) {
+		t.Parallel()
+
+		w := &fakeTerminalWriter{}
+		r := New("gila", "test", w, Screen{Width: 80, Height: 24}, Config{})
+		wantErr := errors.New("write failed")
+		err := r.withCursorSaved(func() error { return wantErr })
+		if !errors.Is(err, wantErr) {
+			t.Errorf("expected error %v, got %v", wantErr, err)
+		}
+		if got, want := w.String(), "\x1b[s\x1b[u"; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+}
+
+// Benchmark_Renderer_Render measures the cost of rendering a document and of
+// rendering the homepage, frame after frame, with nothing changed in between.
+func Benchmark_Renderer_Render(b *testing.B) {
+	lines := make([]*editor.Line, 100)
+	for i := range lines {
+		lines[i] = editor.NewLine(fmt.Sprintf("line %d of a document rendered many times", i))
+	}
+	frames := map[string]editor.Frame{
+		"document": {Cursor: &editor.Cursor{}, Lines: lines, Filename: "doc.txt", FileType: "text"},
+		"homepage": {Cursor: &editor.Cursor{}},
+	}
+
+	for _, name := range []string{"document", "homepage"} {
+		frame := frames[name]
+
+		b.Run(name, func(b *testing.B) {
+			w := &fakeTerminalWriter{}
+			r := New("gila", "test", w, Screen{Width: 80, Height: 24}, Config{})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				w.Reset()
+				if err := r.Render(frame); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func Test_center(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		s     string
+		width int
+		want  string
+	}{
+		{name: "when the width is even and the text is even it is centered", s: "ab", width: 6, want: "  ab  "},
+		{name: "when there is an odd amount of padding the extra space is on the right", s: "ab", width: 5, want: " ab  "},
+		{name: "when the text fills the width it is unpadded", s: "abc", width: 3, want: "abc"},
+		{name: "when the text is wider than the width it is unpadded", s: "abcdef", width: 3, want: "abcdef"},
+		{name: "when the text contains multi-byte runes padding is counted in runes", s: "é", width: 5, want: "  é  "},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := center(tc.s, tc.width); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_Renderer_Render_statusBarCache(t *testing.T) {
+	t.Parallel()
+
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 40, Height: 5}, Config{})
+	lines := []*editor.Line{editor.NewLine("one")}
+	frame := editor.Frame{Cursor: &editor.Cursor{}, Lines: lines, Filename: "a.txt"}
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	frame.Filename, frame.Dirty = "b.txt", true
+	w.Reset()
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := w.String(); !strings.Contains(got, "b.txt (modified)") {
+		t.Errorf("expected the status bar to show the changed filename, got %q", got)
+	}
+
+	r.Resize(20, 7)
+	w.Reset()
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := statusBar(status{filename: "b.txt", line: 0, col: 0, totalLines: 1, scroll: "All", dirty: true}, 20)
+	if got := w.String(); !strings.Contains(got, want) {
+		t.Errorf("expected the status bar to be redrawn at the new width as %q, got %q", want, got)
+	}
+}
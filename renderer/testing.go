@@ -0,0 +1,64 @@
+package renderer
+
+import "github.com/angusgmorrison/gila/editor"
+
+// NullRenderer is an editor.Renderer that discards all output, for running
+// an editor without a terminal.
+type NullRenderer = editor.NullRenderer
+
+// SpyRenderer is an editor.Renderer that records each frame rendered instead
+// of writing it to a terminal. Together with editor.Keys, it allows a sequence
+// of keystrokes to be run through Editor.Run and the state of the editor
+// after each one to be inspected.
+//
+// The editor updates its cursor and lines in place, so each recorded frame
+// holds copies of them taken when the frame was rendered.
+type SpyRenderer struct {
+	frames []editor.Frame
+}
+
+var _ editor.Renderer = (*SpyRenderer)(nil)
+
+// Render records a snapshot of frame.
+func (r *SpyRenderer) Render(frame editor.Frame) error {
+	if frame.Cursor != nil {
+		cursor := *frame.Cursor
+		frame.Cursor = &cursor
+	}
+	lines := make([]*editor.Line, len(frame.Lines))
+	for i, line := range frame.Lines {
+		lines[i] = editor.NewLine(line.String())
+	}
+	frame.Lines = lines
+	r.frames = append(r.frames, frame)
+	return nil
+}
+
+// RenderProgress discards the progress reported.
+func (r *SpyRenderer) RenderProgress(int, int, string) error {
+	return nil
+}
+
+// Clear does nothing. The recorded frames are kept.
+func (r *SpyRenderer) Clear() error {
+	return nil
+}
+
+// Frames returns the frames rendered so far, oldest first.
+func (r *SpyRenderer) Frames() []editor.Frame {
+	return r.frames
+}
+
+// LastFrame returns the most recently rendered frame, or nil if none has been
+// rendered.
+func (r *SpyRenderer) LastFrame() *editor.Frame {
+	if len(r.frames) == 0 {
+		return nil
+	}
+	return &r.frames[len(r.frames)-1]
+}
+
+// RenderCount returns the number of frames rendered so far.
+func (r *SpyRenderer) RenderCount() int {
+	return len(r.frames)
+}
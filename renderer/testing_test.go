@@ -0,0 +1,67 @@
+package renderer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/angusgmorrison/gila/editor"
+)
+
+func Test_SpyRenderer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("when nothing is rendered there is no last frame", func(t *testing.T) {
+		t.Parallel()
+
+		r := &SpyRenderer{}
+		if r.LastFrame() != nil {
+			t.Errorf("expected no last frame, got %+v", r.LastFrame())
+		}
+		if r.RenderCount() != 0 {
+			t.Errorf("expected no frames, got %d", r.RenderCount())
+		}
+	})
+
+	t.Run("when keys are pressed it records the state after each one", func(t *testing.T) {
+		t.Parallel()
+
+		// Type two characters, move down a line and delete a character.
+		r := &SpyRenderer{}
+		kr := editor.Keys([]byte("a"), []byte("b"), []byte("\x1b[B"), []byte("\x1b[3~"))
+		e := editor.New(kr, r, editor.WithConfig(editor.Config{Width: 80, Height: 24}))
+		if err := e.Load(strings.NewReader("one\ntwo\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		type state struct {
+			line, col int
+			lines     []string
+		}
+		want := []state{
+			{line: 1, col: 1, lines: []string{"one", "two"}},
+			{line: 1, col: 2, lines: []string{"aone", "two"}},
+			{line: 1, col: 3, lines: []string{"abone", "two"}},
+			{line: 2, col: 3, lines: []string{"abone", "two"}},
+			{line: 2, col: 3, lines: []string{"abone", "tw"}},
+		}
+		if r.RenderCount() != len(want) {
+			t.Fatalf("expected %d frames, got %d", len(want), r.RenderCount())
+		}
+		for i, frame := range r.Frames() {
+			got := state{line: frame.Cursor.Line(), col: frame.Cursor.Col()}
+			for _, line := range frame.Lines {
+				got.lines = append(got.lines, line.String())
+			}
+			if !reflect.DeepEqual(got, want[i]) {
+				t.Errorf("frame %d: expected %+v, got %+v", i, want[i], got)
+			}
+		}
+		if last := r.LastFrame(); last.Cursor.Line() != 2 || last.Cursor.Col() != 3 {
+			t.Errorf("expected last frame cursor at 2:3, got %d:%d", last.Cursor.Line(), last.Cursor.Col())
+		}
+	})
+}
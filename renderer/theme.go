@@ -0,0 +1,183 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/angusgmorrison/gila/editor"
+)
+
+// Color is a 24-bit color. The zero Color is the terminal's default.
+type Color struct {
+	r, g, b uint8
+	set     bool
+}
+
+// RGB returns the color with the given red, green and blue components.
+func RGB(r, g, b uint8) Color {
+	return Color{r: r, g: g, b: b, set: true}
+}
+
+// ParseColor parses a color in the form "#rrggbb". The empty string is the
+// terminal's default color.
+func ParseColor(s string) (Color, error) {
+	if s == "" {
+		return Color{}, nil
+	}
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || len(hex) != 6 {
+		return Color{}, fmt.Errorf("expected a color of the form #rrggbb, got %q", s)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return Color{}, fmt.Errorf("expected a color of the form #rrggbb, got %q", s)
+	}
+	return RGB(uint8(n>>16), uint8(n>>8), uint8(n)), nil
+}
+
+// String returns the color in the form "#rrggbb", or "" for the terminal's
+// default color.
+func (c Color) String() string {
+	if !c.set {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.r, c.g, c.b)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Color) UnmarshalText(text []byte) error {
+	parsed, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// fgSGR returns the SGR parameters that set the foreground to c.
+func (c Color) fgSGR() string {
+	if !c.set {
+		return "39"
+	}
+	return fmt.Sprintf("38;2;%d;%d;%d", c.r, c.g, c.b)
+}
+
+// bgSGR returns the SGR parameters that set the background to c.
+func (c Color) bgSGR() string {
+	if !c.set {
+		return "49"
+	}
+	return fmt.Sprintf("48;2;%d;%d;%d", c.r, c.g, c.b)
+}
+
+// Theme holds the colors of the text and the status bar. Unset colors are the
+// terminal's defaults, and the zero Theme leaves the terminal's colors
+// unchanged, with the status bar drawn in inverted colors.
+type Theme struct {
+	Background, Foreground Color
+	// The colors of color spans naming the corresponding editor.Syntax
+	// classes.
+	Keyword, String, Comment, Number Color
+	StatusBarBg, StatusBarFg         Color
+}
+
+// Built-in themes, based on the Atom One palettes.
+var (
+	DarkTheme = Theme{
+		Background:  RGB(0x28, 0x2c, 0x34),
+		Foreground:  RGB(0xab, 0xb2, 0xbf),
+		Keyword:     RGB(0xc6, 0x78, 0xdd),
+		String:      RGB(0x98, 0xc3, 0x79),
+		Comment:     RGB(0x5c, 0x63, 0x70),
+		Number:      RGB(0xd1, 0x9a, 0x66),
+		StatusBarBg: RGB(0x3e, 0x44, 0x51),
+		StatusBarFg: RGB(0xdc, 0xdf, 0xe4),
+	}
+	LightTheme = Theme{
+		Background:  RGB(0xfa, 0xfa, 0xfa),
+		Foreground:  RGB(0x38, 0x3a, 0x42),
+		Keyword:     RGB(0xa6, 0x26, 0xa4),
+		String:      RGB(0x50, 0xa1, 0x4f),
+		Comment:     RGB(0xa0, 0xa1, 0xa7),
+		Number:      RGB(0x98, 0x68, 0x01),
+		StatusBarBg: RGB(0xe5, 0xe5, 0xe6),
+		StatusBarFg: RGB(0x38, 0x3a, 0x42),
+	}
+)
+
+// LoadTheme reads a theme from the JSON file at path, which maps the names of
+// Theme's fields to colors of the form "#rrggbb":
+//
+//	{"Background": "#282c34", "Foreground": "#abb2bf"}
+//
+// Colors that aren't given are the terminal's defaults.
+func LoadTheme(path string) (Theme, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Theme{}, err
+	}
+	defer f.Close()
+
+	var t Theme
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// themeStyles holds the SGR parameters that apply a theme, computed once so
+// that they aren't formatted for every rune rendered.
+type themeStyles struct {
+	// The SGR parameters that set the colors of the text and the status bar,
+	// or "" to use the terminal's defaults and inverted colors respectively.
+	text, statusBar string
+	// SGR parameters by the name of the syntax class they color, or "" if the
+	// class isn't colored.
+	syntax map[string]string
+}
+
+func (t Theme) styles() themeStyles {
+	s := themeStyles{
+		syntax: map[string]string{
+			editor.SyntaxKeyword: t.Keyword.spanSGR(),
+			editor.SyntaxString:  t.String.spanSGR(),
+			editor.SyntaxComment: t.Comment.spanSGR(),
+			editor.SyntaxNumber:  t.Number.spanSGR(),
+		},
+	}
+	if t.Background.set || t.Foreground.set {
+		s.text = t.Foreground.fgSGR() + ";" + t.Background.bgSGR()
+	}
+	if t.StatusBarBg.set || t.StatusBarFg.set {
+		s.statusBar = t.StatusBarFg.fgSGR() + ";" + t.StatusBarBg.bgSGR()
+	}
+	return s
+}
+
+// spanSGR returns the SGR parameters of a color span colored c, or "" if c is
+// unset.
+func (c Color) spanSGR() string {
+	if !c.set {
+		return ""
+	}
+	return c.fgSGR()
+}
+
+// spanSGR returns the SGR parameters for a color span with the given SGR,
+// replacing the names of syntax classes with the theme's colors for them.
+func (s themeStyles) spanSGR(sgr string) string {
+	if themed, ok := s.syntax[sgr]; ok {
+		return themed
+	}
+	return sgr
+}
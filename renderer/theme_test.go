@@ -0,0 +1,128 @@
+package renderer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/angusgmorrison/gila/editor"
+	"github.com/angusgmorrison/gila/escseq"
+)
+
+func Test_LoadTheme_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	for name, theme := range map[string]Theme{"dark": DarkTheme, "light": LightTheme, "partial": {Keyword: RGB(0, 0, 0)}} {
+		name, theme := name, theme
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := json.Marshal(theme)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			path := filepath.Join(t.TempDir(), "theme.json")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadTheme(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != theme {
+				t.Errorf("expected %+v, got %+v\nJSON: %s", theme, got, data)
+			}
+		})
+	}
+}
+
+func Test_LoadTheme_errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{name: "when a color isn't hex it returns an error", json: `{"Keyword": "purple"}`, wantErr: "purple"},
+		{name: "when a color is too short it returns an error", json: `{"Keyword": "#fff"}`, wantErr: "#fff"},
+		{name: "when a field is unknown it returns an error", json: `{"Keywords": "#ffffff"}`, wantErr: "Keywords"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "theme.json")
+			if err := os.WriteFile(path, []byte(tc.json), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadTheme(path); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected an error mentioning %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_ParseColor(t *testing.T) {
+	t.Parallel()
+
+	c, err := ParseColor("#C678dd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c != RGB(0xc6, 0x78, 0xdd) {
+		t.Errorf("expected #c678dd, got %s", c)
+	}
+	if c, err := ParseColor(""); err != nil || c != (Color{}) {
+		t.Errorf("expected the default color, got %s, %v", c, err)
+	}
+}
+
+func Test_Renderer_Render_theme(t *testing.T) {
+	t.Parallel()
+
+	theme := Theme{
+		Background:  RGB(1, 2, 3),
+		Keyword:     RGB(4, 5, 6),
+		StatusBarFg: RGB(7, 8, 9),
+	}
+	frame := editor.Frame{
+		Cursor: &editor.Cursor{},
+		Lines:  []*editor.Line{editor.NewLine("func main")},
+		Annotations: []editor.Annotation{{
+			Line: 1,
+			Spans: []editor.ColorSpan{
+				{Start: 0, End: 4, SGR: editor.SyntaxKeyword},
+				{Start: 5, End: 9, SGR: editor.SyntaxString}, // unset in the theme
+			},
+		}},
+	}
+	w := &fakeTerminalWriter{}
+	r := New("gila", "test", w, Screen{Width: 20, Height: 4}, Config{Theme: theme})
+	if err := r.Render(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := "39;48;2;1;2;3"
+	set := func(sgr string) string { return escseq.Format(escseq.EscGRendSet, sgr) }
+	restore := string(escseq.EscGRendRestore) + set(text)
+	got := w.String()
+	for _, want := range []string{
+		string(escseq.EscCursorTopLeft) + set(text),
+		set("38;2;4;5;6") + "func" + restore + " main",
+		set("38;2;7;8;9;49"),
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, string(escseq.EscGRendInvertColors)) {
+		t.Errorf("expected the status bar not to be inverted, got %q", got)
+	}
+}